@@ -0,0 +1,243 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"google.golang.org/api/idtoken"
+)
+
+const (
+	iapIssuer   = "https://cloud.google.com/iap"
+	devCookie   = "bingo-user"
+	proxyHeader = "X-Forwarded-Email"
+)
+
+// AuthError is an error that indicates that the user making a request could
+// not be identified.
+type AuthError struct {
+	Reason string
+}
+
+func (e AuthError) Error() string {
+	return fmt.Sprintf("not authenticated: %s", e.Reason)
+}
+
+// Authenticator works out the email of the user making a request.
+type Authenticator interface {
+	Email(r *http.Request) (string, error)
+}
+
+var authenticator Authenticator = NewIAPAuthenticator("")
+
+// appEngineAudience is the audience IAP signs tokens for App Engine apps
+// with.
+var appEngineAudience = regexp.MustCompile(`^/projects/[0-9]+/apps/(.+)$`)
+
+// NewAuthenticator returns the authenticator for a mode, set up from the
+// environment:
+//
+//	iap     IAPAUDIENCE, optional
+//	oidc    OIDCISSUER and OIDCAUDIENCE, and OIDCCLAIM, optional
+//	header  AUTHHEADER, optional
+//	dev     nothing, and refused on App Engine and Cloud Run
+func NewAuthenticator(mode string) (Authenticator, error) {
+	switch mode {
+	case "", "iap":
+		return NewIAPAuthenticator(os.Getenv("IAPAUDIENCE")), nil
+	case "oidc":
+		return NewOIDCAuthenticator(os.Getenv("OIDCISSUER"), os.Getenv("OIDCAUDIENCE"), os.Getenv("OIDCCLAIM"))
+	case "header":
+		return HeaderAuthenticator{os.Getenv("AUTHHEADER")}, nil
+	case "dev":
+		if onGoogleCloud() {
+			return nil, fmt.Errorf("dev auth lets anyone be any user, it can't be used on Google Cloud")
+		}
+		return DevAuthenticator{}, nil
+	}
+	return nil, fmt.Errorf("unknown auth mode: %s", mode)
+}
+
+// devEmail is who requests are from when nothing says otherwise, as when the
+// app is run locally.
+func devEmail() string {
+	return fmt.Sprintf("%s@google.com", os.Getenv("USER"))
+}
+
+// IAPAuthenticator trusts the user Identity-Aware Proxy signed the request
+// for. Requests without IAP headers can only have come from running the app
+// locally, and are from the developer running it.
+type IAPAuthenticator struct {
+	audience func(string) bool
+	validate func(ctx context.Context, token, audience string) (*idtoken.Payload, error)
+}
+
+// NewIAPAuthenticator returns an authenticator for apps behind IAP. The
+// audience can be left empty, in which case any App Engine audience for this
+// project is accepted, so the project number doesn't have to be looked up.
+func NewIAPAuthenticator(audience string) IAPAuthenticator {
+	match := func(aud string) bool {
+		if audience != "" {
+			return aud == audience
+		}
+		m := appEngineAudience.FindStringSubmatch(aud)
+		return m != nil && projectID != "" && m[1] == projectID
+	}
+
+	return IAPAuthenticator{match, idtoken.Validate}
+}
+
+// Email returns the email in the IAP JWT. Without IAP headers the app is
+// being run locally and gets the developer's email, unless it's on Google
+// Cloud, where a missing header means the request didn't come through IAP.
+func (i IAPAuthenticator) Email(r *http.Request) (string, error) {
+	header := r.Header.Get("X-Goog-Authenticated-User-Email")
+	if header == "" {
+		if onGoogleCloud() {
+			return "", AuthError{"request did not come through IAP"}
+		}
+		return devEmail(), nil
+	}
+
+	// The audience is checked here rather than by idtoken, as it can be any
+	// App Engine audience for the project.
+	payload, err := i.validate(r.Context(), r.Header.Get("X-Goog-IAP-JWT-Assertion"), "")
+	if err != nil {
+		return "", AuthError{fmt.Sprintf("could not validate IAP JWT: %s", err)}
+	}
+
+	if payload.Issuer != iapIssuer {
+		return "", AuthError{fmt.Sprintf("IAP JWT issuer %s is not %s", payload.Issuer, iapIssuer)}
+	}
+
+	if !i.audience(payload.Audience) {
+		return "", AuthError{"IAP JWT is not for this app"}
+	}
+
+	email, ok := payload.Claims["email"].(string)
+	if !ok || email == "" {
+		return "", AuthError{"could not get email from IAP JWT"}
+	}
+
+	if email != getEmailFromString(header) {
+		return "", AuthError{"IAP JWT is not for the user in the IAP header"}
+	}
+
+	return email, nil
+}
+
+// OIDCAuthenticator trusts bearer ID tokens from an OpenID Connect provider.
+type OIDCAuthenticator struct {
+	verifier *oidc.IDTokenVerifier
+	claim    string
+}
+
+// NewOIDCAuthenticator returns an authenticator for ID tokens from issuer,
+// with the signing keys found through its discovery document. The user is
+// the value of claim, which is email if left empty.
+func NewOIDCAuthenticator(issuer, audience, claim string) (OIDCAuthenticator, error) {
+	if issuer == "" || audience == "" {
+		return OIDCAuthenticator{}, fmt.Errorf("OIDCISSUER and OIDCAUDIENCE must be set for oidc auth")
+	}
+
+	if claim == "" {
+		claim = "email"
+	}
+
+	provider, err := oidc.NewProvider(context.Background(), issuer)
+	if err != nil {
+		return OIDCAuthenticator{}, fmt.Errorf("could not get discovery document for %s: %s", issuer, err)
+	}
+
+	return OIDCAuthenticator{provider.Verifier(&oidc.Config{ClientID: audience}), claim}, nil
+}
+
+// Email returns the configured claim of the bearer token.
+func (o OIDCAuthenticator) Email(r *http.Request) (string, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return "", AuthError{"no bearer token"}
+	}
+
+	idToken, err := o.verifier.Verify(r.Context(), token)
+	if err != nil {
+		return "", AuthError{fmt.Sprintf("could not validate ID token: %s", err)}
+	}
+
+	claims := map[string]interface{}{}
+	if err := idToken.Claims(&claims); err != nil {
+		return "", AuthError{fmt.Sprintf("could not read ID token claims: %s", err)}
+	}
+
+	if verified, ok := claims["email_verified"].(bool); ok && !verified && o.claim == "email" {
+		return "", AuthError{"email is not verified"}
+	}
+
+	email, ok := claims[o.claim].(string)
+	if !ok || email == "" {
+		return "", AuthError{fmt.Sprintf("could not get %s from ID token", o.claim)}
+	}
+
+	return email, nil
+}
+
+// HeaderAuthenticator trusts a header set by a reverse proxy in front of the
+// app. The app must only be reachable through the proxy, as anyone else can
+// set the header to whatever they like.
+type HeaderAuthenticator struct {
+	Header string
+}
+
+// Email returns the value of the header.
+func (h HeaderAuthenticator) Email(r *http.Request) (string, error) {
+	header := h.Header
+	if header == "" {
+		header = proxyHeader
+	}
+
+	email := strings.TrimSpace(r.Header.Get(header))
+	if email == "" {
+		return "", AuthError{fmt.Sprintf("no %s header", header)}
+	}
+
+	return email, nil
+}
+
+// DevAuthenticator lets whoever is testing the app be any user, picked with
+// a cookie, so several players can be tried out from one machine.
+type DevAuthenticator struct{}
+
+// Email returns the user in the cookie, or the developer running the app.
+func (d DevAuthenticator) Email(r *http.Request) (string, error) {
+	if c, err := r.Cookie(devCookie); err == nil && c.Value != "" {
+		return c.Value, nil
+	}
+	return devEmail(), nil
+}
+
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(header[7:])
+}
@@ -0,0 +1,329 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"google.golang.org/api/idtoken"
+)
+
+type testSigner struct {
+	ec  *ecdsa.PrivateKey
+	rsa *rsa.PrivateKey
+}
+
+func newTestSigner(t *testing.T) testSigner {
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate EC key: %s", err)
+	}
+
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate RSA key: %s", err)
+	}
+
+	return testSigner{ec, rk}
+}
+
+func (s testSigner) jwks() string {
+	enc := base64.RawURLEncoding.EncodeToString
+	keys := []map[string]string{
+		{
+			"kid": "ec1",
+			"kty": "EC",
+			"crv": "P-256",
+			"x":   enc(s.ec.X.Bytes()),
+			"y":   enc(s.ec.Y.Bytes()),
+		},
+		{
+			"kid": "rsa1",
+			"kty": "RSA",
+			"n":   enc(s.rsa.N.Bytes()),
+			"e":   enc(big.NewInt(int64(s.rsa.E)).Bytes()),
+		},
+	}
+
+	b, _ := json.Marshal(map[string]interface{}{"keys": keys})
+	return string(b)
+}
+
+func (s testSigner) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	enc := base64.RawURLEncoding.EncodeToString
+
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	content := enc(header) + "." + enc(payload)
+	hashed := sha256.Sum256([]byte(content))
+
+	var sig []byte
+	switch alg {
+	case "ES256":
+		r, ss, err := ecdsa.Sign(rand.Reader, s.ec, hashed[:])
+		if err != nil {
+			t.Fatalf("could not sign: %s", err)
+		}
+		sig = make([]byte, 64)
+		rb, sb := r.Bytes(), ss.Bytes()
+		copy(sig[32-len(rb):32], rb)
+		copy(sig[64-len(sb):], sb)
+	case "RS256":
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, s.rsa, crypto.SHA256, hashed[:])
+		if err != nil {
+			t.Fatalf("could not sign: %s", err)
+		}
+	}
+
+	return content + "." + enc(sig)
+}
+
+func testClaims(iss, aud string) map[string]interface{} {
+	return map[string]interface{}{
+		"iss":   iss,
+		"aud":   aud,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"email": "test@example.com",
+	}
+}
+
+func TestIAPAuthenticator(t *testing.T) {
+	s := newTestSigner(t)
+
+	oldProjectID := projectID
+	projectID = "bingo-project"
+	defer func() { projectID = oldProjectID }()
+
+	auth := NewIAPAuthenticator("")
+	auth.validate = func(ctx context.Context, token, audience string) (*idtoken.Payload, error) {
+		if token == "" {
+			return nil, fmt.Errorf("idtoken: invalid token")
+		}
+		return idtoken.ParsePayload(token)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/game", nil)
+	got, err := auth.Email(req)
+	if err != nil || got != devEmail() {
+		t.Errorf("IAPAuthenticator.Email() without IAP want %s got %s %v", devEmail(), got, err)
+	}
+
+	os.Setenv("GAE_ENV", "standard")
+	_, err = auth.Email(req)
+	os.Unsetenv("GAE_ENV")
+	if _, ok := err.(AuthError); !ok {
+		t.Errorf("IAPAuthenticator.Email() without IAP on Google Cloud err want AuthError got %v", err)
+	}
+
+	cases := []struct {
+		header string
+		iss    string
+		aud    string
+		want   string
+		err    bool
+	}{
+		{"accounts.google.com:test@example.com", iapIssuer, "/projects/123/apps/bingo-project", "test@example.com", false},
+		{"accounts.google.com:test@example.com", iapIssuer, "/projects/123/apps/other-project", "", true},
+		{"accounts.google.com:test@example.com", "https://accounts.google.com", "/projects/123/apps/bingo-project", "", true},
+		{"accounts.google.com:other@example.com", iapIssuer, "/projects/123/apps/bingo-project", "", true},
+		{"accounts.google.com:test@example.com", "", "", "", true},
+	}
+
+	for _, c := range cases {
+		token := ""
+		if c.iss != "" {
+			token = s.sign(t, "ES256", "ec1", testClaims(c.iss, c.aud))
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/api/game", nil)
+		req.Header.Set("X-Goog-Authenticated-User-Email", c.header)
+		req.Header.Set("X-Goog-IAP-JWT-Assertion", token)
+
+		got, err := auth.Email(req)
+		if (err != nil) != c.err {
+			t.Errorf("IAPAuthenticator.Email(%s, %s) err want %t got %v", c.header, c.aud, c.err, err)
+		}
+		if _, ok := err.(AuthError); err != nil && !ok {
+			t.Errorf("IAPAuthenticator.Email(%s, %s) err want AuthError got %T", c.header, c.aud, err)
+		}
+		if got != c.want {
+			t.Errorf("IAPAuthenticator.Email(%s, %s) want %s got %s", c.header, c.aud, c.want, got)
+		}
+	}
+}
+
+func TestOIDCAuthenticator(t *testing.T) {
+	s := newTestSigner(t)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			fmt.Fprintf(w, `{"issuer":"%s","jwks_uri":"%s/keys"}`, server.URL, server.URL)
+		case "/keys":
+			fmt.Fprint(w, s.jwks())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	if _, err := NewOIDCAuthenticator("", "bingo", ""); err == nil {
+		t.Errorf("NewOIDCAuthenticator() without issuer err want error got %v", nil)
+	}
+
+	auth, err := NewOIDCAuthenticator(server.URL, "bingo", "")
+	if err != nil {
+		t.Fatalf("NewOIDCAuthenticator() err want %v got %s", nil, err)
+	}
+
+	unverified := testClaims(server.URL, "bingo")
+	unverified["email_verified"] = false
+
+	expired := testClaims(server.URL, "bingo")
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	cases := []struct {
+		name   string
+		header string
+		want   string
+		err    bool
+	}{
+		{"valid", "Bearer " + s.sign(t, "RS256", "rsa1", testClaims(server.URL, "bingo")), "test@example.com", false},
+		{"lowercase scheme", "bearer " + s.sign(t, "RS256", "rsa1", testClaims(server.URL, "bingo")), "test@example.com", false},
+		{"unverified", "Bearer " + s.sign(t, "RS256", "rsa1", unverified), "", true},
+		{"wrong audience", "Bearer " + s.sign(t, "RS256", "rsa1", testClaims(server.URL, "other")), "", true},
+		{"wrong issuer", "Bearer " + s.sign(t, "RS256", "rsa1", testClaims("https://other.example.com", "bingo")), "", true},
+		{"expired", "Bearer " + s.sign(t, "RS256", "rsa1", expired), "", true},
+		{"unknown key", "Bearer " + s.sign(t, "RS256", "rsa2", testClaims(server.URL, "bingo")), "", true},
+		{"no token", "", "", true},
+		{"basic", "Basic dGVzdDp0ZXN0", "", true},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/game", nil)
+		if c.header != "" {
+			req.Header.Set("Authorization", c.header)
+		}
+
+		got, err := auth.Email(req)
+		if (err != nil) != c.err {
+			t.Errorf("OIDCAuthenticator.Email(%s) err want %t got %v", c.name, c.err, err)
+		}
+		if got != c.want {
+			t.Errorf("OIDCAuthenticator.Email(%s) want %s got %s", c.name, c.want, got)
+		}
+	}
+}
+
+func TestHeaderAuthenticator(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/game", nil)
+	if _, err := (HeaderAuthenticator{}).Email(req); err == nil {
+		t.Errorf("HeaderAuthenticator.Email() without header err want error got %v", nil)
+	}
+
+	req.Header.Set(proxyHeader, "test@example.com")
+	if got, _ := (HeaderAuthenticator{}).Email(req); got != "test@example.com" {
+		t.Errorf("HeaderAuthenticator.Email() want %s got %s", "test@example.com", got)
+	}
+
+	req.Header.Set("X-Auth-User", "other@example.com")
+	if got, _ := (HeaderAuthenticator{"X-Auth-User"}).Email(req); got != "other@example.com" {
+		t.Errorf("HeaderAuthenticator.Email() custom header want %s got %s", "other@example.com", got)
+	}
+}
+
+func TestDevAuthenticator(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/game", nil)
+	if got, _ := (DevAuthenticator{}).Email(req); got != devEmail() {
+		t.Errorf("DevAuthenticator.Email() want %s got %s", devEmail(), got)
+	}
+
+	req.AddCookie(&http.Cookie{Name: devCookie, Value: "player2@example.com"})
+	if got, _ := (DevAuthenticator{}).Email(req); got != "player2@example.com" {
+		t.Errorf("DevAuthenticator.Email() cookie want %s got %s", "player2@example.com", got)
+	}
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"issuer":"%s","jwks_uri":"%s/keys"}`, server.URL, server.URL)
+	}))
+	defer server.Close()
+
+	os.Setenv("OIDCISSUER", server.URL)
+	os.Setenv("OIDCAUDIENCE", "bingo")
+	defer os.Unsetenv("OIDCISSUER")
+	defer os.Unsetenv("OIDCAUDIENCE")
+
+	cases := []struct {
+		mode  string
+		cloud string
+		want  string
+		err   bool
+	}{
+		{"", "", "main.IAPAuthenticator", false},
+		{"iap", "", "main.IAPAuthenticator", false},
+		{"oidc", "", "main.OIDCAuthenticator", false},
+		{"header", "", "main.HeaderAuthenticator", false},
+		{"dev", "", "main.DevAuthenticator", false},
+		{"dev", "GAE_ENV", "<nil>", true},
+		{"dev", "K_SERVICE", "<nil>", true},
+		{"saml", "", "<nil>", true},
+	}
+
+	for _, c := range cases {
+		os.Unsetenv("GAE_ENV")
+		os.Unsetenv("K_SERVICE")
+		if c.cloud != "" {
+			os.Setenv(c.cloud, "standard")
+		}
+
+		got, err := NewAuthenticator(c.mode)
+		if (err != nil) != c.err {
+			t.Errorf("NewAuthenticator(%s) err want %t got %v", c.mode, c.err, err)
+		}
+		if fmt.Sprintf("%T", got) != c.want {
+			t.Errorf("NewAuthenticator(%s) want %s got %T", c.mode, c.want, got)
+		}
+	}
+	os.Unsetenv("GAE_ENV")
+	os.Unsetenv("K_SERVICE")
+}
+
+func TestAuthErrorStatus(t *testing.T) {
+	rr := httptest.NewRecorder()
+	writeErrorMsg(rr, AuthError{"no bearer token"})
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("writeErrorMsg(AuthError) status want %d got %d", http.StatusUnauthorized, rr.Code)
+	}
+}
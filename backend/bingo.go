@@ -15,9 +15,9 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"math"
 	"math/rand"
 	"sort"
@@ -30,6 +30,63 @@ import (
 // so I can use batch sets instead of adds for anything
 const alphanum = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
 
+const (
+	boardSize   = 25
+	boardCenter = 12
+
+	// defaultPhraseRate is assumed for phrases with no selection history.
+	defaultPhraseRate = 0.5
+	// certainRate is the rate above which a phrase almost always happens.
+	certainRate = 0.9
+	// balanceAttempts is how many deals are tried for each balanced board.
+	balanceAttempts = 50
+)
+
+// Points awarded to boards in games that keep score.
+const (
+	// pointsPerSquare is for each marked square another player also marked.
+	pointsPerSquare = 10
+	// pointsPerLine is for each completed row, column or diagonal.
+	pointsPerLine = 25
+	// pointsFirstBingo is for the first board to get a confirmed bingo.
+	pointsFirstBingo = 50
+	// pointsSpeed is the most a winner gets for speed, less a point for each
+	// minute between the game starting and their bingo.
+	pointsSpeed = 60
+	// pointsPerRejection is taken off for each bingo claim that was rejected.
+	pointsPerRejection = 20
+)
+
+// Suspicion added to a player's score for each kind of odd behaviour, up to
+// maxSuspicion.
+const (
+	// suspicionPerBurst is for each run of burstSize selections made within
+	// burstWindow of each other.
+	suspicionPerBurst = 15
+	// suspicionPerUnique is for each marked square nobody else has marked.
+	suspicionPerUnique = 10
+	// suspicionPerToggle is for each square marked and then unmarked again.
+	suspicionPerToggle = 10
+	// suspicionPerEarly is for each square marked within earlyWindow of the
+	// player joining.
+	suspicionPerEarly = 10
+	maxSuspicion      = 100
+
+	burstSize   = 4
+	burstWindow = 10 * time.Second
+	earlyWindow = 30 * time.Second
+	// activityLimit is how many of its latest selections a board keeps.
+	activityLimit = 50
+)
+
+// boardLines are the squares, by display order, of each row, column and
+// diagonal that makes a bingo.
+var boardLines = [][]int{
+	{0, 1, 2, 3, 4}, {5, 6, 7, 8, 9}, {10, 11, 12, 13, 14}, {15, 16, 17, 18, 19}, {20, 21, 22, 23, 24},
+	{0, 5, 10, 15, 20}, {1, 6, 11, 16, 21}, {2, 7, 12, 17, 22}, {3, 8, 13, 18, 23}, {4, 9, 14, 19, 24},
+	{0, 6, 12, 18, 24}, {4, 8, 12, 16, 20},
+}
+
 func uniqueID() string {
 	b := make([]byte, 20)
 	if _, err := rand.Read(b); err != nil {
@@ -61,47 +118,904 @@ func (m *Message) SetAudience(a ...string) {
 	m.Audience = a
 }
 
-// Game is the master structure for the game
-type Game struct {
-	ID      string           `json:"id" firestore:"id"`
-	Name    string           `json:"name" firestore:"name"`
-	Active  bool             `json:"active" firestore:"active"`
-	Players Players          `json:"players" firestore:"-"`
-	Admins  Players          `json:"admins" firestore:"-"`
-	Master  Master           `json:"master" firestore:"-"`
-	Boards  map[string]Board `json:"boards" firestore:"-"`
-	Created time.Time        `json:"created" firestore:"created"`
+// Suggestion statuses
+const (
+	SuggestionPending  = "pending"
+	SuggestionApproved = "approved"
+	SuggestionRejected = "rejected"
+)
+
+// Suggestion is a phrase a player would like added to a game or to the master
+// list. Game is the game the player suggested it from, and where they hear
+// back about it.
+type Suggestion struct {
+	ID      string    `json:"id" firestore:"id"`
+	Game    string    `json:"game" firestore:"game"`
+	Master  bool      `json:"master" firestore:"master"`
+	Phrase  Phrase    `json:"phrase" firestore:"phrase"`
+	Player  Player    `json:"player" firestore:"player"`
+	Status  string    `json:"status" firestore:"status"`
+	Created time.Time `json:"created" firestore:"created"`
+}
+
+// NewSuggestion initializes a pending suggestion from a player.
+func NewSuggestion(gid string, player Player, phrase Phrase, master bool) Suggestion {
+	s := Suggestion{}
+	s.ID = uniqueID()
+	s.Game = gid
+	s.Master = master
+	s.Phrase = phrase
+	s.Player = player
+	s.Status = SuggestionPending
+	s.Created = time.Now().UTC().Truncate(time.Millisecond)
+	return s
+}
+
+// Suggestions is a slice of Suggestion.
+type Suggestions []Suggestion
+
+// JSON marshalls the content of a slice of suggestions to json.
+func (ss Suggestions) JSON() (string, error) {
+	bytes, err := json.Marshal(ss)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal json for response: %s", err)
+	}
+
+	return string(bytes), nil
+}
+
+// PhraseStat is how often a master phrase gets selected across the games it
+// has been on boards in. Rate is the share of those boards where the player
+// selected it, so phrases that almost always happen have a rate near 1.
+type PhraseStat struct {
+	Phrase     Phrase  `json:"phrase"`
+	Boards     int     `json:"boards"`
+	Selections int     `json:"selections"`
+	Rate       float64 `json:"rate"`
+}
+
+// PhraseStats is a slice of PhraseStat.
+type PhraseStats []PhraseStat
+
+// NewPhraseStats works out the selection rate of each of the phrases from the
+// number of boards each phrase was dealt to and the number of players that
+// selected it, both keyed by phrase id. Free squares are left out.
+func NewPhraseStats(phrases []Phrase, boards, selections map[string]int) PhraseStats {
+	ps := PhraseStats{}
+	for _, v := range phrases {
+		if v.Free {
+			continue
+		}
+		stat := PhraseStat{}
+		stat.Phrase = v
+		stat.Boards = boards[v.ID]
+		stat.Selections = selections[v.ID]
+		if stat.Boards > 0 {
+			stat.Rate = math.Min(float64(stat.Selections)/float64(stat.Boards), 1)
+		}
+		ps = append(ps, stat)
+	}
+	return ps
+}
+
+// Rates returns the selection rate of each phrase that has been on a board,
+// keyed by phrase id.
+func (ps PhraseStats) Rates() map[string]float64 {
+	rates := make(map[string]float64)
+	for _, v := range ps {
+		if v.Boards > 0 {
+			rates[v.Phrase.ID] = v.Rate
+		}
+	}
+	return rates
+}
+
+// SortByDifficulty orders the phrases from the least to the most often
+// selected. Phrases that have never been on a board come last.
+func (ps PhraseStats) SortByDifficulty() {
+	sort.SliceStable(ps, func(i, j int) bool {
+		if (ps[i].Boards == 0) != (ps[j].Boards == 0) {
+			return ps[j].Boards == 0
+		}
+		return ps[i].Rate < ps[j].Rate
+	})
+}
+
+// JSON marshalls the content of a slice of phrase stats to json.
+func (ps PhraseStats) JSON() (string, error) {
+	bytes, err := json.Marshal(ps)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal json for response: %s", err)
+	}
+
+	return string(bytes), nil
+}
+
+// Tournament groups games played as one competition, such as the same bingo
+// run across several regional meetings.
+type Tournament struct {
+	ID      string    `json:"id" firestore:"id"`
+	Name    string    `json:"name" firestore:"name"`
+	Games   []string  `json:"games" firestore:"games"`
+	Created time.Time `json:"created" firestore:"created"`
+}
+
+// NewTournament initializes a tournament with no games.
+func NewTournament(name string) Tournament {
+	t := Tournament{}
+	t.ID = uniqueID()
+	t.Name = name
+	t.Games = []string{}
+	t.Created = time.Now().UTC().Truncate(time.Millisecond)
+	return t
+}
+
+// HasGame reports whether a game is part of the tournament.
+func (t Tournament) HasGame(gid string) bool {
+	for _, v := range t.Games {
+		if v == gid {
+			return true
+		}
+	}
+	return false
+}
+
+// AddGame adds a game to the tournament.
+func (t *Tournament) AddGame(gid string) {
+	if !t.HasGame(gid) {
+		t.Games = append(t.Games, gid)
+	}
+}
+
+// RemoveGame takes a game out of the tournament.
+func (t *Tournament) RemoveGame(gid string) error {
+	games := []string{}
+	for _, v := range t.Games {
+		if v != gid {
+			games = append(games, v)
+		}
+	}
+
+	if len(games) == len(t.Games) {
+		return fmt.Errorf("game id(%s) is not in tournament id(%s)", gid, t.ID)
+	}
+	t.Games = games
+	return nil
+}
+
+// JSON marshalls the content of a tournament to json.
+func (t Tournament) JSON() (string, error) {
+	bytes, err := json.Marshal(t)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal json for response: %s", err)
+	}
+
+	return string(bytes), nil
+}
+
+// Tournaments is a slice of Tournament.
+type Tournaments []Tournament
+
+// JSON marshalls the content of a slice of tournaments to json.
+func (ts Tournaments) JSON() (string, error) {
+	bytes, err := json.Marshal(ts)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal json for response: %s", err)
+	}
+
+	return string(bytes), nil
+}
+
+// Standing is how a player has done across the games of a tournament: the
+// games they played in, how many bingos they got and how many of those came
+// first in their round, and their total points.
+type Standing struct {
+	Player Player `json:"player"`
+	Games  int    `json:"games"`
+	Bingos int    `json:"bingos"`
+	Wins   int    `json:"wins"`
+	Points int    `json:"points"`
+}
+
+// Standings is a slice of Standing.
+type Standings []Standing
+
+// NewStandings adds up how every player did across the games, every round of
+// each included, ranked by wins, then bingos, then points.
+func NewStandings(games []Game) Standings {
+	index := make(map[string]int)
+	st := Standings{}
+
+	standing := func(p Player) *Standing {
+		i, ok := index[p.Email]
+		if !ok {
+			i = len(st)
+			index[p.Email] = i
+			st = append(st, Standing{Player: p})
+		}
+		return &st[i]
+	}
+
+	countWinners := func(winners []Winner) {
+		for i, w := range winners {
+			s := standing(w.Player)
+			s.Bingos++
+			if i == 0 {
+				s.Wins++
+			}
+		}
+	}
+
+	for _, g := range games {
+		for _, b := range g.Boards {
+			s := standing(b.Player)
+			s.Games++
+			s.Points += b.Points
+		}
+
+		countWinners(g.Winners)
+		for _, r := range g.Rounds {
+			countWinners(r.Winners)
+			for _, e := range r.Scores {
+				standing(e.Player).Points += e.Points
+			}
+		}
+	}
+
+	sort.SliceStable(st, func(i, j int) bool {
+		if st[i].Wins != st[j].Wins {
+			return st[i].Wins > st[j].Wins
+		}
+		if st[i].Bingos != st[j].Bingos {
+			return st[i].Bingos > st[j].Bingos
+		}
+		if st[i].Points != st[j].Points {
+			return st[i].Points > st[j].Points
+		}
+		return st[i].Player.Name < st[j].Player.Name
+	})
+
+	return st
+}
+
+// Obscure will obscure the email of every player other than the one input.
+func (st Standings) Obscure(email string) {
+	for i := range st {
+		st[i].Player.Obscure(email)
+	}
+}
+
+// JSON marshalls the content of a slice of standings to json.
+func (st Standings) JSON() (string, error) {
+	bytes, err := json.Marshal(st)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal json for response: %s", err)
+	}
+
+	return string(bytes), nil
+}
+
+// Candidate is a phrase put forward by a player while a game is in its lobby,
+// along with the players that voted for it.
+type Candidate struct {
+	ID      string    `json:"id" firestore:"id"`
+	Phrase  Phrase    `json:"phrase" firestore:"phrase"`
+	Player  Player    `json:"player" firestore:"player"`
+	Votes   Players   `json:"votes" firestore:"votes"`
+	Created time.Time `json:"created" firestore:"created"`
+}
+
+// NewCandidate initializes a candidate phrase from a player.
+func NewCandidate(player Player, phrase Phrase) Candidate {
+	c := Candidate{}
+	c.ID = uniqueID()
+	c.Phrase = phrase
+	c.Player = player
+	c.Votes = Players{}
+	c.Created = time.Now().UTC().Truncate(time.Millisecond)
+	return c
+}
+
+// Vote adds or removes the vote of a player. Players can't vote for the
+// phrases they put forward themselves.
+func (c *Candidate) Vote(player Player, up bool) error {
+	if c.Player.Email == player.Email {
+		return fmt.Errorf("players can't vote for their own phrases")
+	}
+
+	if up {
+		c.Votes.Add(player)
+		return nil
+	}
+	c.Votes.Remove(player)
+	return nil
+}
+
+// Candidates is a slice of Candidate.
+type Candidates []Candidate
+
+// Top returns the phrases of the n candidates with the most votes, with the
+// earliest submitted winning ties.
+func (cs Candidates) Top(n int) []Phrase {
+	sorted := make(Candidates, len(cs))
+	copy(sorted, cs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if len(sorted[i].Votes) != len(sorted[j].Votes) {
+			return len(sorted[i].Votes) > len(sorted[j].Votes)
+		}
+		return sorted[i].Created.Before(sorted[j].Created)
+	})
+
+	phrases := []Phrase{}
+	for i, v := range sorted {
+		if i >= n {
+			break
+		}
+		phrases = append(phrases, v.Phrase)
+	}
+
+	return phrases
+}
+
+// Phrases returns the phrases of all of the candidates.
+func (cs Candidates) Phrases() []Phrase {
+	phrases := []Phrase{}
+	for _, v := range cs {
+		phrases = append(phrases, v.Phrase)
+	}
+	return phrases
+}
+
+// Obscure will obscure the email of every player other than the one input.
+func (cs Candidates) Obscure(email string) {
+	for i := range cs {
+		cs[i].Player.Obscure(email)
+		cs[i].Votes.Obscure(email)
+	}
+}
+
+// JSON marshalls the content of a slice of candidates to json.
+func (cs Candidates) JSON() (string, error) {
+	bytes, err := json.Marshal(cs)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal json for response: %s", err)
+	}
+
+	return string(bytes), nil
+}
+
+// Game is the master structure for the game
+type Game struct {
+	ID       string           `json:"id" firestore:"id"`
+	Name     string           `json:"name" firestore:"name"`
+	Active   bool             `json:"active" firestore:"active"`
+	Players  Players          `json:"players" firestore:"-"`
+	Admins   Players          `json:"admins" firestore:"-"`
+	Master   Master           `json:"master" firestore:"-"`
+	Boards   map[string]Board `json:"boards" firestore:"-"`
+	Created  time.Time        `json:"created" firestore:"created"`
+	Lobby    bool             `json:"lobby" firestore:"lobby"`
+	Balanced bool             `json:"balanced" firestore:"balanced"`
+	OneAway  bool             `json:"oneaway" firestore:"oneaway"`
+	EndAfter int              `json:"endafter" firestore:"endafter"`
+	Winners  []Winner         `json:"winners" firestore:"winners"`
+	Scoring  bool             `json:"scoring" firestore:"scoring"`
+	Round    int              `json:"round" firestore:"round"`
+	Started  time.Time        `json:"started" firestore:"started"`
+	Rounds   []Round          `json:"rounds" firestore:"rounds"`
+	Claims   bool             `json:"claims" firestore:"claims"`
+	Penalty  int              `json:"penalty" firestore:"penalty"`
+}
+
+// Round is the result of a finished round of a game.
+type Round struct {
+	Number  int        `json:"number" firestore:"number"`
+	Winners []Winner   `json:"winners" firestore:"winners"`
+	Scores  Scoreboard `json:"scores" firestore:"scores"`
+	Ended   time.Time  `json:"ended" firestore:"ended"`
+}
+
+// Winner is a board that got a confirmed bingo. A game keeps its winners in
+// the order they got bingo.
+type Winner struct {
+	Board  string    `json:"board" firestore:"board"`
+	Player Player    `json:"player" firestore:"player"`
+	Time   time.Time `json:"time" firestore:"time"`
+}
+
+// GameOptions are the choices made when a game is created.
+type GameOptions struct {
+	// Free are the free squares, which replace any in the master list.
+	Free []Phrase
+	// Lobby starts the game in the lobby so players can vote on the phrases.
+	Lobby bool
+	// Balanced deals boards with roughly equal odds of bingo, based on how
+	// often each phrase has been selected in past games.
+	Balanced bool
+	// OneAway tells admins how many boards are one square away from bingo.
+	OneAway bool
+	// EndAfter ends the game once this many boards have a confirmed bingo,
+	// or never if it is 0.
+	EndAfter int
+	// Scoring keeps points for each board on top of plain bingo.
+	Scoring bool
+	// Claims makes players claim bingo themselves instead of it being
+	// announced as soon as a line is complete.
+	Claims bool
+	// Penalty is taken off the points of a board for each false claim.
+	Penalty int
+}
+
+// NewGame initializes a new game object
+func NewGame(name string, player Player, phrases []Phrase) Game {
+	g := Game{}
+	g.ID = uniqueID()
+	g.Name = name
+	g.Active = true
+	g.Created = time.Now().UTC().Truncate(time.Millisecond)
+	g.Started = g.Created
+	g.Round = 1
+	g.Boards = make(map[string]Board)
+	g.Admins.Add(player)
+	g.Players.Add(player)
+	g.Master.Load(phrases)
+
+	return g
+}
+
+// NewLobbyGame initializes a game that starts in the lobby, where players put
+// forward and vote on the phrases before any boards are dealt.
+func NewLobbyGame(name string, player Player) Game {
+	g := NewGame(name, player, []Phrase{})
+	g.Lobby = true
+	return g
+}
+
+// Start closes the lobby, loads the chosen phrases into the master list and
+// deals a board to every player. Rates are only needed by balanced games. The
+// new boards are returned.
+func (g *Game) Start(phrases []Phrase, rates map[string]float64) []Board {
+	boards := []Board{}
+	g.Master.Load(phrases)
+	g.Lobby = false
+	if g.Balanced {
+		g.Master.SetRates(rates)
+	}
+
+	for _, v := range g.Players {
+		boards = append(boards, g.NewBoard(v))
+	}
+
+	return boards
+}
+
+// Obscure will obscure the email address of every email in the game other than
+// the one that is input.
+func (g *Game) Obscure(email string) {
+	g.Players.Obscure(email)
+	g.Admins.Obscure(email)
+
+	for _, v := range g.Master.Records {
+		v.Players.Obscure(email)
+	}
+
+	for i, v := range g.Boards {
+		v.Obscure(email)
+		v.Activity = nil
+		v.Suspicion = 0
+		g.Boards[i] = v
+	}
+
+	for i := range g.Winners {
+		g.Winners[i].Player.Obscure(email)
+	}
+
+	for _, v := range g.Rounds {
+		for i := range v.Winners {
+			v.Winners[i].Player.Obscure(email)
+		}
+		v.Scores.Obscure(email)
+	}
+}
+
+// NewRound keeps the results of the current round and starts the next one.
+// The roster stays, but every selection is cleared, and with redeal every
+// player gets a freshly dealt board too. The boards are returned.
+func (g *Game) NewRound(redeal bool, t time.Time) []Board {
+	if g.Round == 0 {
+		g.Round = 1
+	}
+
+	r := Round{}
+	r.Number = g.Round
+	r.Winners = g.Winners
+	r.Ended = t.UTC().Truncate(time.Millisecond)
+	if g.Scoring {
+		r.Scores = g.Scoreboard()
+	}
+	g.Rounds = append(g.Rounds, r)
+
+	g.Round++
+	g.Started = r.Ended
+	g.Winners = []Winner{}
+	g.Active = true
+
+	for i := range g.Master.Records {
+		g.Master.Records[i].Players = Players{}
+		g.Master.Records[i].Phrase.Selected = false
+	}
+
+	boards := []Board{}
+	for id, b := range g.Boards {
+		if redeal {
+			b.Phrases = make(map[string]Phrase)
+			g.deal(&b)
+		} else {
+			for pid, v := range b.Phrases {
+				v.Selected = v.Free
+				b.Phrases[pid] = v
+			}
+		}
+		b.BingoDeclared = false
+		b.Points = 0
+		b.Rejected = 0
+		b.Activity = []Selection{}
+		b.Suspicion = 0
+		g.Boards[id] = b
+		boards = append(boards, b)
+	}
+
+	return boards
+}
+
+// Suspicion is how oddly a player has been marking their board, from 0 to
+// maxSuspicion, with the counts behind it.
+type Suspicion struct {
+	Board   string `json:"board"`
+	Player  Player `json:"player"`
+	Score   int    `json:"score"`
+	Bursts  int    `json:"bursts"`
+	Unique  int    `json:"unique"`
+	Toggles int    `json:"toggles"`
+	Early   int    `json:"early"`
+}
+
+// Suspicions are a slice of suspicions.
+type Suspicions []Suspicion
+
+// JSON marshalls the content of suspicions to json.
+func (s Suspicions) JSON() (string, error) {
+	bytes, err := json.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal json for response: %s", err)
+	}
+
+	return string(bytes), nil
+}
+
+// String describes a suspicion for admins.
+func (s Suspicion) String() string {
+	return fmt.Sprintf("suspicion score %d: %d bursts of clicking, %d squares nobody else marked, %d squares toggled and %d marked right after joining", s.Score, s.Bursts, s.Unique, s.Toggles, s.Early)
+}
+
+// Suspicion scores the way a player has been marking their board: bursts of
+// clicking, squares nobody else marked, squares toggled on and off to fish
+// for lines, and squares marked as soon as they joined.
+func (g Game) Suspicion(board Board) Suspicion {
+	s := Suspicion{}
+	s.Board = board.ID
+	s.Player = board.Player
+
+	selections := []Selection{}
+	for _, v := range board.Activity {
+		if v.Selected {
+			selections = append(selections, v)
+		}
+	}
+
+	for i := burstSize - 1; i < len(selections); i++ {
+		if selections[i].Time.Sub(selections[i-burstSize+1].Time) < burstWindow {
+			s.Bursts++
+			i += burstSize - 1
+		}
+	}
+
+	marked := make(map[string]bool)
+	for _, v := range board.Activity {
+		if v.Selected {
+			marked[v.Phrase] = true
+		} else if marked[v.Phrase] {
+			s.Toggles++
+			marked[v.Phrase] = false
+		}
+	}
+
+	// Only the board's own join counts, everyone marks squares as soon as a
+	// new round starts.
+	if !board.Joined.IsZero() {
+		for _, v := range selections {
+			if v.Time.Sub(board.Joined) < earlyWindow {
+				s.Early++
+			}
+		}
+	}
+
+	// Squares only look unique once there are others who could have marked
+	// them too.
+	if len(g.Players) > 2 {
+		for _, v := range board.Phrases {
+			if !v.Selected || v.Free {
+				continue
+			}
+			_, r := g.FindRecord(v)
+			if len(r.Players) == 1 && r.Players.IsMember(board.Player) {
+				s.Unique++
+			}
+		}
+	}
+
+	s.Score = s.Bursts*suspicionPerBurst + s.Unique*suspicionPerUnique + s.Toggles*suspicionPerToggle + s.Early*suspicionPerEarly
+	if s.Score > maxSuspicion {
+		s.Score = maxSuspicion
+	}
+
+	return s
+}
+
+// Suspicions scores every board in the game, most suspicious first.
+func (g Game) Suspicions() Suspicions {
+	s := Suspicions{}
+	for _, v := range g.Boards {
+		s = append(s, g.Suspicion(v))
+	}
+
+	sort.Slice(s, func(i, j int) bool {
+		if s[i].Score != s[j].Score {
+			return s[i].Score > s[j].Score
+		}
+		return s[i].Player.Email < s[j].Player.Email
+	})
+
+	return s
+}
+
+// AddWinner records a board getting a confirmed bingo at a given time, unless
+// it is already a winner. Winners stay in the order they got bingo, even when
+// one is only heard about later. It reports whether the board was added.
+func (g *Game) AddWinner(board Board, t time.Time) bool {
+	for _, v := range g.Winners {
+		if v.Board == board.ID {
+			return false
+		}
+	}
+
+	w := Winner{}
+	w.Board = board.ID
+	w.Player = board.Player
+	w.Time = t.UTC().Truncate(time.Millisecond)
+
+	i := len(g.Winners)
+	for i > 0 && g.Winners[i-1].Time.After(w.Time) {
+		i--
+	}
+	g.Winners = append(g.Winners, Winner{})
+	copy(g.Winners[i+1:], g.Winners[i:])
+	g.Winners[i] = w
+	return true
+}
+
+// SyncConflict is a queued selection that couldn't be merged into a board,
+// and why.
+type SyncConflict struct {
+	Selection Selection `json:"selection"`
+	Reason    string    `json:"reason"`
+}
+
+// SyncResult is the board after queued selections were merged into it, with
+// the selections that were and weren't merged.
+type SyncResult struct {
+	Board     Board          `json:"board"`
+	Applied   []Selection    `json:"applied"`
+	Conflicts []SyncConflict `json:"conflicts"`
+}
+
+// JSON marshalls the content of a sync result to json.
+func (s SyncResult) JSON() (string, error) {
+	bytes, err := json.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal json for response: %s", err)
+	}
+
+	return string(bytes), nil
+}
+
+// Merge works out which selections a client queued while offline can still
+// be made on a board, in the order they were made. Times from the client's
+// clock are kept between the board being dealt and now. A selection loses to
+// anything that happened to its square after it: the square leaving the
+// board, an admin rewording the phrase and clearing its selections, a new
+// round starting, or a newer change recorded from elsewhere.
+func (g Game) Merge(board Board, queued []Selection, now time.Time) ([]Selection, []SyncConflict) {
+	accepted := []Selection{}
+	conflicts := []SyncConflict{}
+
+	sorted := append([]Selection{}, queued...)
+	for i, v := range sorted {
+		if v.Time.IsZero() || v.Time.After(now) {
+			v.Time = now
+		}
+		if v.Time.Before(board.Joined) {
+			v.Time = board.Joined
+		}
+		sorted[i] = v
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	for _, v := range sorted {
+		reason := ""
+		p, ok := board.Phrases[v.Phrase]
+		_, r := g.FindRecord(p)
+
+		switch {
+		case !ok:
+			reason = "phrase is no longer on the board"
+		case p.Free:
+			reason = "free squares can't be changed"
+		case v.Time.Before(g.Started):
+			reason = "selection was made before the current round started"
+		case r.Edited.After(v.Time):
+			reason = "phrase was changed by the game managers after it was selected"
+		case board.changedSince(v.Phrase, v.Time):
+			reason = "a newer change to this square was already recorded"
+		}
+
+		if reason != "" {
+			conflicts = append(conflicts, SyncConflict{v, reason})
+			continue
+		}
+		accepted = append(accepted, v)
+	}
+
+	return accepted, conflicts
+}
+
+// Points works out the score of a board: points for each marked square that
+// other players corroborate and each completed line, bonuses for getting
+// bingo first and quickly, less penalties for rejected claims.
+func (g Game) Points(board Board) int {
+	points := 0
+
+	corroborated := make(map[string]bool)
+	for _, v := range g.Master.Records {
+		corroborated[v.ID] = len(v.Players) > 1
+	}
+
+	selected := make(map[int]bool)
+	for _, v := range board.Phrases {
+		if !v.Selected {
+			continue
+		}
+		selected[v.DisplayOrder] = true
+		if !v.Free && corroborated[v.ID] {
+			points += pointsPerSquare
+		}
+	}
+
+	for _, line := range boardLines {
+		complete := true
+		for _, i := range line {
+			if !selected[i] {
+				complete = false
+				break
+			}
+		}
+		if complete {
+			points += pointsPerLine
+		}
+	}
+
+	for i, v := range g.Winners {
+		if v.Board != board.ID {
+			continue
+		}
+		if i == 0 {
+			points += pointsFirstBingo
+		}
+		started := g.Started
+		if started.IsZero() {
+			started = g.Created
+		}
+		minutes := int(v.Time.Sub(started).Minutes())
+		if minutes < pointsSpeed {
+			points += pointsSpeed - minutes
+		}
+	}
+
+	return points - board.Rejected*g.RejectionPenalty()
+}
+
+// RejectionPenalty is the points taken off a board for each rejected claim.
+// Games where players claim bingo themselves set their own penalty, and games
+// that don't keep score have none.
+func (g Game) RejectionPenalty() int {
+	if !g.Scoring {
+		return 0
+	}
+	if g.Claims {
+		return g.Penalty
+	}
+	return pointsPerRejection
+}
+
+// UpdatePoints works out the score of every board in the game and returns
+// the boards whose score changed.
+func (g *Game) UpdatePoints() []Board {
+	changed := []Board{}
+	for id, b := range g.Boards {
+		points := g.Points(b)
+		if points != b.Points {
+			b.Points = points
+			g.Boards[id] = b
+			changed = append(changed, b)
+		}
+	}
+	return changed
 }
 
-// NewGame initializes a new game object
-func NewGame(name string, player Player, phrases []Phrase) Game {
-	g := Game{}
-	g.ID = uniqueID()
-	g.Name = name
-	g.Active = true
-	g.Created = time.Now().UTC().Truncate(time.Millisecond)
-	g.Boards = make(map[string]Board)
-	g.Admins.Add(player)
-	g.Players.Add(player)
-	g.Master.Load(phrases)
+// Scoreboard lists the boards in a game by their score, highest first.
+func (g Game) Scoreboard() Scoreboard {
+	sb := Scoreboard{}
+	for _, b := range g.Boards {
+		e := ScoreboardEntry{}
+		e.Board = b.ID
+		e.Player = b.Player
+		e.Points = b.Points
+		e.Bingo = b.BingoDeclared
+		sb = append(sb, e)
+	}
 
-	return g
+	sort.SliceStable(sb, func(i, j int) bool {
+		if sb[i].Points != sb[j].Points {
+			return sb[i].Points > sb[j].Points
+		}
+		return sb[i].Player.Name < sb[j].Player.Name
+	})
+
+	return sb
 }
 
-// Obscure will obscure the email address of every email in the game other than
-// the one that is input.
-func (g *Game) Obscure(email string) {
-	g.Players.Obscure(email)
-	g.Admins.Obscure(email)
+// ScoreboardEntry is the score of one board in a game.
+type ScoreboardEntry struct {
+	Board  string `json:"board" firestore:"board"`
+	Player Player `json:"player" firestore:"player"`
+	Points int    `json:"points" firestore:"points"`
+	Bingo  bool   `json:"bingo" firestore:"bingo"`
+}
 
-	for _, v := range g.Master.Records {
-		v.Players.Obscure(email)
+// Scoreboard is a slice of ScoreboardEntry.
+type Scoreboard []ScoreboardEntry
+
+// Obscure will obscure the email of every player other than the one input.
+func (sb Scoreboard) Obscure(email string) {
+	for i := range sb {
+		sb[i].Player.Obscure(email)
 	}
+}
 
-	for i, v := range g.Boards {
-		v.Obscure(email)
-		g.Boards[i] = v
+// JSON marshalls the content of a scoreboard to json.
+func (sb Scoreboard) JSON() (string, error) {
+	bytes, err := json.Marshal(sb)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal json for response: %s", err)
 	}
+
+	return string(bytes), nil
+}
+
+// IsOver reports whether the game has had as many winners as it was set to
+// end after.
+func (g Game) IsOver() bool {
+	return g.EndAfter > 0 && len(g.Winners) >= g.EndAfter
 }
 
 // NewBoard creates a new board for a user.
@@ -111,13 +1025,28 @@ func (g *Game) NewBoard(player Player) Board {
 	b.ID = uniqueID()
 	b.Game = g.ID
 	b.Player = player
-	b.Load(g.Master.Phrases())
+	b.Joined = time.Now().UTC().Truncate(time.Millisecond)
+	g.deal(&b)
 	g.Players.Add(player)
 	g.Boards[b.ID] = b
 
 	return b
 }
 
+// deal lays the phrases of the game out on a board, balancing it if the game
+// asks for that.
+func (g *Game) deal(b *Board) {
+	if !g.Balanced {
+		b.Load(g.Master.Phrases())
+		return
+	}
+
+	rates := g.Master.Rates()
+	target := g.Master.TargetOdds()
+	b.LoadBalanced(g.Master.Phrases(), rates, target)
+	b.Score(rates, target)
+}
+
 // InitBoard creates a new board and inits Phrases
 func InitBoard() Board {
 	b := Board{}
@@ -128,9 +1057,15 @@ func InitBoard() Board {
 // UpdatePhrase will change a given phrase in the master record of phrases.
 func (g *Game) UpdatePhrase(phrase Phrase) {
 	i, r := g.FindRecord(phrase)
+	if i == -1 {
+		return
+	}
 	phrase.Selected = false
+	phrase.Free = r.Phrase.Free
+	phrase.DisplayOrder = r.Phrase.DisplayOrder
 	r.Phrase = phrase
 	r.Players = Players{}
+	r.Edited = time.Now().UTC().Truncate(time.Millisecond)
 	g.Master.Records[i] = r
 
 	for _, b := range g.Boards {
@@ -139,6 +1074,123 @@ func (g *Game) UpdatePhrase(phrase Phrase) {
 
 }
 
+// UpdatePhraseText changes the text of a given phrase in the master record of
+// phrases and on every board, without touching who has selected it.
+func (g *Game) UpdatePhraseText(phrase Phrase) {
+	i, r := g.FindRecord(phrase)
+	if i == -1 {
+		return
+	}
+	r.Phrase.Text = phrase.Text
+	g.Master.Records[i] = r
+
+	for _, b := range g.Boards {
+		b.UpdatePhraseText(phrase)
+	}
+}
+
+// AddPhrase adds a phrase to the master list of the game. Existing boards only
+// pick it up when they are reshuffled or need a replacement for a removed
+// phrase.
+func (g *Game) AddPhrase(phrase Phrase) Record {
+	phrase.Selected = false
+	average := g.Master.AverageRate()
+	g.Master.Load([]Phrase{phrase})
+	i, r := g.FindRecord(phrase)
+	if g.Balanced {
+		// A new phrase has no history, so it's dealt as an average one.
+		r.Rate = average
+		g.Master.Records[i] = r
+	}
+	return r
+}
+
+// RemovePhrase takes a phrase out of the master list of the game. Every board
+// that has it gets a random phrase from the rest of the master list in its
+// place, and those boards are returned.
+func (g *Game) RemovePhrase(phrase Phrase) ([]Board, error) {
+	boards := []Board{}
+	i, _ := g.FindRecord(phrase)
+	if i == -1 {
+		return boards, fmt.Errorf("phrase %s is not in this game", phrase.ID)
+	}
+
+	if len(g.Master.Records)-1 < boardSize {
+		return boards, fmt.Errorf("a game needs at least %d phrases", boardSize)
+	}
+
+	records := []Record{}
+	for _, v := range g.Master.Records {
+		if v.Phrase.ID != phrase.ID {
+			records = append(records, v)
+		}
+	}
+
+	replacements := make(map[string]Phrase)
+	for _, b := range g.Boards {
+		if _, ok := b.Phrases[phrase.ID]; !ok {
+			continue
+		}
+
+		pool := []Phrase{}
+		for _, v := range records {
+			if _, ok := b.Phrases[v.Phrase.ID]; !ok && !v.Phrase.Free {
+				pool = append(pool, v.Phrase)
+			}
+		}
+
+		if len(pool) == 0 {
+			return boards, fmt.Errorf("no spare phrase to replace %s on board %s", phrase.ID, b.ID)
+		}
+		replacements[b.ID] = pool[rand.Intn(len(pool))]
+	}
+
+	g.Master.Records = records
+
+	for id, replacement := range replacements {
+		b := g.Boards[id]
+		b.ReplacePhrase(phrase, replacement)
+		b.Bingo()
+		g.Boards[id] = b
+		boards = append(boards, b)
+	}
+
+	return boards, nil
+}
+
+// Reshuffle deals every board a new layout from the master list. Squares a
+// player already marked stay marked as long as they are still on the board.
+func (g *Game) Reshuffle() {
+	for id, b := range g.Boards {
+		b.Reshuffle(g.deal)
+		g.Boards[id] = b
+	}
+
+	records := []Record{}
+	for _, r := range g.Master.Records {
+		players := Players{}
+		for _, p := range r.Players {
+			if b, ok := g.FindBoard(p); ok && b.Phrases[r.ID].Selected {
+				players.Add(p)
+			}
+		}
+		r.Players = players
+		r.Phrase.Selected = len(players) > 0
+		records = append(records, r)
+	}
+	g.Master.Records = records
+}
+
+// FindBoard retrieves the board of a particular player.
+func (g Game) FindBoard(player Player) (Board, bool) {
+	for _, v := range g.Boards {
+		if v.Player.Email == player.Email {
+			return v, true
+		}
+	}
+	return Board{}, false
+}
+
 // DeleteBoard removes a board from the game.
 func (g *Game) DeleteBoard(board Board) {
 	g.Master.RemovePlayer(board.Player)
@@ -211,7 +1263,7 @@ func (g *Game) CheckBoard(board Board) Reports {
 	total := len(g.Players)
 
 	for _, v := range board.Phrases {
-		if v.Selected && v.Text != "FREE" {
+		if v.Selected && !v.Free {
 
 			_, record := g.FindRecord(v)
 			r := Report{}
@@ -270,6 +1322,104 @@ type Master struct {
 	Records []Record `json:"record" firestore:"records"`
 }
 
+// SetFree replaces the free squares in the master list with the ones passed
+// in. Passing no phrases results in a game without a free square.
+func (m *Master) SetFree(free []Phrase) {
+	records := []Record{}
+	for _, v := range m.Records {
+		if !v.Phrase.Free {
+			records = append(records, v)
+		}
+	}
+	m.Records = records
+
+	for _, v := range free {
+		v.Free = true
+		v.Selected = false
+		m.Load([]Phrase{v})
+	}
+}
+
+// SetRates records how often each phrase gets selected, keyed by phrase id.
+// Phrases with no rate get the average of the rest, and free squares are
+// always selected.
+func (m *Master) SetRates(rates map[string]float64) {
+	total, known := 0.0, 0
+	for _, v := range m.Records {
+		if rate, ok := rates[v.ID]; ok && !v.Phrase.Free {
+			total += rate
+			known++
+		}
+	}
+
+	average := defaultPhraseRate
+	if known > 0 {
+		average = total / float64(known)
+	}
+
+	for i, v := range m.Records {
+		rate, ok := rates[v.ID]
+		switch {
+		case v.Phrase.Free:
+			rate = 1
+		case !ok:
+			rate = average
+		}
+		m.Records[i].Rate = rate
+	}
+}
+
+// Rates returns how often each phrase gets selected, keyed by phrase id.
+func (m Master) Rates() map[string]float64 {
+	rates := make(map[string]float64)
+	for _, v := range m.Records {
+		rates[v.ID] = v.Rate
+	}
+	return rates
+}
+
+// AverageRate is how often a phrase that isn't free gets selected, on
+// average.
+func (m Master) AverageRate() float64 {
+	total, count := m.rates()
+	if count == 0 {
+		return defaultPhraseRate
+	}
+	return total / float64(count)
+}
+
+func (m Master) rates() (float64, int) {
+	total, count := 0.0, 0
+	for _, v := range m.Records {
+		if !v.Phrase.Free {
+			total += v.Rate
+			count++
+		}
+	}
+	return total, count
+}
+
+// TargetOdds is the odds of bingo on an average board: one with the free
+// squares in place and every other square at the average rate.
+func (m Master) TargetOdds() float64 {
+	total, count := m.rates()
+	if count == 0 {
+		return 0
+	}
+
+	squares := [boardSize]float64{}
+	for i := range squares {
+		squares[i] = total / float64(count)
+	}
+	for _, v := range m.Records {
+		if v.Phrase.Free && v.Phrase.DisplayOrder >= 0 && v.Phrase.DisplayOrder < boardSize {
+			squares[v.Phrase.DisplayOrder] = 1
+		}
+	}
+
+	return bingoOdds(squares)
+}
+
 // Load adds the master list of phrases to the game.
 func (m *Master) Load(phrases []Phrase) {
 	for _, v := range phrases {
@@ -289,26 +1439,23 @@ func (m Master) Phrases() []Phrase {
 	return result
 }
 
-// Select marks a phrase as selected by one or more players
+// Select sets whether a player has selected a phrase. Selecting a phrase the
+// player already has, or unselecting one they don't, changes nothing.
 func (m *Master) Select(phrase Phrase, player Player) Record {
 	r := Record{}
 	for i, v := range m.Records {
+		if v.Phrase.ID != phrase.ID {
+			continue
+		}
 
-		if v.Phrase.ID == phrase.ID {
-			if v.Players.IsMember(player) {
-				v.Players.Remove(player)
-
-				if !phrase.Selected {
-					v.Phrase.Selected = false
-				}
-				m.Records[i] = v
-				return v
-			}
-			v.Phrase.Selected = phrase.Selected
-			m.Records[i] = v
-			m.Records[i].Players.Add(player)
-			return v
+		if phrase.Selected {
+			v.Players.Add(player)
+		} else {
+			v.Players.Remove(player)
 		}
+		v.Phrase.Selected = len(v.Players) > 0
+		m.Records[i] = v
+		return v
 	}
 	return r
 }
@@ -325,9 +1472,11 @@ func (m *Master) RemovePlayer(player Player) {
 
 // Record is a structure that keeps track of who has selected which Phrase
 type Record struct {
-	ID      string  `json:"id"  firestore:"id"`
-	Phrase  Phrase  `json:"phrase"  firestore:"phrase"`
-	Players Players `json:"players"  firestore:"players"`
+	ID      string    `json:"id"  firestore:"id"`
+	Phrase  Phrase    `json:"phrase"  firestore:"phrase"`
+	Players Players   `json:"players"  firestore:"players"`
+	Rate    float64   `json:"rate"  firestore:"rate"`
+	Edited  time.Time `json:"edited"  firestore:"edited"`
 }
 
 // Player is a human user who is playing the game.
@@ -397,6 +1546,16 @@ func (ps *Players) Add(player Player) {
 	return
 }
 
+// Find retrieves a player from the list by email.
+func (ps Players) Find(email string) (Player, bool) {
+	for _, v := range ps {
+		if v.Email == email {
+			return v, true
+		}
+	}
+	return Player{"", email}, false
+}
+
 // Sort orders Players by email
 func (ps *Players) Sort() {
 	tmp := *ps
@@ -420,11 +1579,65 @@ func (ps Players) JSON() (string, error) {
 
 // Board is an individual board that the players use to play bingo
 type Board struct {
-	ID            string  `json:"id" firestore:"id"`
-	Game          string  `json:"game" firestore:"game"`
-	Player        Player  `json:"player" firestore:"player"`
-	BingoDeclared bool    `json:"bingodeclared" firestore:"bingodeclared"`
-	Phrases       Phrases `json:"phrases" firestore:"-"`
+	ID            string      `json:"id" firestore:"id"`
+	Game          string      `json:"game" firestore:"game"`
+	Player        Player      `json:"player" firestore:"player"`
+	BingoDeclared bool        `json:"bingodeclared" firestore:"bingodeclared"`
+	Phrases       Phrases     `json:"phrases" firestore:"-"`
+	Odds          float64     `json:"odds" firestore:"odds"`
+	Fairness      float64     `json:"fairness" firestore:"fairness"`
+	Points        int         `json:"points" firestore:"points"`
+	Rejected      int         `json:"rejected" firestore:"rejected"`
+	Joined        time.Time   `json:"joined" firestore:"joined"`
+	Activity      []Selection `json:"activity" firestore:"activity"`
+	Suspicion     int         `json:"suspicion" firestore:"suspicion"`
+	quiet         bool
+}
+
+// Selection is a square being marked or unmarked on a board.
+type Selection struct {
+	Phrase   string    `json:"phrase" firestore:"phrase"`
+	Selected bool      `json:"selected" firestore:"selected"`
+	Time     time.Time `json:"time" firestore:"time"`
+	Key      string    `json:"key,omitempty" firestore:"key,omitempty"`
+}
+
+// AddActivity records a selection on the board, along with the idempotency
+// key of the request that made it if there was one, keeping only the latest
+// activityLimit of them.
+func (b *Board) AddActivity(phrase Phrase, key string, t time.Time) {
+	s := Selection{}
+	s.Phrase = phrase.ID
+	s.Selected = phrase.Selected
+	s.Time = t.UTC().Truncate(time.Millisecond)
+	s.Key = key
+	b.Activity = append(b.Activity, s)
+
+	if len(b.Activity) > activityLimit {
+		b.Activity = b.Activity[len(b.Activity)-activityLimit:]
+	}
+}
+
+// changedSince reports whether the board has recorded a change to a phrase
+// after a given time.
+func (b Board) changedSince(pid string, t time.Time) bool {
+	for _, v := range b.Activity {
+		if v.Phrase == pid && v.Time.After(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasKey reports whether a selection with the idempotency key is among the
+// board's recent activity.
+func (b Board) HasKey(key string) bool {
+	for _, v := range b.Activity {
+		if v.Key == key {
+			return true
+		}
+	}
+	return false
 }
 
 // Obscure obscures the email of the board's player
@@ -471,35 +1684,54 @@ func (b *Board) Bingo() bool {
 	return false
 }
 
-// Select records if a phrase on the board has been selected.
+// Select records if a phrase on the board has been selected. Free squares
+// stay selected no matter what.
 func (b *Board) Select(phrase Phrase) Phrase {
 	v := b.Phrases[phrase.ID]
-	v.Selected = phrase.Selected
+	v.Selected = phrase.Selected || v.Free
 	b.Phrases[phrase.ID] = v
 	return v
 }
 
-// Load adds the phrases to the board and randomly orders them.
+// Load adds the phrases to the board and randomly orders them. Free phrases
+// are pinned to the square set in their DisplayOrder and marked as selected.
+// Only the first boardSize phrases after shuffling make it onto the board.
 func (b *Board) Load(p []Phrase) {
-	rand.Seed(randseedfunc())
-	rand.Shuffle(len(p), func(i, j int) { p[i], p[j] = p[j], p[i] })
+	b.load(p, rand.New(rand.NewSource(randseedfunc())).Shuffle)
+}
 
-	free := 0
-	center := 12
+// load lays the phrases out on the board after shuffling them with shuffle.
+func (b *Board) load(p []Phrase, shuffle func(n int, swap func(i, j int))) {
+	shuffle(len(p), func(i, j int) { p[i], p[j] = p[j], p[i] })
 
 	for i, v := range p {
+		v.Selected = v.Free
+		p[i] = v
+	}
 
-		v.Selected = false
-
-		if v.Text == "FREE" {
-			free = i
-			v.Selected = true
+	free := []string{}
+	for _, v := range p {
+		if v.Free {
+			free = append(free, v.ID)
 		}
-		p[i] = v
+	}
 
+	for _, id := range free {
+		for i, v := range p {
+			if v.ID != id {
+				continue
+			}
+			target := v.DisplayOrder
+			if target >= 0 && target < len(p) && target < boardSize {
+				p[i], p[target] = p[target], p[i]
+			}
+			break
+		}
 	}
 
-	p[free], p[center] = p[center], p[free]
+	if len(p) > boardSize {
+		p = p[:boardSize]
+	}
 
 	for i, v := range p {
 		v.Column, v.Row = calcColumnsRows(i)
@@ -510,15 +1742,201 @@ func (b *Board) Load(p []Phrase) {
 
 // UpdatePhrase change the text of a given phrases.
 func (b *Board) UpdatePhrase(phrase Phrase) {
-
-	v := b.Phrases[phrase.ID]
+	v, ok := b.Phrases[phrase.ID]
+	if !ok {
+		return
+	}
 	v.Text = phrase.Text
-	v.Selected = false
+	v.Selected = v.Free
 	b.Phrases[phrase.ID] = v
 
 	return
 }
 
+// UpdatePhraseText changes the text of a given phrase, keeping it selected if
+// it was.
+func (b *Board) UpdatePhraseText(phrase Phrase) {
+	v, ok := b.Phrases[phrase.ID]
+	if !ok {
+		return
+	}
+	v.Text = phrase.Text
+	b.Phrases[phrase.ID] = v
+}
+
+// ReplacePhrase puts a new phrase in the square taken by an old one.
+func (b *Board) ReplacePhrase(old, new Phrase) Phrase {
+	v := b.Phrases[old.ID]
+	delete(b.Phrases, old.ID)
+
+	new.Selected = new.Free
+	new.Column = v.Column
+	new.Row = v.Row
+	new.DisplayOrder = v.DisplayOrder
+	b.Phrases[new.ID] = new
+
+	return new
+}
+
+// OneAway returns the phrases that would each complete a line on the board
+// if they were selected.
+func (b Board) OneAway() []Phrase {
+	squares := make(map[int]Phrase)
+	for _, v := range b.Phrases {
+		squares[v.DisplayOrder] = v
+	}
+
+	missing := []Phrase{}
+	seen := make(map[string]bool)
+	for _, line := range boardLines {
+		unselected := []Phrase{}
+		for _, i := range line {
+			if v, ok := squares[i]; !ok || !v.Selected {
+				unselected = append(unselected, v)
+			}
+		}
+		if len(unselected) == 1 && unselected[0].ID != "" && !seen[unselected[0].ID] {
+			seen[unselected[0].ID] = true
+			missing = append(missing, unselected[0])
+		}
+	}
+
+	sort.Slice(missing, func(i, j int) bool { return missing[i].DisplayOrder < missing[j].DisplayOrder })
+	return missing
+}
+
+// CountOneAway counts the boards in the game that are one square away from
+// bingo and don't have it already.
+func (g Game) CountOneAway() int {
+	count := 0
+	for _, b := range g.Boards {
+		if !b.BingoDeclared && len(b.OneAway()) > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// LoadBalanced deals the phrases onto the board several times and keeps the
+// deal whose odds of bingo are closest to the target, passing over any deal
+// with a line made up only of phrases that almost always happen.
+func (b *Board) LoadBalanced(p []Phrase, rates map[string]float64, target float64) {
+	var best Phrases
+	bestDiff := math.Inf(1)
+	bestCertain := true
+
+	for i := 0; i < balanceAttempts; i++ {
+		deal := InitBoard()
+		deal.Load(append([]Phrase{}, p...))
+
+		certain := deal.hasCertainLine(rates)
+		diff := math.Abs(deal.odds(rates) - target)
+
+		if (bestCertain && !certain) || (certain == bestCertain && diff < bestDiff) {
+			best, bestDiff, bestCertain = deal.Phrases, diff, certain
+		}
+	}
+
+	b.Phrases = best
+}
+
+// Score records the odds of bingo on the board, and how fair those odds are
+// compared to the target odds of an average board, from 0 to 1.
+func (b *Board) Score(rates map[string]float64, target float64) {
+	b.Odds = b.odds(rates)
+	b.Fairness = 1
+	if target > 0 {
+		b.Fairness = math.Max(0, 1-math.Abs(b.Odds-target)/target)
+	}
+}
+
+func (b Board) squares(rates map[string]float64) [boardSize]float64 {
+	squares := [boardSize]float64{}
+	for _, v := range b.Phrases {
+		if v.DisplayOrder < 0 || v.DisplayOrder >= boardSize {
+			continue
+		}
+		rate := rates[v.ID]
+		if v.Free {
+			rate = 1
+		}
+		squares[v.DisplayOrder] = rate
+	}
+	return squares
+}
+
+func (b Board) odds(rates map[string]float64) float64 {
+	return bingoOdds(b.squares(rates))
+}
+
+func (b Board) hasCertainLine(rates map[string]float64) bool {
+	certain := make(map[int]bool)
+	for _, v := range b.Phrases {
+		if !v.Free && rates[v.ID] >= certainRate {
+			certain[v.DisplayOrder] = true
+		}
+	}
+
+	free := make(map[int]bool)
+	for _, v := range b.Phrases {
+		if v.Free {
+			free[v.DisplayOrder] = true
+		}
+	}
+
+	for _, line := range boardLines {
+		count := 0
+		for _, i := range line {
+			if certain[i] {
+				count++
+			} else if !free[i] {
+				count = -1
+				break
+			}
+		}
+		if count > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// bingoOdds works out the odds of completing at least one line, given the
+// odds of each square being selected, treating the lines as independent.
+func bingoOdds(squares [boardSize]float64) float64 {
+	none := 1.0
+	for _, line := range boardLines {
+		odds := 1.0
+		for _, i := range line {
+			odds *= squares[i]
+		}
+		none *= 1 - odds
+	}
+	return 1 - none
+}
+
+// Reshuffle lays the board out again from scratch with deal, keeping the
+// marks on the phrases that are still on it.
+func (b *Board) Reshuffle(deal func(*Board)) {
+	selected := make(map[string]bool)
+	for _, v := range b.Phrases {
+		if v.Selected {
+			selected[v.ID] = true
+		}
+	}
+
+	b.Phrases = make(map[string]Phrase)
+	deal(b)
+
+	for id := range selected {
+		if v, ok := b.Phrases[id]; ok {
+			v.Selected = true
+			b.Phrases[id] = v
+		}
+	}
+	b.Bingo()
+}
+
 // Print prints out the board for debugging
 func (b *Board) Print() {
 
@@ -595,6 +2013,24 @@ type Phrase struct {
 	Row          string `json:"row" firestore:"row"`
 	Column       string `json:"column" firestore:"column"`
 	DisplayOrder int    `json:"displayorder" firestore:"displayorder"`
+	Free         bool   `json:"free" firestore:"free"`
+}
+
+// NewFreePhrase returns a free square with the given text pinned to a
+// position on the board.
+func NewFreePhrase(text string, position int) Phrase {
+	p := Phrase{}
+	p.ID = fmt.Sprintf("free-%d", position)
+	p.Text = text
+	p.DisplayOrder = position
+	p.Free = true
+	return p
+}
+
+// DefaultFreeSquares returns the classic single "FREE" square in the center
+// of the board.
+func DefaultFreeSquares() []Phrase {
+	return []Phrase{NewFreePhrase("FREE", boardCenter)}
 }
 
 // Position returns the combined Row and Column of the Phrase
@@ -603,8 +2039,8 @@ func (p Phrase) Position() string {
 }
 
 func (b Board) log(msg string) {
-	if noisy {
-		log.Printf("Bingo     : %s\n", msg)
+	if !b.quiet {
+		logger.Debug(context.Background(), "bingo", msg)
 	}
 }
 
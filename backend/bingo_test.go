@@ -15,7 +15,9 @@
 package main
 
 import (
+	"math"
 	"testing"
+	"time"
 )
 
 func TestBoardBingo(t *testing.T) {
@@ -54,6 +56,444 @@ func TestBoardBingo(t *testing.T) {
 	}
 }
 
+func TestBoardOneAway(t *testing.T) {
+	pl := Player{"Test", "test@example.com"}
+	game := NewGame("test name", pl, getTestPhrases())
+	b := game.NewBoard(pl)
+
+	squares := make(map[int]Phrase)
+	for _, v := range b.Phrases {
+		squares[v.DisplayOrder] = v
+	}
+
+	if got := b.OneAway(); len(got) != 0 {
+		t.Errorf("Board.OneAway() empty board want %d got %d", 0, len(got))
+	}
+
+	// The center is free, so three more on the middle row leaves it one away.
+	for _, i := range []int{10, 11, 13} {
+		b.Select(Phrase{ID: squares[i].ID, Selected: true})
+	}
+
+	got := b.OneAway()
+	if len(got) != 1 || got[0].ID != squares[14].ID {
+		t.Errorf("Board.OneAway() want %s got %+v", squares[14].ID, got)
+	}
+
+	if count := game.CountOneAway(); count != 1 {
+		t.Errorf("Game.CountOneAway() want %d got %d", 1, count)
+	}
+
+	b.Select(Phrase{ID: squares[14].ID, Selected: true})
+	if got := b.OneAway(); len(got) != 0 {
+		t.Errorf("Board.OneAway() completed line want %d got %d", 0, len(got))
+	}
+}
+
+func TestGameAddWinner(t *testing.T) {
+	now := time.Now()
+	game := Game{}
+	game.EndAfter = 2
+	b1 := Board{ID: "1", Player: Player{"Test1", "test1@example.com"}}
+	b2 := Board{ID: "2", Player: Player{"Test2", "test2@example.com"}}
+
+	if !game.AddWinner(b1, now) {
+		t.Errorf("Game.AddWinner() want first winner added")
+	}
+
+	if game.AddWinner(b1, now.Add(time.Second)) {
+		t.Errorf("Game.AddWinner() want repeat winner skipped")
+	}
+
+	if game.IsOver() {
+		t.Errorf("Game.IsOver() want false with %d of %d winners", len(game.Winners), game.EndAfter)
+	}
+
+	game.AddWinner(b2, now.Add(time.Second))
+
+	if len(game.Winners) != 2 || game.Winners[0].Board != "1" || game.Winners[1].Board != "2" {
+		t.Errorf("Game.AddWinner() want winners in order got %+v", game.Winners)
+	}
+
+	if !game.IsOver() {
+		t.Errorf("Game.IsOver() want true with %d of %d winners", len(game.Winners), game.EndAfter)
+	}
+
+	b3 := Board{ID: "3", Player: Player{"Test3", "test3@example.com"}}
+	game.AddWinner(b3, now.Add(-time.Second))
+	if len(game.Winners) != 3 || game.Winners[0].Board != "3" || game.Winners[2].Board != "2" {
+		t.Errorf("Game.AddWinner() want a late winner placed by time got %+v", game.Winners)
+	}
+	game.Winners = game.Winners[1:]
+
+	game.Obscure("test2@example.com")
+	if game.Winners[0].Player.Email == "test1@example.com" || game.Winners[1].Player.Email != "test2@example.com" {
+		t.Errorf("Game.Obscure() did not obscure winners: %+v", game.Winners)
+	}
+}
+
+func TestGamePoints(t *testing.T) {
+	p1 := Player{"Test1", "test1@example.com"}
+	p2 := Player{"Test2", "test2@example.com"}
+	game := NewGame("test name", p1, getTestPhrases())
+	game.Scoring = true
+	b1 := game.NewBoard(p1)
+	b2 := game.NewBoard(p2)
+
+	squares := make(map[int]Phrase)
+	for _, v := range b1.Phrases {
+		squares[v.DisplayOrder] = v
+	}
+
+	// Complete the middle row on the first board, the center being free.
+	for _, i := range []int{10, 11, 13, 14} {
+		p := b1.Select(Phrase{ID: squares[i].ID, Selected: true})
+		game.Select(p, p1)
+	}
+
+	// The second player corroborates one of those squares.
+	p := b2.Select(Phrase{ID: squares[10].ID, Selected: true})
+	game.Select(p, p2)
+
+	game.Boards[b1.ID] = b1
+	game.Boards[b2.ID] = b2
+
+	if got, want := game.Points(b1), pointsPerSquare+pointsPerLine; got != want {
+		t.Errorf("Game.Points() want %d got %d", want, got)
+	}
+
+	game.AddWinner(b1, game.Created.Add(10*time.Minute))
+	if got, want := game.Points(b1), pointsPerSquare+pointsPerLine+pointsFirstBingo+pointsSpeed-10; got != want {
+		t.Errorf("Game.Points() with bingo want %d got %d", want, got)
+	}
+
+	b2.Rejected = 1
+	game.Boards[b2.ID] = b2
+	if got, want := game.Points(b2), pointsPerSquare-pointsPerRejection; got != want {
+		t.Errorf("Game.Points() with rejection want %d got %d", want, got)
+	}
+
+	changed := game.UpdatePoints()
+	if len(changed) != 2 {
+		t.Errorf("Game.UpdatePoints() changed want %d got %d", 2, len(changed))
+	}
+
+	if changed := game.UpdatePoints(); len(changed) != 0 {
+		t.Errorf("Game.UpdatePoints() unchanged want %d got %d", 0, len(changed))
+	}
+
+	sb := game.Scoreboard()
+	if len(sb) != 2 || sb[0].Board != b1.ID || sb[1].Points != pointsPerSquare-pointsPerRejection {
+		t.Errorf("Game.Scoreboard() got %+v", sb)
+	}
+}
+
+func TestGameSuspicion(t *testing.T) {
+	p1 := Player{"Test1", "test1@example.com"}
+	p2 := Player{"Test2", "test2@example.com"}
+	p3 := Player{"Test3", "test3@example.com"}
+	game := NewGame("test name", p1, getTestPhrases())
+	b1 := game.NewBoard(p1)
+	game.NewBoard(p2)
+	game.NewBoard(p3)
+
+	phrases := []Phrase{}
+	for _, v := range b1.Phrases {
+		if !v.Free {
+			phrases = append(phrases, v)
+		}
+	}
+
+	joined := game.Started.Add(time.Hour)
+	b1.Joined = joined
+
+	cases := []struct {
+		label   string
+		offset  []time.Duration
+		toggle  bool
+		started time.Duration
+		want    Suspicion
+	}{
+		{"slow", []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute}, false, 0, Suspicion{Unique: 3}},
+		{"burst", []time.Duration{time.Minute, time.Minute + time.Second, time.Minute + 2*time.Second, time.Minute + 3*time.Second}, false, 0, Suspicion{Bursts: 1, Unique: 4}},
+		{"early", []time.Duration{time.Second, 5 * time.Minute}, false, 0, Suspicion{Early: 1, Unique: 2}},
+		{"toggle", []time.Duration{time.Minute, 2 * time.Minute}, true, 0, Suspicion{Toggles: 1, Unique: 1}},
+		{"round start", []time.Duration{10*time.Minute + time.Second, 15 * time.Minute}, false, 10 * time.Minute, Suspicion{Unique: 2}},
+	}
+
+	for _, c := range cases {
+		g := NewGame("test name", p1, getTestPhrases())
+		g.Started = game.Started
+		if c.started > 0 {
+			g.Started = joined.Add(c.started)
+		}
+		g.Players = game.Players
+		b := b1
+		b.Phrases = make(map[string]Phrase)
+		for k, v := range b1.Phrases {
+			b.Phrases[k] = v
+		}
+		b.Activity = nil
+
+		for i, offset := range c.offset {
+			p := b.Select(Phrase{ID: phrases[i].ID, Selected: true})
+			g.Select(p, p1)
+			b.AddActivity(p, "", joined.Add(offset))
+		}
+
+		if c.toggle {
+			p := b.Select(Phrase{ID: phrases[0].ID, Selected: false})
+			g.Select(p, p1)
+			b.AddActivity(p, "", joined.Add(3*time.Minute))
+		}
+
+		got := g.Suspicion(b)
+		if got.Bursts != c.want.Bursts || got.Unique != c.want.Unique || got.Toggles != c.want.Toggles || got.Early != c.want.Early {
+			t.Errorf("%s: Game.Suspicion() want %+v got %+v", c.label, c.want, got)
+		}
+
+		score := c.want.Bursts*suspicionPerBurst + c.want.Unique*suspicionPerUnique + c.want.Toggles*suspicionPerToggle + c.want.Early*suspicionPerEarly
+		if got.Score != score {
+			t.Errorf("%s: Game.Suspicion() score want %d got %d", c.label, score, got.Score)
+		}
+	}
+
+	clicked := phrases[0]
+	clicked.Selected = true
+	for i := 0; i < activityLimit+10; i++ {
+		b1.AddActivity(clicked, "", joined)
+	}
+	if len(b1.Activity) != activityLimit {
+		t.Errorf("Board.AddActivity() want %d selections kept got %d", activityLimit, len(b1.Activity))
+	}
+
+	game.Boards[b1.ID] = b1
+	if s := game.Suspicions(); len(s) != 3 || s[0].Board != b1.ID || s[0].Score != maxSuspicion {
+		t.Errorf("Game.Suspicions() want %s first got %+v", b1.ID, s)
+	}
+
+	game.Obscure(p2.Email)
+	if got := game.Boards[b1.ID]; len(got.Activity) != 0 {
+		t.Errorf("Game.Obscure() did not hide activity: %+v", got.Activity)
+	}
+}
+
+func TestGameMerge(t *testing.T) {
+	p1 := Player{"Test1", "test1@example.com"}
+	game := NewGame("test name", p1, getTestPhrases())
+	board := game.NewBoard(p1)
+	now := board.Joined.Add(time.Hour)
+
+	phrases := []Phrase{}
+	free := Phrase{}
+	for _, v := range board.Phrases {
+		if v.Free {
+			free = v
+			continue
+		}
+		phrases = append(phrases, v)
+	}
+
+	edited := phrases[2]
+	game.UpdatePhrase(edited)
+	i, r := game.FindRecord(edited)
+	r.Edited = board.Joined.Add(30 * time.Minute)
+	game.Master.Records[i] = r
+
+	board.AddActivity(Phrase{ID: phrases[3].ID, Selected: true}, "", board.Joined.Add(40*time.Minute))
+
+	queued := []Selection{
+		{Phrase: phrases[1].ID, Selected: true, Time: board.Joined.Add(20 * time.Minute)},
+		{Phrase: phrases[0].ID, Selected: true, Time: board.Joined.Add(10 * time.Minute)},
+		{Phrase: "notonboard", Selected: true, Time: board.Joined.Add(10 * time.Minute)},
+		{Phrase: free.ID, Selected: false, Time: board.Joined.Add(10 * time.Minute)},
+		{Phrase: edited.ID, Selected: true, Time: board.Joined.Add(20 * time.Minute)},
+		{Phrase: phrases[3].ID, Selected: false, Time: board.Joined.Add(20 * time.Minute)},
+		{Phrase: phrases[4].ID, Selected: true, Time: now.Add(time.Hour)},
+		{Phrase: phrases[5].ID, Selected: true, Time: board.Joined.Add(-time.Hour)},
+	}
+
+	accepted, conflicts := game.Merge(board, queued, now)
+
+	want := []string{phrases[5].ID, phrases[0].ID, phrases[1].ID, phrases[4].ID}
+	if len(accepted) != len(want) {
+		t.Fatalf("Game.Merge() accepted want %d got %d %+v", len(want), len(accepted), accepted)
+	}
+	for i, v := range want {
+		if accepted[i].Phrase != v {
+			t.Errorf("Game.Merge() accepted[%d] want %s got %s", i, v, accepted[i].Phrase)
+		}
+	}
+
+	if !accepted[0].Time.Equal(board.Joined) || !accepted[3].Time.Equal(now) {
+		t.Errorf("Game.Merge() want times kept between %s and %s got %s %s", board.Joined, now, accepted[0].Time, accepted[3].Time)
+	}
+
+	if len(conflicts) != 4 {
+		t.Errorf("Game.Merge() conflicts want %d got %d %+v", 4, len(conflicts), conflicts)
+	}
+
+	game.Started = now
+	if accepted, _ := game.Merge(board, queued[:1], now); len(accepted) != 0 {
+		t.Errorf("Game.Merge() want selections from a past round rejected got %+v", accepted)
+	}
+}
+
+func TestGameRejectionPenalty(t *testing.T) {
+	cases := []struct {
+		label   string
+		scoring bool
+		claims  bool
+		penalty int
+		want    int
+	}{
+		{"auto bingo", true, false, 5, pointsPerRejection},
+		{"claims", true, true, 5, 5},
+		{"claims without penalty", true, true, 0, 0},
+		{"claims without scoring", false, true, 5, 0},
+	}
+
+	for _, c := range cases {
+		p1 := Player{"Test1", "test1@example.com"}
+		game := NewGame("test name", p1, getTestPhrases())
+		game.Scoring = c.scoring
+		game.Claims = c.claims
+		game.Penalty = c.penalty
+		b := game.NewBoard(p1)
+		b.Rejected = 2
+
+		if got := game.RejectionPenalty(); got != c.want {
+			t.Errorf("%s: Game.RejectionPenalty() want %d got %d", c.label, c.want, got)
+		}
+
+		if got := game.Points(b); got != -2*c.want {
+			t.Errorf("%s: Game.Points() want %d got %d", c.label, -2*c.want, got)
+		}
+	}
+}
+
+func TestGameNewRound(t *testing.T) {
+	for _, redeal := range []bool{false, true} {
+		p1 := Player{"Test1", "test1@example.com"}
+		game := NewGame("test name", p1, getTestPhrases())
+		game.Scoring = true
+		b := game.NewBoard(p1)
+
+		for _, v := range b.Phrases {
+			p := b.Select(Phrase{ID: v.ID, Selected: true})
+			game.Select(p, p1)
+		}
+		b.Bingo()
+		game.Boards[b.ID] = b
+		game.AddWinner(b, time.Now())
+		game.UpdatePoints()
+		game.Active = false
+
+		boards := game.NewRound(redeal, time.Now())
+
+		if game.Round != 2 || len(game.Rounds) != 1 {
+			t.Errorf("Game.NewRound(%t) round want %d of %d got %d of %d", redeal, 2, 1, game.Round, len(game.Rounds))
+		}
+
+		if len(game.Rounds[0].Winners) != 1 || len(game.Rounds[0].Scores) != 1 || game.Rounds[0].Scores[0].Points == 0 {
+			t.Errorf("Game.NewRound(%t) did not keep the results of the round: %+v", redeal, game.Rounds[0])
+		}
+
+		if len(game.Winners) != 0 || !game.Active {
+			t.Errorf("Game.NewRound(%t) want active game with no winners got %t %d", redeal, game.Active, len(game.Winners))
+		}
+
+		if len(game.Players) != 1 {
+			t.Errorf("Game.NewRound(%t) players want %d got %d", redeal, 1, len(game.Players))
+		}
+
+		for _, v := range game.Master.Records {
+			if len(v.Players) != 0 || v.Phrase.Selected {
+				t.Errorf("Game.NewRound(%t) record %s still selected", redeal, v.ID)
+			}
+		}
+
+		if len(boards) != 1 {
+			t.Fatalf("Game.NewRound(%t) boards want %d got %d", redeal, 1, len(boards))
+		}
+
+		if boards[0].BingoDeclared || boards[0].Points != 0 || len(boards[0].Phrases) != boardSize {
+			t.Errorf("Game.NewRound(%t) board not reset: %+v", redeal, boards[0])
+		}
+
+		for _, v := range boards[0].Phrases {
+			if v.Selected != v.Free {
+				t.Errorf("Game.NewRound(%t) phrase %s selected %t", redeal, v.ID, v.Selected)
+			}
+		}
+	}
+}
+
+func TestTournamentGames(t *testing.T) {
+	tournament := NewTournament("test tournament")
+	tournament.AddGame("1")
+	tournament.AddGame("2")
+	tournament.AddGame("1")
+
+	if len(tournament.Games) != 2 {
+		t.Errorf("Tournament.AddGame() games want %d got %d", 2, len(tournament.Games))
+	}
+
+	if err := tournament.RemoveGame("1"); err != nil {
+		t.Errorf("Tournament.RemoveGame() err want %v got %s ", nil, err)
+	}
+
+	if tournament.HasGame("1") || !tournament.HasGame("2") {
+		t.Errorf("Tournament.RemoveGame() games got %v", tournament.Games)
+	}
+
+	if err := tournament.RemoveGame("3"); err == nil {
+		t.Errorf("Tournament.RemoveGame() expected an error removing a game not in the tournament")
+	}
+}
+
+func TestNewStandings(t *testing.T) {
+	p1 := Player{"Test1", "test1@example.com"}
+	p2 := Player{"Test2", "test2@example.com"}
+	now := time.Now()
+
+	g1 := NewGame("game 1", p1, getTestPhrases())
+	b1 := g1.NewBoard(p1)
+	b1.Points = 30
+	g1.Boards[b1.ID] = b1
+	b2 := g1.NewBoard(p2)
+	b2.Points = 50
+	g1.Boards[b2.ID] = b2
+	g1.AddWinner(b2, now)
+	g1.AddWinner(b1, now.Add(time.Minute))
+
+	g2 := NewGame("game 2", p1, getTestPhrases())
+	b3 := g2.NewBoard(p1)
+	g2.Boards[b3.ID] = b3
+	g2.Rounds = []Round{{
+		Number:  1,
+		Winners: []Winner{{Board: b3.ID, Player: p1, Time: now}},
+		Scores:  Scoreboard{{Board: b3.ID, Player: p1, Points: 40}},
+	}}
+
+	st := NewStandings([]Game{g1, g2})
+
+	if len(st) != 2 {
+		t.Fatalf("NewStandings() count want %d got %d", 2, len(st))
+	}
+
+	want := Standings{
+		{Player: p1, Games: 2, Bingos: 2, Wins: 1, Points: 70},
+		{Player: p2, Games: 1, Bingos: 1, Wins: 1, Points: 50},
+	}
+
+	for i, v := range want {
+		if st[i] != v {
+			t.Errorf("NewStandings() [%d] want %+v got %+v", i, v, st[i])
+		}
+	}
+}
+
 func TestBoardLoad(t *testing.T) {
 	phrases := getTestPhrases()
 
@@ -86,6 +526,69 @@ func TestBoardLoad(t *testing.T) {
 	}
 }
 
+func TestBoardLoadFreeSquares(t *testing.T) {
+	cases := []struct {
+		label string
+		free  []Phrase
+	}{
+		{"None", []Phrase{}},
+		{"Center", DefaultFreeSquares()},
+		{"Custom text", []Phrase{NewFreePhrase("Someone says 'synergy'", 12)}},
+		{"Corners", []Phrase{NewFreePhrase("FREE", 0), NewFreePhrase("FREE", 4), NewFreePhrase("FREE", 20), NewFreePhrase("FREE", 24)}},
+	}
+
+	for _, c := range cases {
+		phrases := []Phrase{}
+		for _, v := range getTestPhrases() {
+			if !v.Free {
+				phrases = append(phrases, v)
+			}
+		}
+		phrases = append(phrases, Phrase{"26", "Filler 26", false, "", "", 0, false})
+		phrases = append(phrases, c.free...)
+
+		b := InitBoard()
+		b.Load(phrases)
+
+		if len(b.Phrases) != boardSize {
+			t.Errorf("Board.Load(%s) squares got %d, want %d", c.label, len(b.Phrases), boardSize)
+		}
+
+		selected := 0
+		for _, v := range b.Phrases {
+			if v.Selected {
+				selected++
+			}
+		}
+		if selected != len(c.free) {
+			t.Errorf("Board.Load(%s) selected got %d, want %d", c.label, selected, len(c.free))
+		}
+
+		for _, v := range c.free {
+			got, ok := b.Phrases[v.ID]
+			if !ok {
+				t.Errorf("Board.Load(%s) free square %s missing from board", c.label, v.ID)
+				continue
+			}
+			if got.DisplayOrder != v.DisplayOrder || !got.Selected || got.Text != v.Text {
+				t.Errorf("Board.Load(%s) free square got %+v, want %+v", c.label, got, v)
+			}
+		}
+	}
+}
+
+func TestBoardSelectFreeSquare(t *testing.T) {
+	board := getTestBoard()
+	phrase := board.Phrases["13"]
+	phrase.Selected = false
+
+	got := board.Select(phrase)
+
+	if !got.Selected {
+		t.Errorf("Board.Select() free square selected got %t, want %t", got.Selected, true)
+	}
+}
+
 func TestRowCalc(t *testing.T) {
 	cases := []struct {
 		in     int
@@ -132,17 +635,19 @@ func TestRowCalc(t *testing.T) {
 
 func TestBoardPhraseUpdate(t *testing.T) {
 	board := getTestBoard()
-	phrase := Phrase{"1", "Test Phrase", false, "", "", 0}
+	phrase := Phrase{"1", "Test Phrase", false, "", "", 0, false}
 
 	board.UpdatePhrase(phrase)
 
-	for _, v := range board.Phrases {
-		if v.ID == phrase.ID {
-			if v.Text != phrase.Text {
-				t.Errorf("Board.UpdatePhrase() got %s, want %s", v.Text, phrase.Text)
-			}
-			return
-		}
+	if got := board.Phrases[phrase.ID].Text; got != phrase.Text {
+		t.Errorf("Board.UpdatePhrase() got %s, want %s", got, phrase.Text)
+	}
+
+	missing := Phrase{"99", "Not on this board", false, "", "", 0, false}
+	board.UpdatePhrase(missing)
+
+	if _, ok := board.Phrases[missing.ID]; ok {
+		t.Errorf("Board.UpdatePhrase() should not add a phrase the board wasn't dealt")
 	}
 }
 
@@ -191,6 +696,251 @@ func TestPlayerAddAndRemove(t *testing.T) {
 	}
 }
 
+func TestPlayersFind(t *testing.T) {
+	players := Players{}
+	players.Add(Player{"Test", "test@example.com"})
+
+	got, ok := players.Find("test@example.com")
+	if !ok || got.Name != "Test" {
+		t.Errorf("Players.Find() got %+v %t, want %s %t", got, ok, "Test", true)
+	}
+
+	got, ok = players.Find("test2@example.com")
+	if ok || got.Email != "test2@example.com" {
+		t.Errorf("Players.Find() got %+v %t, want %s %t", got, ok, "test2@example.com", false)
+	}
+}
+
+func TestCandidateVote(t *testing.T) {
+	author := Player{"Author", "author@example.com"}
+	voter := Player{"Voter", "voter@example.com"}
+	c := NewCandidate(author, Phrase{ID: "1", Text: "Test Phrase"})
+
+	if err := c.Vote(author, true); err == nil {
+		t.Errorf("Candidate.Vote() expected an error voting for your own phrase")
+	}
+
+	if err := c.Vote(voter, true); err != nil {
+		t.Errorf("Candidate.Vote() err want %v got %s ", nil, err)
+	}
+
+	if err := c.Vote(voter, true); err != nil {
+		t.Errorf("Candidate.Vote() err want %v got %s ", nil, err)
+	}
+
+	if len(c.Votes) != 1 {
+		t.Errorf("Candidate.Vote() votes want %d got %d", 1, len(c.Votes))
+	}
+
+	if err := c.Vote(voter, false); err != nil {
+		t.Errorf("Candidate.Vote() err want %v got %s ", nil, err)
+	}
+
+	if len(c.Votes) != 0 {
+		t.Errorf("Candidate.Vote() votes want %d got %d", 0, len(c.Votes))
+	}
+}
+
+func TestCandidatesTop(t *testing.T) {
+	now := time.Now()
+	p1 := Player{"Test1", "test1@example.com"}
+	p2 := Player{"Test2", "test2@example.com"}
+
+	cs := Candidates{
+		{ID: "1", Phrase: Phrase{ID: "1"}, Created: now, Votes: Players{p1}},
+		{ID: "2", Phrase: Phrase{ID: "2"}, Created: now.Add(time.Second), Votes: Players{p1, p2}},
+		{ID: "3", Phrase: Phrase{ID: "3"}, Created: now.Add(2 * time.Second), Votes: Players{}},
+		{ID: "4", Phrase: Phrase{ID: "4"}, Created: now.Add(-time.Second), Votes: Players{p2}},
+	}
+
+	want := []string{"2", "4", "1"}
+	got := cs.Top(3)
+
+	if len(got) != len(want) {
+		t.Fatalf("Candidates.Top() count want %d got %d", len(want), len(got))
+	}
+
+	for i, v := range want {
+		if got[i].ID != v {
+			t.Errorf("Candidates.Top() [%d] want %s got %s", i, v, got[i].ID)
+		}
+	}
+
+	if cs[0].ID != "1" {
+		t.Errorf("Candidates.Top() should not reorder the candidates")
+	}
+}
+
+func TestGameStart(t *testing.T) {
+	p1 := Player{"Test1", "test1@example.com"}
+	p2 := Player{"Test2", "test2@example.com"}
+
+	game := NewLobbyGame("test name", p1)
+	game.Master.SetFree(DefaultFreeSquares())
+	game.Players.Add(p2)
+
+	if !game.Lobby {
+		t.Errorf("NewLobbyGame() expected game in the lobby")
+	}
+
+	phrases := []Phrase{}
+	for _, v := range getTestPhrases() {
+		if v.ID != "13" {
+			v.Free = false
+			phrases = append(phrases, v)
+		}
+	}
+
+	boards := game.Start(phrases, nil)
+
+	if game.Lobby {
+		t.Errorf("Game.Start() expected game out of the lobby")
+	}
+
+	if len(game.Master.Records) != boardSize {
+		t.Errorf("Game.Start() records want %d got %d", boardSize, len(game.Master.Records))
+	}
+
+	if len(boards) != 2 || len(game.Boards) != 2 {
+		t.Errorf("Game.Start() boards want %d got %d", 2, len(boards))
+	}
+
+	for _, b := range boards {
+		if len(b.Phrases) != boardSize {
+			t.Errorf("Game.Start() board phrases want %d got %d", boardSize, len(b.Phrases))
+		}
+	}
+}
+
+func TestNewPhraseStats(t *testing.T) {
+	phrases := []Phrase{
+		{ID: "1", Text: "Always"},
+		{ID: "2", Text: "Sometimes"},
+		{ID: "3", Text: "Never dealt"},
+		{ID: "4", Text: "FREE", Free: true},
+	}
+	boards := map[string]int{"1": 4, "2": 4, "4": 4}
+	selections := map[string]int{"1": 5, "2": 1, "4": 4}
+
+	ps := NewPhraseStats(phrases, boards, selections)
+
+	if len(ps) != 3 {
+		t.Fatalf("NewPhraseStats() count want %d got %d", 3, len(ps))
+	}
+
+	want := []float64{1, 0.25, 0}
+	for i, v := range want {
+		if ps[i].Rate != v {
+			t.Errorf("NewPhraseStats() rate for %s want %f got %f", ps[i].Phrase.ID, v, ps[i].Rate)
+		}
+	}
+
+	ps.SortByDifficulty()
+
+	order := []string{"2", "1", "3"}
+	for i, v := range order {
+		if ps[i].Phrase.ID != v {
+			t.Errorf("PhraseStats.SortByDifficulty() [%d] want %s got %s", i, v, ps[i].Phrase.ID)
+		}
+	}
+}
+
+func TestBingoOdds(t *testing.T) {
+	squares := [boardSize]float64{}
+	if got := bingoOdds(squares); got != 0 {
+		t.Errorf("bingoOdds() empty board want %f got %f", 0.0, got)
+	}
+
+	for i := range squares {
+		squares[i] = 1
+	}
+	if got := bingoOdds(squares); got != 1 {
+		t.Errorf("bingoOdds() full board want %f got %f", 1.0, got)
+	}
+
+	squares = [boardSize]float64{}
+	for _, i := range boardLines[0] {
+		squares[i] = 0.5
+	}
+	if got, want := bingoOdds(squares), 1.0/32; math.Abs(got-want) > 0.0001 {
+		t.Errorf("bingoOdds() single line want %f got %f", want, got)
+	}
+}
+
+func TestMasterSetRates(t *testing.T) {
+	m := Master{}
+	m.Load(getTestPhrases())
+	m.SetRates(map[string]float64{"1": 0.2, "2": 0.4, "13": 0})
+
+	rates := m.Rates()
+
+	if rates["1"] != 0.2 {
+		t.Errorf("Master.SetRates() known rate want %f got %f", 0.2, rates["1"])
+	}
+
+	if math.Abs(rates["3"]-0.3) > 0.0001 {
+		t.Errorf("Master.SetRates() unknown rate want %f got %f", 0.3, rates["3"])
+	}
+
+	if rates["13"] != 1 {
+		t.Errorf("Master.SetRates() free rate want %f got %f", 1.0, rates["13"])
+	}
+}
+
+func TestBoardLoadBalanced(t *testing.T) {
+	phrases := getTestPhrases()
+
+	m := Master{}
+	m.Load(phrases)
+
+	rates := map[string]float64{}
+	for _, v := range phrases {
+		rates[v.ID] = 0.3
+	}
+	// Enough certain phrases to fill any line that runs through the center.
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		rates[id] = 1
+	}
+	m.SetRates(rates)
+
+	target := m.TargetOdds()
+
+	for i := 0; i < 20; i++ {
+		b := InitBoard()
+		b.LoadBalanced(m.Phrases(), m.Rates(), target)
+
+		if len(b.Phrases) != boardSize {
+			t.Fatalf("Board.LoadBalanced() phrases want %d got %d", boardSize, len(b.Phrases))
+		}
+
+		if b.hasCertainLine(m.Rates()) {
+			t.Errorf("Board.LoadBalanced() dealt a line of phrases that always happen")
+		}
+
+		b.Score(m.Rates(), target)
+		if b.Fairness < 0 || b.Fairness > 1 {
+			t.Errorf("Board.Score() fairness want between 0 and 1 got %f", b.Fairness)
+		}
+	}
+}
+
+func TestGameNewBoardBalanced(t *testing.T) {
+	pl := Player{"Test", "test@example.com"}
+	game := NewGame("test name", pl, getTestPhrases())
+	game.Balanced = true
+	game.Master.SetRates(map[string]float64{"1": 0.9, "2": 0.1})
+
+	b := game.NewBoard(pl)
+
+	if b.Odds <= 0 {
+		t.Errorf("Game.NewBoard() balanced board odds want > 0 got %f", b.Odds)
+	}
+
+	if b.Fairness <= 0 {
+		t.Errorf("Game.NewBoard() balanced board fairness want > 0 got %f", b.Fairness)
+	}
+}
+
 func TestGamePhraseUpdate(t *testing.T) {
 	pl := Player{}
 	pl.Email = "test@example.com"
@@ -200,18 +950,183 @@ func TestGamePhraseUpdate(t *testing.T) {
 	game.Admins.Add(pl2)
 	_ = game.NewBoard(pl2)
 
-	phrase := Phrase{"1", "Test Phrase", false, "", "", 0}
+	phrase := Phrase{"1", "Test Phrase", false, "", "", 0, false}
 
 	game.UpdatePhrase(phrase)
 
+	_, record := game.FindRecord(phrase)
+	if record.Phrase.Text != phrase.Text {
+		t.Errorf("Game.UpdatePhrase() got %s, want %s", record.Phrase.Text, phrase.Text)
+	}
+
+	before := len(game.Master.Records)
+	game.UpdatePhrase(Phrase{"99", "Not in this game", false, "", "", 0, false})
+
+	if len(game.Master.Records) != before {
+		t.Errorf("Game.UpdatePhrase() records got %d, want %d", len(game.Master.Records), before)
+	}
+}
+
+func TestMasterSetFree(t *testing.T) {
+	pl := Player{}
+	pl.Email = "test@example.com"
+	game := NewGame("test name", pl, getTestPhrases())
+
+	game.Master.SetFree([]Phrase{NewFreePhrase("Custom", 0), NewFreePhrase("Custom", 24)})
+
+	free := 0
 	for _, v := range game.Master.Records {
-		if v.Phrase.ID == phrase.ID {
-			if v.Phrase.Text != phrase.Text {
-				t.Errorf("Board.UpdatePhrase() got %s, want %s", v.Phrase.Text, phrase.Text)
+		if v.Phrase.Free {
+			free++
+			if v.Phrase.Text != "Custom" {
+				t.Errorf("Master.SetFree() text got %s, want %s", v.Phrase.Text, "Custom")
 			}
-			return
 		}
 	}
+
+	if free != 2 {
+		t.Errorf("Master.SetFree() free records got %d, want %d", free, 2)
+	}
+
+	game.Master.SetFree([]Phrase{})
+
+	for _, v := range game.Master.Records {
+		if v.Phrase.Free {
+			t.Errorf("Master.SetFree() expected no free records, got %s", v.ID)
+		}
+	}
+}
+
+func TestGamePhraseUpdateText(t *testing.T) {
+	pl := Player{}
+	pl.Email = "test@example.com"
+	game := NewGame("test name", pl, getTestPhrases())
+	board := game.NewBoard(pl)
+
+	phrase := board.Phrases["1"]
+	phrase.Selected = true
+	game.Select(phrase, pl)
+
+	fixed := Phrase{}
+	fixed.ID = "1"
+	fixed.Text = "Filler one"
+	game.UpdatePhraseText(fixed)
+
+	_, record := game.FindRecord(fixed)
+	if record.Phrase.Text != fixed.Text {
+		t.Errorf("Game.UpdatePhraseText() got %s, want %s", record.Phrase.Text, fixed.Text)
+	}
+
+	if !record.Players.IsMember(pl) || !record.Phrase.Selected {
+		t.Errorf("Game.UpdatePhraseText() expected record to keep its players")
+	}
+
+	got := game.Boards[board.ID].Phrases["1"]
+	if got.Text != fixed.Text || !got.Selected {
+		t.Errorf("Game.UpdatePhraseText() board phrase got %+v, want selected with text %s", got, fixed.Text)
+	}
+}
+
+func TestGameAddAndRemovePhrase(t *testing.T) {
+	pl := Player{}
+	pl.Email = "test@example.com"
+	game := NewGame("test name", pl, getTestPhrases())
+	board := game.NewBoard(pl)
+
+	if _, err := game.RemovePhrase(board.Phrases["1"]); err == nil {
+		t.Errorf("Game.RemovePhrase() expected an error for a game with only %d phrases", boardSize)
+	}
+
+	added := game.AddPhrase(Phrase{"26", "Filler 26", false, "", "", 0, false})
+	if added.ID != "26" {
+		t.Errorf("Game.AddPhrase() record id got %s, want %s", added.ID, "26")
+	}
+
+	if _, ok := game.Boards[board.ID].Phrases["26"]; ok {
+		t.Errorf("Game.AddPhrase() should not change existing boards")
+	}
+
+	removed := game.Boards[board.ID].Phrases["1"]
+	boards, err := game.RemovePhrase(removed)
+	if err != nil {
+		t.Errorf("Game.RemovePhrase() err want %v got %s ", nil, err)
+	}
+
+	if len(boards) != 1 {
+		t.Errorf("Game.RemovePhrase() boards changed got %d, want %d", len(boards), 1)
+	}
+
+	if i, _ := game.FindRecord(removed); i != -1 {
+		t.Errorf("Game.RemovePhrase() expected phrase to be gone from the master list")
+	}
+
+	updated := game.Boards[board.ID]
+	if _, ok := updated.Phrases["1"]; ok {
+		t.Errorf("Game.RemovePhrase() expected phrase to be gone from the board")
+	}
+
+	replacement, ok := updated.Phrases["26"]
+	if !ok {
+		t.Errorf("Game.RemovePhrase() expected the spare phrase to replace the removed one")
+	}
+
+	if replacement.DisplayOrder != removed.DisplayOrder {
+		t.Errorf("Game.RemovePhrase() replacement square got %d, want %d", replacement.DisplayOrder, removed.DisplayOrder)
+	}
+}
+
+func TestGameReshuffle(t *testing.T) {
+	pl := Player{}
+	pl.Email = "test@example.com"
+	game := NewGame("test name", pl, getTestPhrases())
+	board := game.NewBoard(pl)
+	game.AddPhrase(Phrase{"26", "Filler 26", false, "", "", 0, false})
+
+	phrase := board.Phrases["1"]
+	phrase.Selected = true
+	game.Select(phrase, pl)
+
+	game.Reshuffle()
+
+	reshuffled := game.Boards[board.ID]
+	if len(reshuffled.Phrases) != boardSize {
+		t.Errorf("Game.Reshuffle() squares got %d, want %d", len(reshuffled.Phrases), boardSize)
+	}
+
+	_, record := game.FindRecord(phrase)
+	v, ok := reshuffled.Phrases["1"]
+
+	if ok && (!v.Selected || !record.Players.IsMember(pl)) {
+		t.Errorf("Game.Reshuffle() expected a marked phrase still on the board to stay marked")
+	}
+
+	if !ok && record.Players.IsMember(pl) {
+		t.Errorf("Game.Reshuffle() expected a phrase dealt off the board to lose its player")
+	}
+}
+
+func TestGameReshuffleBalanced(t *testing.T) {
+	pl := Player{"Test", "test@example.com"}
+	game := NewGame("test name", pl, getTestPhrases())
+	game.Balanced = true
+	game.Master.SetRates(map[string]float64{"1": 0.9, "2": 0.1})
+	board := game.NewBoard(pl)
+
+	added := game.AddPhrase(Phrase{"26", "Filler 26", false, "", "", 0, false})
+	if added.Rate != game.Master.AverageRate() || added.Rate == 0 {
+		t.Errorf("Game.AddPhrase() rate want %f got %f", game.Master.AverageRate(), added.Rate)
+	}
+
+	stale := game.Boards[board.ID]
+	stale.Odds, stale.Fairness = 0, 0
+	game.Boards[board.ID] = stale
+
+	game.Reshuffle()
+
+	got := game.Boards[board.ID]
+	if got.Odds <= 0 || got.Fairness <= 0 {
+		t.Errorf("Game.Reshuffle() balanced board want odds and fairness got %f %f", got.Odds, got.Fairness)
+	}
 }
 
 func TestGameCheckBoardAndDubious(t *testing.T) {
@@ -499,6 +1414,61 @@ func TestGameSelectAndUnselect(t *testing.T) {
 
 }
 
+func TestMasterSelectSetsState(t *testing.T) {
+	phrases := getTestPhrases()
+	p1 := Player{"Test1", "test1@example.com"}
+	p2 := Player{"Test2", "test2@example.com"}
+	g := NewGame("test game", p1, phrases)
+
+	selected := phrases[0]
+	selected.Selected = true
+	unselected := phrases[0]
+	unselected.Selected = false
+
+	cases := []struct {
+		label   string
+		phrase  Phrase
+		player  Player
+		players int
+		want    bool
+	}{
+		{"select", selected, p1, 1, true},
+		{"select again", selected, p1, 1, true},
+		{"second player", selected, p2, 2, true},
+		{"unselect", unselected, p1, 1, true},
+		{"unselect again", unselected, p1, 1, true},
+		{"last player", unselected, p2, 0, false},
+	}
+
+	for _, c := range cases {
+		got := g.Master.Select(c.phrase, c.player)
+
+		if len(got.Players) != c.players || got.Phrase.Selected != c.want {
+			t.Errorf("%s: Master.Select() want %d players selected %t got %d %t", c.label, c.players, c.want, len(got.Players), got.Phrase.Selected)
+		}
+
+		if _, r := g.FindRecord(c.phrase); len(r.Players) != len(got.Players) {
+			t.Errorf("%s: Master.Select() returned record differs from master %+v %+v", c.label, got, r)
+		}
+	}
+}
+
+func TestBoardHasKey(t *testing.T) {
+	b := getTestBoard()
+	p := Phrase{ID: "1", Selected: true}
+
+	b.AddActivity(p, "abc", time.Now())
+	b.AddActivity(p, "", time.Now())
+
+	if !b.HasKey("abc") {
+		t.Errorf("Board.HasKey() want %t got %t", true, false)
+	}
+
+	if b.HasKey("xyz") {
+		t.Errorf("Board.HasKey() want %t got %t", false, true)
+	}
+}
+
 func TestMasterDoesNotExist(t *testing.T) {
 	phrases := getTestPhrases()
 	phrase := phrases[0]
@@ -534,31 +1504,31 @@ func getTestGame() Game {
 
 func getTestPhrases() []Phrase {
 	phrases := []Phrase{
-		{"1", "Filler 1", false, "0", "B", 0},
-		{"2", "Filler 2", false, "0", "I", 1},
-		{"3", "Filler 3", false, "0", "N", 2},
-		{"4", "Filler 4", false, "0", "G", 3},
-		{"5", "Filler 5", false, "0", "O", 4},
-		{"6", "Filler 6", false, "1", "B", 5},
-		{"7", "Filler 7", false, "1", "I", 6},
-		{"8", "Filler 8", false, "1", "N", 7},
-		{"9", "Filler 9", false, "1", "G", 8},
-		{"10", "Filler 10", false, "1", "O", 9},
-		{"11", "Filler 11", false, "2", "B", 10},
-		{"12", "Filler 12", false, "2", "I", 11},
-		{"13", "FREE", false, "2", "N", 12},
-		{"14", "Filler 14", false, "2", "G", 13},
-		{"15", "Filler 15", false, "2", "O", 14},
-		{"16", "Filler 16", false, "3", "B", 15},
-		{"17", "Filler 17", false, "3", "I", 16},
-		{"18", "Filler 18", false, "3", "N", 17},
-		{"19", "Filler 19", false, "3", "G", 18},
-		{"20", "Filler 20", false, "3", "O", 19},
-		{"21", "Filler 21", false, "4", "B", 20},
-		{"22", "Filler 22", false, "4", "I", 21},
-		{"23", "Filler 23", false, "4", "N", 22},
-		{"24", "Filler 24", false, "4", "G", 23},
-		{"25", "Filler 25", false, "4", "O", 24},
+		{"1", "Filler 1", false, "0", "B", 0, false},
+		{"2", "Filler 2", false, "0", "I", 1, false},
+		{"3", "Filler 3", false, "0", "N", 2, false},
+		{"4", "Filler 4", false, "0", "G", 3, false},
+		{"5", "Filler 5", false, "0", "O", 4, false},
+		{"6", "Filler 6", false, "1", "B", 5, false},
+		{"7", "Filler 7", false, "1", "I", 6, false},
+		{"8", "Filler 8", false, "1", "N", 7, false},
+		{"9", "Filler 9", false, "1", "G", 8, false},
+		{"10", "Filler 10", false, "1", "O", 9, false},
+		{"11", "Filler 11", false, "2", "B", 10, false},
+		{"12", "Filler 12", false, "2", "I", 11, false},
+		{"13", "FREE", false, "2", "N", 12, true},
+		{"14", "Filler 14", false, "2", "G", 13, false},
+		{"15", "Filler 15", false, "2", "O", 14, false},
+		{"16", "Filler 16", false, "3", "B", 15, false},
+		{"17", "Filler 17", false, "3", "I", 16, false},
+		{"18", "Filler 18", false, "3", "N", 17, false},
+		{"19", "Filler 19", false, "3", "G", 18, false},
+		{"20", "Filler 20", false, "3", "O", 19, false},
+		{"21", "Filler 21", false, "4", "B", 20, false},
+		{"22", "Filler 22", false, "4", "I", 21, false},
+		{"23", "Filler 23", false, "4", "N", 22, false},
+		{"24", "Filler 24", false, "4", "G", 23, false},
+		{"25", "Filler 25", false, "4", "O", 24, false},
 	}
 
 	return phrases
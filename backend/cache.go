@@ -15,9 +15,9 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 
 	"github.com/gomodule/redigo/redis"
 )
@@ -47,17 +47,33 @@ type Cache struct {
 	enabled   bool
 }
 
-func (c *Cache) log(msg string) {
-	if noisy {
-		log.Printf("Cache     : %s\n", msg)
+// trace starts a span for a Cache method. The returned func ends it.
+func (c *Cache) trace(ctx context.Context, operation string, err *error) (context.Context, func()) {
+	return startSpan(ctx, "Cache."+operation, err)
+}
+
+// observe starts a span for a lookup. The returned func ends it and counts the
+// lookup as a hit, miss or error, if the cache is in use.
+func (c *Cache) observe(ctx context.Context, operation string, err *error) (context.Context, func()) {
+	ctx, end := c.trace(ctx, operation, err)
+
+	return ctx, func() {
+		end()
+		if c.enabled {
+			observeCache(operation, *err)
+		}
 	}
 }
 
+func (c *Cache) log(ctx context.Context, msg string) {
+	logger.Debug(ctx, "cache", msg)
+}
+
 // InitPool starts the cache off
 func (c Cache) InitPool(redisHost, redisPort string) RedisPool {
 	redisAddr := fmt.Sprintf("%s:%s", redisHost, redisPort)
 	msg := fmt.Sprintf("Initialized Redis at %s", redisAddr)
-	c.log(msg)
+	c.log(ctx, msg)
 	const maxConnections = 10
 
 	pool := redis.NewPool(func() (redis.Conn, error) {
@@ -68,7 +84,10 @@ func (c Cache) InitPool(redisHost, redisPort string) RedisPool {
 }
 
 // Clear removes all items from the cache.
-func (c Cache) Clear() error {
+func (c Cache) Clear(ctx context.Context) (err error) {
+	ctx, done := c.trace(ctx, "Clear", &err)
+	defer done()
+
 	if !c.enabled {
 		return nil
 	}
@@ -108,7 +127,10 @@ func (c *Cache) gamesKey(key string) string {
 ////////////////////////////////////////////////////////////////////////////////
 
 // SaveBoard records a board into the cache.
-func (c *Cache) SaveBoard(board Board) error {
+func (c *Cache) SaveBoard(ctx context.Context, board Board) (err error) {
+	ctx, done := c.trace(ctx, "SaveBoard", &err)
+	defer done()
+
 	if !c.enabled {
 		return nil
 	}
@@ -130,21 +152,25 @@ func (c *Cache) SaveBoard(board Board) error {
 	if _, err := conn.Do("EXEC"); err != nil {
 		return err
 	}
-	c.log("Successfully saved board to cache")
+	c.log(ctx, "Successfully saved board to cache")
 	return nil
 }
 
 // GetBoard retrieves an board from the cache usign board pattern
-func (c *Cache) GetBoard(bid string) (Board, error) {
-	return c.getBoardForKey(c.boardKeyForBoard(bid))
+func (c *Cache) GetBoard(ctx context.Context, bid string) (_ Board, err error) {
+	ctx, done := c.observe(ctx, "GetBoard", &err)
+	defer done()
+	return c.getBoardForKey(ctx, c.boardKeyForBoard(bid))
 }
 
 // GetBoardForPlayer retrieves an board from the cache using player patern
-func (c *Cache) GetBoardForPlayer(gid, email string) (Board, error) {
-	return c.getBoardForKey(c.boardKeyForPlayer(gid, email))
+func (c *Cache) GetBoardForPlayer(ctx context.Context, gid, email string) (_ Board, err error) {
+	ctx, done := c.observe(ctx, "GetBoardForPlayer", &err)
+	defer done()
+	return c.getBoardForKey(ctx, c.boardKeyForPlayer(gid, email))
 }
 
-func (c *Cache) getBoardForKey(key string) (Board, error) {
+func (c *Cache) getBoardForKey(ctx context.Context, key string) (Board, error) {
 	b := Board{}
 	if !c.enabled {
 		return b, ErrCacheMiss
@@ -162,13 +188,16 @@ func (c *Cache) getBoardForKey(key string) (Board, error) {
 	if err := json.Unmarshal([]byte(s), &b); err != nil {
 		return Board{}, err
 	}
-	c.log("Successfully retrieved board from cache")
+	c.log(ctx, "Successfully retrieved board from cache")
 
 	return b, nil
 }
 
 // DeleteBoard will remove a board from the cache completely.
-func (c *Cache) DeleteBoard(board Board) error {
+func (c *Cache) DeleteBoard(ctx context.Context, board Board) (err error) {
+	ctx, done := c.trace(ctx, "DeleteBoard", &err)
+	defer done()
+
 	if !c.enabled {
 		return nil
 	}
@@ -196,9 +225,9 @@ func (c *Cache) DeleteBoard(board Board) error {
 		return err
 	}
 
-	c.log(fmt.Sprintf("Cleaning from cache %s", boardkey))
-	c.log(fmt.Sprintf("Cleaning from cache %s", playerkey))
-	c.log(fmt.Sprintf("Cleaning from cache %s", gameskey))
+	c.log(ctx, fmt.Sprintf("Cleaning from cache %s", boardkey))
+	c.log(ctx, fmt.Sprintf("Cleaning from cache %s", playerkey))
+	c.log(ctx, fmt.Sprintf("Cleaning from cache %s", gameskey))
 	return nil
 }
 
@@ -207,7 +236,10 @@ func (c *Cache) DeleteBoard(board Board) error {
 ////////////////////////////////////////////////////////////////////////////////
 
 // SaveGame records a game in the cache.
-func (c *Cache) SaveGame(game Game) error {
+func (c *Cache) SaveGame(ctx context.Context, game Game) (err error) {
+	ctx, done := c.trace(ctx, "SaveGame", &err)
+	defer done()
+
 	if !c.enabled {
 		return nil
 	}
@@ -225,17 +257,19 @@ func (c *Cache) SaveGame(game Game) error {
 	if _, err := conn.Do("SET", gamekey, json); err != nil {
 		return err
 	}
-	c.log("Successfully saved game to cache")
+	c.log(ctx, "Successfully saved game to cache")
 
 	if len(game.Boards) == 0 {
-		c.log("WARNING game saved to cache without the boards.")
+		c.log(ctx, "WARNING game saved to cache without the boards.")
 	}
 
 	return nil
 }
 
 // GetGame retrieves an game from the cache
-func (c *Cache) GetGame(key string) (Game, error) {
+func (c *Cache) GetGame(ctx context.Context, key string) (_ Game, err error) {
+	ctx, done := c.observe(ctx, "GetGame", &err)
+	defer done()
 	g := Game{}
 	if !c.enabled {
 		return g, ErrCacheMiss
@@ -256,13 +290,16 @@ func (c *Cache) GetGame(key string) (Game, error) {
 	if err := json.Unmarshal([]byte(s), &g); err != nil {
 		return Game{}, err
 	}
-	c.log("Successfully retrieved game from cache")
+	c.log(ctx, "Successfully retrieved game from cache")
 
 	return g, nil
 }
 
 // SaveGamesForKey saves a list of all of the games a player is in.
-func (c *Cache) SaveGamesForKey(key string, games Games) error {
+func (c *Cache) SaveGamesForKey(ctx context.Context, key string, games Games) (err error) {
+	ctx, done := c.trace(ctx, "SaveGamesForKey", &err)
+	defer done()
+
 	if !c.enabled {
 		return nil
 	}
@@ -280,12 +317,14 @@ func (c *Cache) SaveGamesForKey(key string, games Games) error {
 	if _, err := conn.Do("SET", rkey, json); err != nil {
 		return err
 	}
-	c.log("Successfully saved game list to cache")
+	c.log(ctx, "Successfully saved game list to cache")
 	return nil
 }
 
 // GetGamesForKey retrieves a list of games from the cache
-func (c *Cache) GetGamesForKey(key string) (Games, error) {
+func (c *Cache) GetGamesForKey(ctx context.Context, key string) (_ Games, err error) {
+	ctx, done := c.observe(ctx, "GetGamesForKey", &err)
+	defer done()
 	g := []Game{}
 	if !c.enabled {
 		return g, ErrCacheMiss
@@ -306,13 +345,16 @@ func (c *Cache) GetGamesForKey(key string) (Games, error) {
 	if err := json.Unmarshal([]byte(s), &g); err != nil {
 		return g, err
 	}
-	c.log("Successfully retrieved games from cache")
+	c.log(ctx, "Successfully retrieved games from cache")
 
 	return g, nil
 }
 
 // DeleteGamesForKey will remove the list of games for a particular player
-func (c *Cache) DeleteGamesForKey(keys []string) error {
+func (c *Cache) DeleteGamesForKey(ctx context.Context, keys []string) (err error) {
+	ctx, done := c.trace(ctx, "DeleteGamesForKey", &err)
+	defer done()
+
 	if !c.enabled {
 		return nil
 	}
@@ -332,12 +374,15 @@ func (c *Cache) DeleteGamesForKey(keys []string) error {
 		return err
 	}
 
-	c.log(fmt.Sprintf("Cleaning games for key from cache"))
+	c.log(ctx, fmt.Sprintf("Cleaning games for key from cache"))
 	return nil
 }
 
 // DeleteGame will remove a game from the cache completely.
-func (c *Cache) DeleteGame(game Game) error {
+func (c *Cache) DeleteGame(ctx context.Context, game Game) (err error) {
+	ctx, done := c.trace(ctx, "DeleteGame", &err)
+	defer done()
+
 	if !c.enabled {
 		return nil
 	}
@@ -356,7 +401,7 @@ func (c *Cache) DeleteGame(game Game) error {
 		return err
 	}
 
-	c.log(fmt.Sprintf("Cleaning from cache %s", gamekey))
+	c.log(ctx, fmt.Sprintf("Cleaning from cache %s", gamekey))
 	return nil
 }
 
@@ -366,8 +411,24 @@ func (c *Cache) DeleteGame(game Game) error {
 
 // UpdatePhrase will update all of the versions of a phrase in a game and all
 // of the boards in that game.
-func (c *Cache) UpdatePhrase(game Game, phrase Phrase) error {
-	c.log("Update Phrase " + phrase.Text)
+func (c *Cache) UpdatePhrase(ctx context.Context, game Game, phrase Phrase) (err error) {
+	ctx, done := c.trace(ctx, "UpdatePhrase", &err)
+	defer done()
+
+	c.log(ctx, "Update Phrase "+phrase.Text)
+	return c.SaveGameAndBoards(ctx, game)
+}
+
+// SaveGameAndBoards records a game and every one of its boards in the cache in
+// one go.
+func (c *Cache) SaveGameAndBoards(ctx context.Context, game Game) (err error) {
+	ctx, done := c.trace(ctx, "SaveGameAndBoards", &err)
+	defer done()
+
+	if !c.enabled {
+		return nil
+	}
+
 	conn := c.redisPool.Get()
 	defer conn.Close()
 
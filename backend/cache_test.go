@@ -121,11 +121,11 @@ func TestCacheBoard(t *testing.T) {
 	player.Email = "test@example.com"
 	board.Player = player
 
-	if err := cache.SaveBoard(board); err != nil {
+	if err := cache.SaveBoard(ctx, board); err != nil {
 		t.Errorf("Cache.SaveBoard() err want %v got %s ", nil, err)
 	}
 
-	boardFromCache, err := cache.GetBoard(board.ID)
+	boardFromCache, err := cache.GetBoard(ctx, board.ID)
 
 	if err != nil {
 		t.Errorf("Cache.GetBoard() err want %v got %s ", nil, err)
@@ -135,7 +135,7 @@ func TestCacheBoard(t *testing.T) {
 		t.Errorf("Cache.GetBoard() should return an unchanged board")
 	}
 
-	boardFromCacheForPlayer, err := cache.GetBoardForPlayer(board.Game, board.Player.Email)
+	boardFromCacheForPlayer, err := cache.GetBoardForPlayer(ctx, board.Game, board.Player.Email)
 
 	if err != nil {
 		t.Errorf("Cache.GetBoard() err want %v got %s ", nil, err)
@@ -145,11 +145,11 @@ func TestCacheBoard(t *testing.T) {
 		t.Errorf("Cache.GetBoard() err want %+v got %+v ", board, boardFromCache)
 	}
 
-	if err := cache.DeleteBoard(board); err != nil {
+	if err := cache.DeleteBoard(ctx, board); err != nil {
 		t.Errorf("Cache.DeleteBoard() err want %v got %s ", nil, err)
 	}
 
-	boardFromCachePostDelete, err := cache.GetBoard(board.ID)
+	boardFromCachePostDelete, err := cache.GetBoard(ctx, board.ID)
 	if err != ErrCacheMiss {
 		t.Errorf("Cache.GetBoard() post delete err want %v got %v ", nil, boardFromCachePostDelete)
 	}
@@ -162,24 +162,24 @@ func TestClearCache(t *testing.T) {
 	board.Player = player
 	game := NewGame("test game", player, getTestPhrases())
 
-	if err := cache.SaveBoard(board); err != nil {
+	if err := cache.SaveBoard(ctx, board); err != nil {
 		t.Errorf("Cache.SaveBoard() err want %v got %s ", nil, err)
 	}
 
-	if err := cache.SaveGame(game); err != nil {
+	if err := cache.SaveGame(ctx, game); err != nil {
 		t.Errorf("Cache.SaveGame() err want %v got %s ", nil, err)
 	}
 
-	if err := cache.Clear(); err != nil {
+	if err := cache.Clear(ctx); err != nil {
 		t.Errorf("Cache.Clear() err want %v got %s ", nil, err)
 	}
 
-	boardFromCachePostClear, err := cache.GetBoard(board.ID)
+	boardFromCachePostClear, err := cache.GetBoard(ctx, board.ID)
 	if err != ErrCacheMiss {
 		t.Errorf("Cache.GetBoard() post delete err want %v got %v ", nil, boardFromCachePostClear)
 	}
 
-	gameFromCachePostDelete, err := cache.GetGame(game.ID)
+	gameFromCachePostDelete, err := cache.GetGame(ctx, game.ID)
 	if err != ErrCacheMiss {
 		t.Errorf("Cache.GetBoard() post delete err want %v got %v ", nil, gameFromCachePostDelete)
 	}
@@ -193,31 +193,31 @@ func TestUpdatePhrase(t *testing.T) {
 	phrase.Text = "Totally new text"
 	board := game.NewBoard(player)
 
-	if err := cache.SaveBoard(board); err != nil {
+	if err := cache.SaveBoard(ctx, board); err != nil {
 		t.Errorf("Cache.SaveBoard() err want %v got %s ", nil, err)
 	}
 
-	if err := cache.SaveGame(game); err != nil {
+	if err := cache.SaveGame(ctx, game); err != nil {
 		t.Errorf("Cache.SaveGame() err want %v got %s ", nil, err)
 	}
 
 	game.UpdatePhrase(phrase)
 
-	if err := cache.UpdatePhrase(game, phrase); err != nil {
+	if err := cache.UpdatePhrase(ctx, game, phrase); err != nil {
 		t.Errorf("Cache.UpdatePhrase() err want %v got %s ", nil, err)
 	}
 
-	gameFromCache, err := cache.GetGame(game.ID)
+	gameFromCache, err := cache.GetGame(ctx, game.ID)
 	if err != nil {
 		t.Errorf("Cache.GetGame() err want %v got %s ", nil, err)
 	}
 
-	boardFromCache, err := cache.GetBoard(board.ID)
+	boardFromCache, err := cache.GetBoard(ctx, board.ID)
 	if err != nil {
 		t.Errorf("Cache.GetBoard() err want %v got %s ", nil, err)
 	}
 
-	boardFromCacheForPlayer, err := cache.GetBoardForPlayer(board.Game, board.Player.Email)
+	boardFromCacheForPlayer, err := cache.GetBoardForPlayer(ctx, board.Game, board.Player.Email)
 	if err != nil {
 		t.Errorf("Cache.GetBoard() err want %v got %s ", nil, err)
 	}
@@ -248,11 +248,11 @@ func TestCacheGame(t *testing.T) {
 	player.Email = "test@example.com"
 	game := NewGame("test game", player, getTestPhrases())
 
-	if err := cache.SaveGame(game); err != nil {
+	if err := cache.SaveGame(ctx, game); err != nil {
 		t.Errorf("Cache.SaveGame() err want %v got %s ", nil, err)
 	}
 
-	gameFromCache, err := cache.GetGame(game.ID)
+	gameFromCache, err := cache.GetGame(ctx, game.ID)
 
 	if err != nil {
 		t.Errorf("Cache.GetGame() err want %v got %s ", nil, err)
@@ -262,11 +262,11 @@ func TestCacheGame(t *testing.T) {
 		t.Errorf("Cache.GetGame() should return an unchanged game")
 	}
 
-	if err := cache.DeleteGame(game); err != nil {
+	if err := cache.DeleteGame(ctx, game); err != nil {
 		t.Errorf("Cache.DeleteGame() err want %v got %s ", nil, err)
 	}
 
-	gameFromCachePostDelete, err := cache.GetGame(game.ID)
+	gameFromCachePostDelete, err := cache.GetGame(ctx, game.ID)
 	if err != ErrCacheMiss {
 		t.Errorf("Cache.GetBoard() post delete err want %v got %v ", nil, gameFromCachePostDelete)
 	}
@@ -283,11 +283,11 @@ func TestCacheGames(t *testing.T) {
 	games = append(games, game2)
 	key := "uniqueid"
 
-	if err := cache.SaveGamesForKey(key, games); err != nil {
+	if err := cache.SaveGamesForKey(ctx, key, games); err != nil {
 		t.Errorf("Cache.SaveGamesForKey() err want %v got %s ", nil, err)
 	}
 
-	gamesFromCache, err := cache.GetGamesForKey(key)
+	gamesFromCache, err := cache.GetGamesForKey(ctx, key)
 
 	if err != nil {
 		t.Errorf("Cache.GetGamesForKey() err want %v got %s ", nil, err)
@@ -297,11 +297,11 @@ func TestCacheGames(t *testing.T) {
 		t.Errorf("Cache.GetGames() should return an unchanged set of games")
 	}
 
-	if err := cache.DeleteGamesForKey([]string{key}); err != nil {
+	if err := cache.DeleteGamesForKey(ctx, []string{key}); err != nil {
 		t.Errorf("Cache.DeleteGamesForKey() err want %v got %s ", nil, err)
 	}
 
-	gamesFromCachePostDelete, err := cache.GetGamesForKey(key)
+	gamesFromCachePostDelete, err := cache.GetGamesForKey(ctx, key)
 	if err != ErrCacheMiss {
 		t.Errorf("Cache.GetGamesForKey() post delete err want %v got %v ", nil, gamesFromCachePostDelete)
 	}
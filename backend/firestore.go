@@ -17,7 +17,6 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
 	"math/rand"
 	"strconv"
 	"strings"
@@ -33,35 +32,34 @@ func NewAgent(ctx context.Context, projectID string) (Agent, error) {
 	rand.Seed(time.Now().UTC().UnixNano())
 	a := Agent{}
 	a.ProjectID = projectID
-	a.ctx = ctx
-	a.client, err = firestore.NewClient(a.ctx, a.ProjectID)
+	a.client, err = firestore.NewClient(ctx, a.ProjectID)
 	if err != nil {
 		return a, fmt.Errorf("Failed to create client: %v", err)
 	}
 
-	admins, err := a.GetAdmins()
+	admins, err := a.GetAdmins(ctx)
 	if err != nil {
 		return a, fmt.Errorf("error trying to check on admins: %v", err)
 	}
 
 	if len(admins) == 0 {
-		a.log("Intializing admin email.")
+		a.log(ctx, "Intializing admin email.")
 		player := Player{"", "notrealemail"}
-		err = a.AddAdmin(player)
+		err = a.AddAdmin(ctx, player)
 		if err != nil {
-			log.Fatal(err)
+			return a, fmt.Errorf("error trying to add admin: %v", err)
 		}
 	}
 
-	phrases, err := a.GetPhrases()
+	phrases, err := a.GetPhrases(ctx)
 	if err != nil {
 		return a, fmt.Errorf("error trying to check on phrases: %v", err)
 	}
 
 	if len(phrases) < 25 {
-		phrases = a.getDefaultList()
+		phrases = a.getDefaultList(ctx)
 
-		if err := a.LoadPhrases(phrases); err != nil {
+		if err := a.LoadPhrases(ctx, phrases); err != nil {
 			return a, fmt.Errorf("error loading phrases: %v", err)
 		}
 	}
@@ -72,25 +70,39 @@ func NewAgent(ctx context.Context, projectID string) (Agent, error) {
 // Agent is a go between for the main application and firestore.
 type Agent struct {
 	ProjectID string
-	ctx       context.Context
 	client    *firestore.Client
 }
 
-func (a *Agent) log(msg string) {
-	if noisy {
-		log.Printf("Firestore : %s\n", msg)
+// observe starts a span for an Agent method. The returned func ends it and
+// records how long the method took and whether it failed.
+func (a *Agent) observe(ctx context.Context, operation string, err *error) (context.Context, func()) {
+	start := time.Now()
+	ctx, end := startSpan(ctx, "Agent."+operation, err)
+
+	return ctx, func() {
+		end()
+		metrics.firestoreDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+		if *err != nil {
+			metrics.firestoreErrors.WithLabelValues(operation).Inc()
+		}
 	}
 }
 
+func (a *Agent) log(ctx context.Context, msg string) {
+	logger.Debug(ctx, "firestore", msg)
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // ADMINS
 ////////////////////////////////////////////////////////////////////////////////
 
 // IsAdmin tests if a give player is in the admin group by email
-func (a *Agent) IsAdmin(email string) (bool, error) {
+func (a *Agent) IsAdmin(ctx context.Context, email string) (_ bool, err error) {
+	ctx, done := a.observe(ctx, "IsAdmin", &err)
+	defer done()
 
-	a.log("See if user is in admin collection")
-	doc, err := a.client.Collection("admins").Doc(email).Get(a.ctx)
+	a.log(ctx, "See if user is in admin collection")
+	doc, err := a.client.Collection("admins").Doc(email).Get(ctx)
 	if err != nil {
 		if strings.Contains(err.Error(), "code = NotFound") {
 			return false, nil
@@ -103,7 +115,9 @@ func (a *Agent) IsAdmin(email string) (bool, error) {
 }
 
 // AddAdmin adds an admin to the over all system
-func (a *Agent) AddAdmin(player Player) error {
+func (a *Agent) AddAdmin(ctx context.Context, player Player) (err error) {
+	ctx, done := a.observe(ctx, "AddAdmin", &err)
+	defer done()
 	if _, err := a.client.Collection("admins").Doc(player.Email).Set(ctx, player); err != nil {
 		return fmt.Errorf("unable to add admin: %s", err)
 	}
@@ -111,7 +125,9 @@ func (a *Agent) AddAdmin(player Player) error {
 }
 
 // DeleteAdmin Deletes an admin to the over all system
-func (a *Agent) DeleteAdmin(player Player) error {
+func (a *Agent) DeleteAdmin(ctx context.Context, player Player) (err error) {
+	ctx, done := a.observe(ctx, "DeleteAdmin", &err)
+	defer done()
 	if _, err := a.client.Collection("admins").Doc(player.Email).Delete(ctx); err != nil {
 		return fmt.Errorf("unable to delete admin: %s", err)
 	}
@@ -119,12 +135,14 @@ func (a *Agent) DeleteAdmin(player Player) error {
 }
 
 // GetAdmins fetches the master list of Admins for populating Games
-func (a *Agent) GetAdmins() (Players, error) {
+func (a *Agent) GetAdmins(ctx context.Context) (_ Players, err error) {
+	ctx, done := a.observe(ctx, "GetAdmins", &err)
+	defer done()
 
 	p := Players{}
 
-	a.log("Getting Phrases")
-	iter := a.client.Collection("admins").Documents(a.ctx)
+	a.log(ctx, "Getting Phrases")
+	iter := a.client.Collection("admins").Documents(ctx)
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -146,12 +164,14 @@ func (a *Agent) GetAdmins() (Players, error) {
 ////////////////////////////////////////////////////////////////////////////////
 
 // GetPhrases fetches the master list of Phrases for populating Games
-func (a *Agent) GetPhrases() ([]Phrase, error) {
+func (a *Agent) GetPhrases(ctx context.Context) (_ []Phrase, err error) {
+	ctx, done := a.observe(ctx, "GetPhrases", &err)
+	defer done()
 
 	p := []Phrase{}
 
-	a.log("Getting Phrases")
-	iter := a.client.Collection("phrases").Documents(a.ctx)
+	a.log(ctx, "Getting Phrases")
+	iter := a.client.Collection("phrases").Documents(ctx)
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -166,6 +186,19 @@ func (a *Agent) GetPhrases() ([]Phrase, error) {
 		phrase.ID = dataMap["id"].(string)
 		phrase.Text = dataMap["text"].(string)
 
+		if free, ok := dataMap["free"].(bool); ok {
+			phrase.Free = free
+		} else if phrase.Text == "FREE" {
+			// Lists saved before free squares were an attribute relied on
+			// the text alone, and always put the square in the center.
+			phrase.Free = true
+			phrase.DisplayOrder = boardCenter
+		}
+
+		if order, ok := dataMap["displayorder"].(int64); ok && phrase.Free {
+			phrase.DisplayOrder = int(order)
+		}
+
 		p = append(p, phrase)
 	}
 
@@ -173,7 +206,9 @@ func (a *Agent) GetPhrases() ([]Phrase, error) {
 }
 
 // LoadPhrases does a batch load of the master phrases for the game.
-func (a *Agent) LoadPhrases(phrases []Phrase) error {
+func (a *Agent) LoadPhrases(ctx context.Context, phrases []Phrase) (err error) {
+	ctx, done := a.observe(ctx, "LoadPhrases", &err)
+	defer done()
 	batch := a.client.Batch()
 
 	for _, v := range phrases {
@@ -181,7 +216,7 @@ func (a *Agent) LoadPhrases(phrases []Phrase) error {
 		batch.Set(ref, v)
 	}
 
-	if _, err := batch.Commit(a.ctx); err != nil {
+	if _, err := batch.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to load phrases to database: %v", err)
 	}
 
@@ -189,43 +224,142 @@ func (a *Agent) LoadPhrases(phrases []Phrase) error {
 }
 
 // UpdateMasterPhrase updates a phrase in the master collection of phrases
-func (a *Agent) UpdateMasterPhrase(phrase Phrase) error {
+func (a *Agent) UpdateMasterPhrase(ctx context.Context, phrase Phrase) (err error) {
+	ctx, done := a.observe(ctx, "UpdateMasterPhrase", &err)
+	defer done()
 
-	if _, err := a.client.Collection("phrases").Doc(phrase.ID).Set(a.ctx, phrase); err != nil {
+	if _, err := a.client.Collection("phrases").Doc(phrase.ID).Set(ctx, phrase); err != nil {
 		return fmt.Errorf("failed to update phrase: %v", err)
 	}
 
 	return nil
 }
 
-func (a *Agent) getDefaultList() []Phrase {
-	a.log("Getting the default phrase list")
+// DeleteMasterPhrase removes a phrase from the master collection of phrases
+func (a *Agent) DeleteMasterPhrase(ctx context.Context, phrase Phrase) (err error) {
+	ctx, done := a.observe(ctx, "DeleteMasterPhrase", &err)
+	defer done()
+
+	if _, err := a.client.Collection("phrases").Doc(phrase.ID).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete phrase: %v", err)
+	}
+
+	return nil
+}
+
+// phraseStats is the collection of running counts, by phrase id, of the boards
+// each phrase was dealt to and the players that selected it, so stats don't
+// need every board read. Game phrases that aren't on the master list are
+// counted too, but never reported.
+const phraseStats = "phrasestats"
+
+// GetPhraseStats works out how often each master phrase gets selected across
+// every game, from the counts kept as boards are dealt and phrases selected.
+func (a *Agent) GetPhraseStats(ctx context.Context) (_ PhraseStats, err error) {
+	ctx, done := a.observe(ctx, "GetPhraseStats", &err)
+	defer done()
+	phrases, err := a.GetPhrases(ctx)
+	if err != nil {
+		return PhraseStats{}, fmt.Errorf("failed to get phrases: %v", err)
+	}
+
+	boards := make(map[string]int)
+	selections := make(map[string]int)
+
+	a.log(ctx, "Getting phrase counts")
+	iter := a.client.Collection(phraseStats).Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return PhraseStats{}, fmt.Errorf("Failed to iterate: %v", err)
+		}
+		dataMap := doc.Data()
+		if n, ok := dataMap["boards"].(int64); ok {
+			boards[doc.Ref.ID] = int(n)
+		}
+		if n, ok := dataMap["selections"].(int64); ok {
+			selections[doc.Ref.ID] = int(n)
+		}
+	}
+
+	return NewPhraseStats(phrases, boards, selections), nil
+}
+
+// CountDeals adds the phrases on boards that were just dealt, or started a
+// new round, to the counts of boards each phrase was on.
+func (a *Agent) CountDeals(ctx context.Context, boards []Board) (err error) {
+	ctx, done := a.observe(ctx, "CountDeals", &err)
+	defer done()
+
+	batch := a.client.Batch()
+	if a.countDeals(batch, boards) == 0 {
+		return nil
+	}
+
+	if _, err := batch.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to count phrases on boards: %v", err)
+	}
+
+	return nil
+}
+
+// countDeals adds the counting of the phrases on boards to batch, with one
+// write for each phrase, and returns how many writes that is.
+func (a *Agent) countDeals(batch *firestore.WriteBatch, boards []Board) int {
+	counts := make(map[string]int)
+	for _, b := range boards {
+		for _, v := range b.Phrases {
+			if !v.Free {
+				counts[v.ID]++
+			}
+		}
+	}
+	a.countPhrases(batch, "boards", counts)
+	return len(counts)
+}
+
+// countPhrases adds the changes to a count of each phrase to batch.
+func (a *Agent) countPhrases(batch *firestore.WriteBatch, field string, counts map[string]int) {
+	for id, n := range counts {
+		if n == 0 {
+			continue
+		}
+		ref := a.client.Collection(phraseStats).Doc(id)
+		batch.Set(ref, map[string]interface{}{field: firestore.Increment(n)}, firestore.MergeAll)
+	}
+}
+
+func (a *Agent) getDefaultList(ctx context.Context) []Phrase {
+	a.log(ctx, "Getting the default phrase list")
 	phrases := []Phrase{
-		{"101", "Someone tells a dad joke", false, "", "", 0},
-		{"102", "Greg references airplanes/piloting", false, "", "", 0},
-		{"103", "\"We’re all in this together\"", false, "", "", 0},
-		{"104", "\"the new normal\"", false, "", "", 0},
-		{"105", "Someone's child/S.O. on screen", false, "", "", 0},
-		{"106", "\"Goals\"", false, "", "", 0},
-		{"107", "\"Increased (better, clearer) focus\"", false, "", "", 0},
-		{"108", "\"These uncertain times\"", false, "", "", 0},
-		{"109", "Someone’s pet on screen", false, "", "", 0},
-		{"110", "\"working from home\"", false, "", "", 0},
-		{"111", "Someone speaks when muted", false, "", "", 0},
-		{"112", "\"Wash your hands\"", false, "", "", 0},
-		{"113", "FREE", false, "", "", 0},
-		{"114", "Awkward silence", false, "", "", 0},
-		{"115", "Sports metaphor", false, "", "", 0},
-		{"116", "Start at least 5 min late", false, "", "", 0},
-		{"117", "Joke made, but no one laughs", false, "", "", 0},
-		{"118", "Someone eats on screen", false, "", "", 0},
-		{"119", "Answer all Dory questions", false, "", "", 0},
-		{"120", "\"self care\"", false, "", "", 0},
-		{"121", "\"Can you see my screen?\"", false, "", "", 0},
-		{"122", "\"headcount\"", false, "", "", 0},
-		{"123", "CEO's name mentioned", false, "", "", 0},
-		{"124", "\"TK\"", false, "", "", 0},
-		{"125", "VP's name mentioned", false, "", "", 0},
+		{"101", "Someone tells a dad joke", false, "", "", 0, false},
+		{"102", "Greg references airplanes/piloting", false, "", "", 0, false},
+		{"103", "\"We’re all in this together\"", false, "", "", 0, false},
+		{"104", "\"the new normal\"", false, "", "", 0, false},
+		{"105", "Someone's child/S.O. on screen", false, "", "", 0, false},
+		{"106", "\"Goals\"", false, "", "", 0, false},
+		{"107", "\"Increased (better, clearer) focus\"", false, "", "", 0, false},
+		{"108", "\"These uncertain times\"", false, "", "", 0, false},
+		{"109", "Someone’s pet on screen", false, "", "", 0, false},
+		{"110", "\"working from home\"", false, "", "", 0, false},
+		{"111", "Someone speaks when muted", false, "", "", 0, false},
+		{"112", "\"Wash your hands\"", false, "", "", 0, false},
+		{"113", "FREE", false, "", "", 12, true},
+		{"114", "Awkward silence", false, "", "", 0, false},
+		{"115", "Sports metaphor", false, "", "", 0, false},
+		{"116", "Start at least 5 min late", false, "", "", 0, false},
+		{"117", "Joke made, but no one laughs", false, "", "", 0, false},
+		{"118", "Someone eats on screen", false, "", "", 0, false},
+		{"119", "Answer all Dory questions", false, "", "", 0, false},
+		{"120", "\"self care\"", false, "", "", 0, false},
+		{"121", "\"Can you see my screen?\"", false, "", "", 0, false},
+		{"122", "\"headcount\"", false, "", "", 0, false},
+		{"123", "CEO's name mentioned", false, "", "", 0, false},
+		{"124", "\"TK\"", false, "", "", 0, false},
+		{"125", "VP's name mentioned", false, "", "", 0, false},
 	}
 
 	return phrases
@@ -235,59 +369,102 @@ func (a *Agent) getDefaultList() []Phrase {
 // GAMES
 ////////////////////////////////////////////////////////////////////////////////
 
-// NewGame will create a new game in the database and initialize it.
-func (a *Agent) NewGame(name string, player Player) (Game, error) {
+// NewGame will create a new game in the database and initialize it. The free
+// squares passed in replace any free squares in the master list of phrases.
+// Lobby games start with only their free squares, leaving the rest of the
+// phrases to be voted on by the players.
+func (a *Agent) NewGame(ctx context.Context, name string, player Player, opts GameOptions) (_ Game, err error) {
+	ctx, done := a.observe(ctx, "NewGame", &err)
+	defer done()
+	g := NewGame(name, player, []Phrase{})
+	g.Lobby = opts.Lobby
+	g.Balanced = opts.Balanced
+	g.OneAway = opts.OneAway
+	g.EndAfter = opts.EndAfter
+	g.Scoring = opts.Scoring
+	g.Claims = opts.Claims
+	g.Penalty = opts.Penalty
+	welcome := "The lobby is open, suggest some phrases!"
+
+	if !g.Lobby {
+		phrases, err := a.GetPhrases(ctx)
+		if err != nil {
+			return Game{}, fmt.Errorf("failed to get phrases: %v", err)
+		}
+		g.Master.Load(phrases)
+		welcome = "Game has begun!"
+	}
 
-	phrases, err := a.GetPhrases()
-	if err != nil {
-		return Game{}, fmt.Errorf("failed to get phrases: %v", err)
+	g.Master.SetFree(opts.Free)
+
+	if !g.Lobby && len(g.Master.Records) < boardSize {
+		return Game{}, fmt.Errorf("not enough phrases to fill a board: need %d, have %d", boardSize, len(g.Master.Records))
+	}
+
+	if g.Balanced && !g.Lobby {
+		stats, err := a.GetPhraseStats(ctx)
+		if err != nil {
+			return Game{}, fmt.Errorf("failed to get phrase stats: %v", err)
+		}
+		g.Master.SetRates(stats.Rates())
 	}
 
-	g := NewGame(name, player, phrases)
+	if err := a.createGame(ctx, g, welcome); err != nil {
+		return g, err
+	}
 
+	return g, nil
+}
+
+func (a *Agent) createGame(ctx context.Context, g Game, welcome string) error {
 	batch := a.client.Batch()
-	a.log(fmt.Sprintf("Creating new game, id: %s", g.ID))
+	a.log(ctx, fmt.Sprintf("Creating new game, id: %s", g.ID))
 
 	gref := a.client.Collection("games").Doc(g.ID)
 	batch.Set(gref, g)
 
-	aref := a.client.Collection("games").Doc(g.ID).Collection("admins").Doc(player.Email)
-	batch.Set(aref, player)
+	for _, v := range g.Admins {
+		aref := a.client.Collection("games").Doc(g.ID).Collection("admins").Doc(v.Email)
+		batch.Set(aref, v)
+	}
 
-	pref := a.client.Collection("games").Doc(g.ID).Collection("players").Doc(player.Email)
-	batch.Set(pref, player)
+	for _, v := range g.Players {
+		pref := a.client.Collection("games").Doc(g.ID).Collection("players").Doc(v.Email)
+		batch.Set(pref, v)
+	}
 
-	a.log("Adding phrases to new game")
+	a.log(ctx, "Adding phrases to new game")
 	for _, v := range g.Master.Records {
 		ref := a.client.Collection("games").Doc(g.ID).Collection("records").Doc(v.Phrase.ID)
 		batch.Set(ref, v)
 	}
 
 	m := Message{}
-	m.SetText("Game has begun!")
+	m.SetText("%s", welcome)
 	m.SetAudience("all")
 
 	timestamp := strconv.FormatInt(time.Now().UTC().UnixNano(), 10)
 	mref := a.client.Collection("games").Doc(g.ID).Collection("messages").Doc(timestamp)
 	batch.Set(mref, m)
 
-	_, err = batch.Commit(a.ctx)
-	if err != nil {
-		return g, fmt.Errorf("failed to add records to database: %v", err)
+	if _, err := batch.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to add records to database: %v", err)
 	}
 
-	return g, nil
+	return nil
 }
 
 // GetGames finds a collection of all games.
-func (a *Agent) GetGames(limit int, token time.Time) (Games, error) {
+func (a *Agent) GetGames(ctx context.Context, limit int, token time.Time) (_ Games, err error) {
+	ctx, done := a.observe(ctx, "GetGames", &err)
+	defer done()
 	g := []Game{}
 
-	a.log("Getting Games")
+	a.log(ctx, "Getting Games")
 	iter := a.client.Collection("games").
 		Where("active", "==", true).Limit(limit).
 		OrderBy("created", firestore.Desc).
-		StartAfter(token).Documents(a.ctx)
+		StartAfter(token).Documents(ctx)
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -300,22 +477,22 @@ func (a *Agent) GetGames(limit int, token time.Time) (Games, error) {
 		doc.DataTo(&game)
 		game.ID = doc.Ref.ID
 
-		game, err = a.loadGameWithRecords(game)
+		game, err = a.loadGameWithRecords(ctx, game)
 		if err != nil {
 			return g, fmt.Errorf("failed to load records for game: %v", err)
 		}
 
-		game, err = a.loadGameWithPlayers(game)
+		game, err = a.loadGameWithPlayers(ctx, game)
 		if err != nil {
 			return g, fmt.Errorf("failed to load players for game: %v", err)
 		}
 
-		game, err = a.loadGameWithAdmins(game)
+		game, err = a.loadGameWithAdmins(ctx, game)
 		if err != nil {
 			return g, fmt.Errorf("failed to load admins for game: %v", err)
 		}
 
-		game, err = a.loadGameWithBoards(game)
+		game, err = a.loadGameWithBoards(ctx, game)
 		if err != nil {
 			return g, fmt.Errorf("failed to load boards for game: %v", err)
 		}
@@ -326,35 +503,58 @@ func (a *Agent) GetGames(limit int, token time.Time) (Games, error) {
 	return g, nil
 }
 
+// CountActiveGames counts the games that are still being played.
+func (a *Agent) CountActiveGames(ctx context.Context) (_ int, err error) {
+	ctx, done := a.observe(ctx, "CountActiveGames", &err)
+	defer done()
+
+	count := 0
+	iter := a.client.Collection("games").Where("active", "==", true).Select().Documents(ctx)
+	for {
+		_, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to count active games: %v", err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
 // GetGame gets a given game from the database
-func (a *Agent) GetGame(gid string) (Game, error) {
+func (a *Agent) GetGame(ctx context.Context, gid string) (_ Game, err error) {
+	ctx, done := a.observe(ctx, "GetGame", &err)
+	defer done()
 	g := Game{}
 	g.Boards = map[string]Board{}
 
-	a.log("Getting existing game")
-	doc, err := a.client.Collection("games").Doc(gid).Get(a.ctx)
+	a.log(ctx, "Getting existing game")
+	doc, err := a.client.Collection("games").Doc(gid).Get(ctx)
 	if err != nil {
 		return g, fmt.Errorf("failed to get game: %v", err)
 	}
 
 	doc.DataTo(&g)
 	g.ID = gid
-	g, err = a.loadGameWithRecords(g)
+	g, err = a.loadGameWithRecords(ctx, g)
 	if err != nil {
 		return g, fmt.Errorf("failed to load records for game: %v", err)
 	}
 
-	g, err = a.loadGameWithPlayers(g)
+	g, err = a.loadGameWithPlayers(ctx, g)
 	if err != nil {
 		return g, fmt.Errorf("failed to load players for game: %v", err)
 	}
 
-	g, err = a.loadGameWithAdmins(g)
+	g, err = a.loadGameWithAdmins(ctx, g)
 	if err != nil {
 		return g, fmt.Errorf("failed to load admins for game: %v", err)
 	}
 
-	g, err = a.loadGameWithBoards(g)
+	g, err = a.loadGameWithBoards(ctx, g)
 	if err != nil {
 		return g, fmt.Errorf("failed to load boards for game: %v", err)
 	}
@@ -362,10 +562,10 @@ func (a *Agent) GetGame(gid string) (Game, error) {
 	return g, nil
 }
 
-func (a *Agent) loadGameWithRecords(game Game) (Game, error) {
+func (a *Agent) loadGameWithRecords(ctx context.Context, game Game) (Game, error) {
 
-	a.log("Loading records from game")
-	iter := a.client.Collection("games").Doc(game.ID).Collection("records").Documents(a.ctx)
+	a.log(ctx, "Loading records from game")
+	iter := a.client.Collection("games").Doc(game.ID).Collection("records").Documents(ctx)
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -382,10 +582,10 @@ func (a *Agent) loadGameWithRecords(game Game) (Game, error) {
 	return game, nil
 }
 
-func (a *Agent) loadGameWithPlayers(game Game) (Game, error) {
+func (a *Agent) loadGameWithPlayers(ctx context.Context, game Game) (Game, error) {
 
-	a.log("Loading players from game")
-	iter := a.client.Collection("games").Doc(game.ID).Collection("players").Documents(a.ctx)
+	a.log(ctx, "Loading players from game")
+	iter := a.client.Collection("games").Doc(game.ID).Collection("players").Documents(ctx)
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -402,10 +602,10 @@ func (a *Agent) loadGameWithPlayers(game Game) (Game, error) {
 	return game, nil
 }
 
-func (a *Agent) loadGameWithBoards(game Game) (Game, error) {
+func (a *Agent) loadGameWithBoards(ctx context.Context, game Game) (Game, error) {
 
-	a.log("Loading boards from game")
-	iter := a.client.Collection("games").Doc(game.ID).Collection("boards").Documents(a.ctx)
+	a.log(ctx, "Loading boards from game")
+	iter := a.client.Collection("games").Doc(game.ID).Collection("boards").Documents(ctx)
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -417,7 +617,7 @@ func (a *Agent) loadGameWithBoards(game Game) (Game, error) {
 		b := InitBoard()
 		doc.DataTo(&b)
 
-		b, err = a.loadBoardWithPhrases(b)
+		b, err = a.loadBoardWithPhrases(ctx, b)
 		if err != nil {
 			return game, fmt.Errorf("Failed to populare board: %v", err)
 		}
@@ -428,11 +628,11 @@ func (a *Agent) loadGameWithBoards(game Game) (Game, error) {
 	return game, nil
 }
 
-func (a *Agent) loadGameWithAdmins(game Game) (Game, error) {
+func (a *Agent) loadGameWithAdmins(ctx context.Context, game Game) (Game, error) {
 	game.Boards = map[string]Board{}
 
-	a.log("Loading players from game")
-	iter := a.client.Collection("games").Doc(game.ID).Collection("admins").Documents(a.ctx)
+	a.log(ctx, "Loading players from game")
+	iter := a.client.Collection("games").Doc(game.ID).Collection("admins").Documents(ctx)
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -450,14 +650,16 @@ func (a *Agent) loadGameWithAdmins(game Game) (Game, error) {
 }
 
 // SaveGame records a game to firestore.
-func (a *Agent) SaveGame(game Game) error {
+func (a *Agent) SaveGame(ctx context.Context, game Game) (err error) {
+	ctx, done := a.observe(ctx, "SaveGame", &err)
+	defer done()
 
-	oldgame, err := a.GetGame(game.ID)
+	oldgame, err := a.GetGame(ctx, game.ID)
 	if err != nil {
 		return fmt.Errorf("error getting old data for game: %s", err)
 	}
 
-	a.log("Save game")
+	a.log(ctx, "Save game")
 	batch := a.client.Batch()
 	gref := a.client.Collection("games").Doc(game.ID)
 	batch.Set(gref, game)
@@ -484,46 +686,230 @@ func (a *Agent) SaveGame(game Game) error {
 		batch.Set(ref, v)
 	}
 
-	if _, err := batch.Commit(a.ctx); err != nil {
+	if _, err := batch.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to save game to database: %v", err)
 	}
 
 	return nil
 }
 
+// SaveWinner adds a board that won to the winners of a game. The winners are
+// read and written in a transaction, so boards that win at the same time are
+// all kept. It returns every winner of the game.
+func (a *Agent) SaveWinner(ctx context.Context, game Game, board Board) (_ []Winner, err error) {
+	ctx, done := a.observe(ctx, "SaveWinner", &err)
+	defer done()
+
+	w := Winner{}
+	for _, v := range game.Winners {
+		if v.Board == board.ID {
+			w = v
+		}
+	}
+
+	a.log(ctx, "Saving winner")
+	winners := []Winner{}
+	ref := a.client.Collection("games").Doc(game.ID)
+	err = a.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+
+		stored := Game{}
+		doc.DataTo(&stored)
+		stored.AddWinner(board, w.Time)
+		winners = stored.Winners
+
+		return tx.Update(ref, []firestore.Update{{Path: "winners", Value: winners}})
+	})
+	if err != nil {
+		return winners, fmt.Errorf("failed to save winners: %v", err)
+	}
+
+	return winners, nil
+}
+
+// SaveRounds records the current round of a game, along with the results of
+// the rounds before it.
+func (a *Agent) SaveRounds(ctx context.Context, game Game) (err error) {
+	ctx, done := a.observe(ctx, "SaveRounds", &err)
+	defer done()
+
+	a.log(ctx, "Saving rounds")
+	ref := a.client.Collection("games").Doc(game.ID)
+	updates := []firestore.Update{
+		{Path: "round", Value: game.Round},
+		{Path: "started", Value: game.Started},
+		{Path: "rounds", Value: game.Rounds},
+		{Path: "winners", Value: game.Winners},
+		{Path: "active", Value: game.Active},
+	}
+	if _, err := ref.Update(ctx, updates); err != nil {
+		return fmt.Errorf("failed to save rounds: %v", err)
+	}
+
+	return nil
+}
+
+// SaveBoardStatus records the scores, rejected claims and bingo status of
+// boards in a game.
+func (a *Agent) SaveBoardStatus(ctx context.Context, game Game, boards []Board) (err error) {
+	ctx, done := a.observe(ctx, "SaveBoardStatus", &err)
+	defer done()
+	if len(boards) == 0 {
+		return nil
+	}
+
+	a.log(ctx, "Saving board status")
+	batch := a.client.Batch()
+	for _, v := range boards {
+		ref := a.client.Collection("games").Doc(game.ID).Collection("boards").Doc(v.ID)
+		update := map[string]interface{}{"points": v.Points, "rejected": v.Rejected, "bingodeclared": v.BingoDeclared}
+		batch.Set(ref, update, firestore.MergeAll)
+	}
+
+	if _, err := batch.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to save board status: %v", err)
+	}
+
+	return nil
+}
+
 // UpdatePhrase updates a phrase on a particular game and all boards associated with it.
-func (a *Agent) UpdatePhrase(game Game, phrase Phrase) error {
+func (a *Agent) UpdatePhrase(ctx context.Context, game Game, phrase Phrase) (err error) {
+	ctx, done := a.observe(ctx, "UpdatePhrase", &err)
+	defer done()
 	b := game.Boards
 
-	phraseMap := map[string]interface{}{"text": phrase.Text, "selected": false}
-	recordMap := map[string]interface{}{"phrase": phraseMap, "players": Players{}}
+	phraseMap := map[string]interface{}{"text": phrase.Text, "selected": phrase.Free}
+	recordPhraseMap := map[string]interface{}{"text": phrase.Text, "selected": false}
+	_, record := game.FindRecord(phrase)
+	recordMap := map[string]interface{}{"phrase": recordPhraseMap, "players": Players{}, "edited": record.Edited}
 
 	batch := a.client.Batch()
 	recoref := a.client.Collection("games").Doc(game.ID).Collection("records").Doc(phrase.ID)
 	batch.Set(recoref, recordMap, firestore.MergeAll)
 
 	for _, v := range b {
+		if _, ok := v.Phrases[phrase.ID]; !ok {
+			continue
+		}
 		msg := fmt.Sprintf("Updating to phrase %s on board %s on game %s", phrase.ID, v.ID, game.ID)
-		a.log(msg)
+		a.log(ctx, msg)
 		ref := a.client.Collection("games").Doc(game.ID).Collection("boards").Doc(v.ID).Collection("phrases").Doc(phrase.ID)
 		batch.Set(ref, phraseMap, firestore.MergeAll)
 	}
 
-	a.log("Committing Batch")
-	if _, err := batch.Commit(a.ctx); err != nil {
+	a.log(ctx, "Committing Batch")
+	if _, err := batch.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to update phrase: %v", err)
 	}
 
 	return nil
 }
 
+// UpdatePhraseText updates the text of a phrase on a particular game and all
+// boards associated with it, leaving selections alone.
+func (a *Agent) UpdatePhraseText(ctx context.Context, game Game, phrase Phrase) (err error) {
+	ctx, done := a.observe(ctx, "UpdatePhraseText", &err)
+	defer done()
+	phraseMap := map[string]interface{}{"text": phrase.Text}
+	recordMap := map[string]interface{}{"phrase": phraseMap}
+
+	batch := a.client.Batch()
+	recoref := a.client.Collection("games").Doc(game.ID).Collection("records").Doc(phrase.ID)
+	batch.Set(recoref, recordMap, firestore.MergeAll)
+
+	for _, v := range game.Boards {
+		if _, ok := v.Phrases[phrase.ID]; !ok {
+			continue
+		}
+		msg := fmt.Sprintf("Updating text of phrase %s on board %s on game %s", phrase.ID, v.ID, game.ID)
+		a.log(ctx, msg)
+		ref := a.client.Collection("games").Doc(game.ID).Collection("boards").Doc(v.ID).Collection("phrases").Doc(phrase.ID)
+		batch.Set(ref, phraseMap, firestore.MergeAll)
+	}
+
+	a.log(ctx, "Committing Batch")
+	if _, err := batch.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to update phrase text: %v", err)
+	}
+
+	return nil
+}
+
+// SaveGamePhrases saves all of the master records of a game and the phrases of
+// the boards passed in. Phrases with an id in removed are deleted from the
+// records, and any phrase that is no longer on a board is deleted from it.
+func (a *Agent) SaveGamePhrases(ctx context.Context, game Game, boards []Board, removed []string) (err error) {
+	ctx, done := a.observe(ctx, "SaveGamePhrases", &err)
+	defer done()
+
+	a.log(ctx, "Saving game records")
+	batch := a.client.Batch()
+	for _, id := range removed {
+		ref := a.client.Collection("games").Doc(game.ID).Collection("records").Doc(id)
+		batch.Delete(ref)
+	}
+
+	for _, v := range game.Master.Records {
+		ref := a.client.Collection("games").Doc(game.ID).Collection("records").Doc(v.Phrase.ID)
+		batch.Set(ref, v)
+	}
+
+	if _, err := batch.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to save game records: %v", err)
+	}
+
+	for _, b := range boards {
+		a.log(ctx, fmt.Sprintf("Saving phrases on board %s on game %s", b.ID, game.ID))
+		batch := a.client.Batch()
+		bref := a.client.Collection("games").Doc(game.ID).Collection("boards").Doc(b.ID)
+
+		stale := append([]string{}, removed...)
+		for _, v := range game.Master.Records {
+			if _, ok := b.Phrases[v.Phrase.ID]; !ok {
+				stale = append(stale, v.Phrase.ID)
+			}
+		}
+
+		for _, id := range stale {
+			batch.Delete(bref.Collection("phrases").Doc(id))
+		}
+
+		for _, v := range b.Phrases {
+			batch.Set(bref.Collection("phrases").Doc(v.ID), v)
+		}
+
+		update := map[string]interface{}{
+			"bingodeclared": b.BingoDeclared,
+			"points":        b.Points,
+			"rejected":      b.Rejected,
+			"odds":          b.Odds,
+			"fairness":      b.Fairness,
+			"activity":      b.Activity,
+			"suspicion":     b.Suspicion,
+		}
+		batch.Set(bref, update, firestore.MergeAll)
+
+		if _, err := batch.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to save board phrases: %v", err)
+		}
+	}
+
+	return nil
+}
+
 // GetBoardsForGame gets all the boards for a give game.
-func (a *Agent) GetBoardsForGame(game Game) ([]Board, error) {
+func (a *Agent) GetBoardsForGame(ctx context.Context, game Game) (_ []Board, err error) {
+	ctx, done := a.observe(ctx, "GetBoardsForGame", &err)
+	defer done()
 
 	b := []Board{}
 
-	a.log("Getting boards for game")
-	iter := a.client.Collection("games").Doc(game.ID).Collection("boards").Documents(a.ctx)
+	a.log(ctx, "Getting boards for game")
+	iter := a.client.Collection("games").Doc(game.ID).Collection("boards").Documents(ctx)
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -537,7 +923,7 @@ func (a *Agent) GetBoardsForGame(game Game) ([]Board, error) {
 		doc.DataTo(&board)
 		board.ID = doc.Ref.ID
 
-		board, err = a.loadBoardWithPhrases(board)
+		board, err = a.loadBoardWithPhrases(ctx, board)
 		if err != nil {
 			return b, fmt.Errorf("Failed to populare board: %v", err)
 		}
@@ -550,13 +936,15 @@ func (a *Agent) GetBoardsForGame(game Game) ([]Board, error) {
 }
 
 // GetGamesForKey fetches the list of all games a user in currently in.
-func (a *Agent) GetGamesForKey(email string) (Games, error) {
+func (a *Agent) GetGamesForKey(ctx context.Context, email string) (_ Games, err error) {
+	ctx, done := a.observe(ctx, "GetGamesForKey", &err)
+	defer done()
 
 	g := []Game{}
 
 	refs := []*firestore.DocumentRef{}
-	a.log("Getting Games for player")
-	iter := a.client.CollectionGroup("players").Where("email", "==", email).Documents(a.ctx)
+	a.log(ctx, "Getting Games for player")
+	iter := a.client.CollectionGroup("players").Where("email", "==", email).Documents(ctx)
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -569,8 +957,8 @@ func (a *Agent) GetGamesForKey(email string) (Games, error) {
 		refs = append(refs, doc.Ref.Parent.Parent)
 	}
 
-	a.log("Getting Games for player")
-	snapshots, err := a.client.GetAll(a.ctx, refs)
+	a.log(ctx, "Getting Games for player")
+	snapshots, err := a.client.GetAll(ctx, refs)
 	if err != nil {
 		return g, fmt.Errorf("Failed to get games: %v", err)
 	}
@@ -584,7 +972,7 @@ func (a *Agent) GetGamesForKey(email string) (Games, error) {
 			continue
 		}
 
-		game, err := a.loadGameWithAdmins(game)
+		game, err := a.loadGameWithAdmins(ctx, game)
 		if err != nil {
 			return g, fmt.Errorf("failed to get admins for game: %v", err)
 		}
@@ -595,15 +983,17 @@ func (a *Agent) GetGamesForKey(email string) (Games, error) {
 	return g, nil
 }
 
-func (a *Agent) PurgeOldGames() error {
+func (a *Agent) PurgeOldGames(ctx context.Context) (err error) {
+	ctx, done := a.observe(ctx, "PurgeOldGames", &err)
+	defer done()
 	g := []Game{}
 
 	dateCutoff := time.Now().AddDate(0, 0, -30)
 
 	msg := fmt.Sprintf("Getting Games before %s", dateCutoff.Format("2006-01-02"))
 
-	a.log(msg)
-	iter := a.client.Collection("games").Where("created", "<", dateCutoff).Documents(a.ctx)
+	a.log(ctx, msg)
+	iter := a.client.Collection("games").Where("created", "<", dateCutoff).Documents(ctx)
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -621,8 +1011,8 @@ func (a *Agent) PurgeOldGames() error {
 
 	for _, v := range g {
 		msg := fmt.Sprintf("%s - %s \n", v.Name, v.Created.Format("2006-01-02"))
-		a.log(msg)
-		err := a.DeleteGame(v)
+		a.log(ctx, msg)
+		err := a.DeleteGame(ctx, v)
 		if err != nil {
 			return fmt.Errorf("failure deleting %s: %s", v.Name, err)
 		}
@@ -632,55 +1022,98 @@ func (a *Agent) PurgeOldGames() error {
 }
 
 // DeleteGame delete a specifc game from firestore
-func (a *Agent) DeleteGame(game Game) error {
+func (a *Agent) DeleteGame(ctx context.Context, game Game) (err error) {
+	ctx, done := a.observe(ctx, "DeleteGame", &err)
+	defer done()
 
 	refs := []*firestore.DocumentRef{}
 
-	game, err := a.GetGame(game.ID)
+	game, err = a.GetGame(ctx, game.ID)
 	if err != nil {
 		return fmt.Errorf("loading complete game data: %v", err)
 	}
 
 	batch := a.client.Batch()
-	a.log("Deleting game")
+	a.log(ctx, "Deleting game")
 	gref := a.client.Collection("games").Doc(game.ID)
 	refs = append(refs, gref)
 
 	for _, v := range game.Admins {
-		a.log("Deleting admin: " + v.Email)
+		a.log(ctx, "Deleting admin: "+v.Email)
 		rref := a.client.Collection("games").Doc(game.ID).Collection("admins").Doc(v.Email)
 		refs = append(refs, rref)
 	}
 
 	for _, v := range game.Players {
-		a.log("Deleting player: " + v.Email)
+		a.log(ctx, "Deleting player: "+v.Email)
 		rref := a.client.Collection("games").Doc(game.ID).Collection("players").Doc(v.Email)
 		refs = append(refs, rref)
 	}
 
 	for _, v := range game.Master.Records {
-		a.log("Deleting record: " + v.ID)
+		a.log(ctx, "Deleting record: "+v.ID)
 		rref := a.client.Collection("games").Doc(game.ID).Collection("records").Doc(v.ID)
 		refs = append(refs, rref)
 	}
 
 	for _, v := range game.Boards {
-		a.log("Deleting boards: " + v.ID)
+		a.log(ctx, "Deleting boards: "+v.ID)
 		rref := a.client.Collection("games").Doc(game.ID).Collection("boards").Doc(v.ID)
 		refs = append(refs, rref)
 
 		for _, subv := range v.Phrases {
-			a.log("Deleting phrases: " + subv.ID)
+			a.log(ctx, "Deleting phrases: "+subv.ID)
 			pref := a.client.Collection("games").Doc(game.ID).Collection("boards").Doc(v.ID).Collection("phrases").Doc(subv.ID)
 			refs = append(refs, pref)
 		}
 	}
 
-	a.log("removing messages from board")
+	a.log(ctx, "removing suggestions for game")
+	siter := a.client.Collection("suggestions").Where("game", "==", game.ID).Documents(ctx)
+	for {
+		doc, err := siter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to clean suggestions from firestore: %v", err)
+		}
+		refs = append(refs, doc.Ref)
+	}
+
+	a.log(ctx, "removing lobby candidates for game")
+	citer := a.client.Collection("games").Doc(game.ID).Collection("candidates").Documents(ctx)
+	for {
+		doc, err := citer.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to clean candidates from firestore: %v", err)
+		}
+		refs = append(refs, doc.Ref)
+	}
+
+	a.log(ctx, "removing game from tournaments")
+	titer := a.client.Collection("tournaments").Where("games", "array-contains", game.ID).Documents(ctx)
+	for {
+		doc, err := titer.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to clean tournaments from firestore: %v", err)
+		}
+		if _, err := doc.Ref.Update(ctx, []firestore.Update{{Path: "games", Value: firestore.ArrayRemove(game.ID)}}); err != nil {
+			return fmt.Errorf("failed to remove game from tournament: %v", err)
+		}
+	}
+
+	a.log(ctx, "removing messages from board")
 	ref := a.client.Collection("games").Doc(game.ID).Collection("messages")
 	for {
 		// Get a batch of documents
-		iter := ref.Limit(500).Documents(a.ctx)
+		iter := ref.Limit(500).Documents(ctx)
 
 		for {
 			doc, err := iter.Next()
@@ -691,7 +1124,7 @@ func (a *Agent) DeleteGame(game Game) error {
 				return fmt.Errorf("failed to clean messages from firestore: %v", err)
 			}
 
-			a.log(fmt.Sprintf("removing message %s from board", doc.Ref.ID))
+			a.log(ctx, fmt.Sprintf("removing message %s from board", doc.Ref.ID))
 			refs = append(refs, doc.Ref)
 			batch.Delete(doc.Ref)
 		}
@@ -713,13 +1146,13 @@ func (a *Agent) DeleteGame(game Game) error {
 				break
 			}
 
-			if _, err := batch.Commit(a.ctx); err != nil {
+			if _, err := batch.Commit(ctx); err != nil {
 				return fmt.Errorf("failed to clean messages from firestore: %v", err)
 			}
 
 		}
 
-		if _, err := batch.Commit(a.ctx); err != nil {
+		if _, err := batch.Commit(ctx); err != nil {
 			return fmt.Errorf("failed to clean messages from firestore: %v", err)
 		}
 		return nil
@@ -728,23 +1161,313 @@ func (a *Agent) DeleteGame(game Game) error {
 
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// SUGGESTIONS
+////////////////////////////////////////////////////////////////////////////////
+
+// SaveSuggestion records a phrase suggestion to firestore.
+func (a *Agent) SaveSuggestion(ctx context.Context, suggestion Suggestion) (err error) {
+	ctx, done := a.observe(ctx, "SaveSuggestion", &err)
+	defer done()
+
+	a.log(ctx, "Saving suggestion")
+	if _, err := a.client.Collection("suggestions").Doc(suggestion.ID).Set(ctx, suggestion); err != nil {
+		return fmt.Errorf("failed to save suggestion: %v", err)
+	}
+
+	return nil
+}
+
+// GetSuggestion retrieves a specific suggestion from firestore.
+func (a *Agent) GetSuggestion(ctx context.Context, sid string) (_ Suggestion, err error) {
+	ctx, done := a.observe(ctx, "GetSuggestion", &err)
+	defer done()
+	s := Suggestion{}
+
+	a.log(ctx, "Getting suggestion")
+	doc, err := a.client.Collection("suggestions").Doc(sid).Get(ctx)
+	if err != nil {
+		return s, fmt.Errorf("failed to get suggestion: %v", err)
+	}
+
+	doc.DataTo(&s)
+	s.ID = sid
+
+	return s, nil
+}
+
+// GetPendingSuggestions lists the suggestions waiting on an admin, either for
+// the master list or for a particular game.
+func (a *Agent) GetPendingSuggestions(ctx context.Context, gid string, master bool) (_ Suggestions, err error) {
+	ctx, done := a.observe(ctx, "GetPendingSuggestions", &err)
+	defer done()
+	s := Suggestions{}
+
+	a.log(ctx, "Getting pending suggestions")
+	q := a.client.Collection("suggestions").
+		Where("master", "==", master).
+		Where("status", "==", SuggestionPending)
+
+	if !master {
+		q = q.Where("game", "==", gid)
+	}
+
+	iter := q.OrderBy("created", firestore.Asc).Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return s, fmt.Errorf("Failed to iterate: %v", err)
+		}
+		suggestion := Suggestion{}
+		doc.DataTo(&suggestion)
+		suggestion.ID = doc.Ref.ID
+		s = append(s, suggestion)
+	}
+
+	return s, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TOURNAMENTS
+////////////////////////////////////////////////////////////////////////////////
+
+// SaveTournament records a tournament to firestore.
+func (a *Agent) SaveTournament(ctx context.Context, tournament Tournament) (err error) {
+	ctx, done := a.observe(ctx, "SaveTournament", &err)
+	defer done()
+
+	a.log(ctx, "Saving tournament")
+	if _, err := a.client.Collection("tournaments").Doc(tournament.ID).Set(ctx, tournament); err != nil {
+		return fmt.Errorf("failed to save tournament: %v", err)
+	}
+
+	return nil
+}
+
+// GetTournament retrieves a specific tournament from firestore.
+func (a *Agent) GetTournament(ctx context.Context, tid string) (_ Tournament, err error) {
+	ctx, done := a.observe(ctx, "GetTournament", &err)
+	defer done()
+	t := Tournament{}
+
+	a.log(ctx, "Getting tournament")
+	doc, err := a.client.Collection("tournaments").Doc(tid).Get(ctx)
+	if err != nil {
+		return t, fmt.Errorf("failed to get tournament: %v", err)
+	}
+
+	doc.DataTo(&t)
+	t.ID = tid
+
+	return t, nil
+}
+
+// GetTournaments lists every tournament, newest first.
+func (a *Agent) GetTournaments(ctx context.Context) (_ Tournaments, err error) {
+	ctx, done := a.observe(ctx, "GetTournaments", &err)
+	defer done()
+	t := Tournaments{}
+
+	a.log(ctx, "Getting tournaments")
+	iter := a.client.Collection("tournaments").OrderBy("created", firestore.Desc).Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return t, fmt.Errorf("Failed to iterate: %v", err)
+		}
+		tournament := Tournament{}
+		doc.DataTo(&tournament)
+		tournament.ID = doc.Ref.ID
+		t = append(t, tournament)
+	}
+
+	return t, nil
+}
+
+// DeleteTournament removes a tournament from firestore, leaving its games.
+func (a *Agent) DeleteTournament(ctx context.Context, tournament Tournament) (err error) {
+	ctx, done := a.observe(ctx, "DeleteTournament", &err)
+	defer done()
+
+	a.log(ctx, "Deleting tournament")
+	if _, err := a.client.Collection("tournaments").Doc(tournament.ID).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete tournament: %v", err)
+	}
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TOKENS
+////////////////////////////////////////////////////////////////////////////////
+
+// SaveToken records an API token to firestore.
+func (a *Agent) SaveToken(ctx context.Context, token Token) (err error) {
+	ctx, done := a.observe(ctx, "SaveToken", &err)
+	defer done()
+
+	a.log(ctx, "Saving token")
+	if _, err := a.client.Collection("tokens").Doc(token.ID).Set(ctx, token); err != nil {
+		return fmt.Errorf("failed to save token: %v", err)
+	}
+
+	return nil
+}
+
+// GetToken retrieves a specific API token from firestore.
+func (a *Agent) GetToken(ctx context.Context, id string) (_ Token, err error) {
+	ctx, done := a.observe(ctx, "GetToken", &err)
+	defer done()
+	t := Token{}
+
+	a.log(ctx, "Getting token")
+	doc, err := a.client.Collection("tokens").Doc(id).Get(ctx)
+	if err != nil {
+		return t, fmt.Errorf("failed to get token: %v", err)
+	}
+
+	doc.DataTo(&t)
+	t.ID = id
+
+	return t, nil
+}
+
+// GetTokens lists every API token, newest first.
+func (a *Agent) GetTokens(ctx context.Context) (_ Tokens, err error) {
+	ctx, done := a.observe(ctx, "GetTokens", &err)
+	defer done()
+	t := Tokens{}
+
+	a.log(ctx, "Getting tokens")
+	iter := a.client.Collection("tokens").OrderBy("created", firestore.Desc).Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return t, fmt.Errorf("Failed to iterate: %v", err)
+		}
+		token := Token{}
+		doc.DataTo(&token)
+		token.ID = doc.Ref.ID
+		t = append(t, token)
+	}
+
+	return t, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// LOBBY
+////////////////////////////////////////////////////////////////////////////////
+
+// AddPlayerToGame records a player joining a game without a board, as they
+// do while the game is in its lobby.
+func (a *Agent) AddPlayerToGame(ctx context.Context, game Game, player Player) (err error) {
+	ctx, done := a.observe(ctx, "AddPlayerToGame", &err)
+	defer done()
+
+	a.log(ctx, "Adding player to game")
+	ref := a.client.Collection("games").Doc(game.ID).Collection("players").Doc(player.Email)
+	if _, err := ref.Set(ctx, player); err != nil {
+		return fmt.Errorf("failed to add player to game: %v", err)
+	}
+
+	return nil
+}
+
+// SaveCandidate records a lobby candidate phrase to firestore.
+func (a *Agent) SaveCandidate(ctx context.Context, gid string, candidate Candidate) (err error) {
+	ctx, done := a.observe(ctx, "SaveCandidate", &err)
+	defer done()
+
+	a.log(ctx, "Saving candidate")
+	ref := a.client.Collection("games").Doc(gid).Collection("candidates").Doc(candidate.ID)
+	if _, err := ref.Set(ctx, candidate); err != nil {
+		return fmt.Errorf("failed to save candidate: %v", err)
+	}
+
+	return nil
+}
+
+// VoteCandidate adds or removes the vote of a player on a lobby candidate. The
+// votes are read and written in a transaction, so votes cast at the same
+// time aren't lost.
+func (a *Agent) VoteCandidate(ctx context.Context, gid, cid string, player Player, up bool) (err error) {
+	ctx, done := a.observe(ctx, "VoteCandidate", &err)
+	defer done()
+
+	a.log(ctx, "Voting on candidate")
+	ref := a.client.Collection("games").Doc(gid).Collection("candidates").Doc(cid)
+	return a.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return fmt.Errorf("failed to get candidate: %v", err)
+		}
+
+		c := Candidate{}
+		doc.DataTo(&c)
+		c.ID = cid
+
+		if err := c.Vote(player, up); err != nil {
+			return err
+		}
+
+		return tx.Set(ref, c)
+	})
+}
+
+// GetCandidates lists the phrases put forward in the lobby of a game.
+func (a *Agent) GetCandidates(ctx context.Context, gid string) (_ Candidates, err error) {
+	ctx, done := a.observe(ctx, "GetCandidates", &err)
+	defer done()
+	c := Candidates{}
+
+	a.log(ctx, "Getting candidates")
+	iter := a.client.Collection("games").Doc(gid).Collection("candidates").
+		OrderBy("created", firestore.Asc).Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return c, fmt.Errorf("Failed to iterate: %v", err)
+		}
+		candidate := Candidate{}
+		doc.DataTo(&candidate)
+		candidate.ID = doc.Ref.ID
+		c = append(c, candidate)
+	}
+
+	return c, nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // MESSAGES
 ////////////////////////////////////////////////////////////////////////////////
 
 // AddMessagesToGame broadcasts a message to the game players
-func (a *Agent) AddMessagesToGame(game Game, messages []Message) error {
+func (a *Agent) AddMessagesToGame(ctx context.Context, game Game, messages []Message) (err error) {
+	ctx, done := a.observe(ctx, "AddMessagesToGame", &err)
+	defer done()
 
 	batch := a.client.Batch()
 	for _, v := range messages {
-		a.log("Adding message to game")
+		a.log(ctx, "Adding message to game")
 		timestamp := strconv.FormatInt(time.Now().UTC().UnixNano(), 10)
 		v.ID = timestamp
 		ref := a.client.Collection("games").Doc(game.ID).Collection("messages").Doc(timestamp)
 		batch.Set(ref, v)
 	}
 
-	if _, err := batch.Commit(a.ctx); err != nil {
+	if _, err := batch.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to send messages : %v", err)
 	}
 
@@ -752,7 +1475,9 @@ func (a *Agent) AddMessagesToGame(game Game, messages []Message) error {
 }
 
 // AcknowledgeMessage marks the message as having been received.
-func (a *Agent) AcknowledgeMessage(game Game, message Message) error {
+func (a *Agent) AcknowledgeMessage(ctx context.Context, game Game, message Message) (err error) {
+	ctx, done := a.observe(ctx, "AcknowledgeMessage", &err)
+	defer done()
 
 	update := map[string]interface{}{"received": true}
 	if _, err := a.client.Collection("games").Doc(game.ID).Collection("messages").Doc(message.ID).Set(ctx, update, firestore.MergeAll); err != nil {
@@ -767,11 +1492,13 @@ func (a *Agent) AcknowledgeMessage(game Game, message Message) error {
 ////////////////////////////////////////////////////////////////////////////////
 
 // GetBoardForPlayer returns the board for a given player
-func (a *Agent) GetBoardForPlayer(gid string, p Player) (Board, error) {
+func (a *Agent) GetBoardForPlayer(ctx context.Context, gid string, p Player) (_ Board, err error) {
+	ctx, done := a.observe(ctx, "GetBoardForPlayer", &err)
+	defer done()
 	b := InitBoard()
 
-	a.log("get board for player")
-	iter := a.client.Collection("games").Doc(gid).Collection("boards").Where("player.email", "==", p.Email).Documents(a.ctx)
+	a.log(ctx, "get board for player")
+	iter := a.client.Collection("games").Doc(gid).Collection("boards").Where("player.email", "==", p.Email).Documents(ctx)
 
 	for {
 		doc, err := iter.Next()
@@ -787,7 +1514,7 @@ func (a *Agent) GetBoardForPlayer(gid string, p Player) (Board, error) {
 	}
 	if b.ID != "" {
 		var err error
-		b, err = a.loadBoardWithPhrases(b)
+		b, err = a.loadBoardWithPhrases(ctx, b)
 		if err != nil {
 			return b, fmt.Errorf("failed to load phrases for board: %v", err)
 		}
@@ -798,18 +1525,20 @@ func (a *Agent) GetBoardForPlayer(gid string, p Player) (Board, error) {
 }
 
 // GetBoard retrieves a specifc board from firestore
-func (a *Agent) GetBoard(bid, gid string) (Board, error) {
+func (a *Agent) GetBoard(ctx context.Context, bid, gid string) (_ Board, err error) {
+	ctx, done := a.observe(ctx, "GetBoard", &err)
+	defer done()
 	b := InitBoard()
 
-	a.log("Getting board")
-	doc, err := a.client.Collection("games").Doc(gid).Collection("boards").Doc(bid).Get(a.ctx)
+	a.log(ctx, "Getting board")
+	doc, err := a.client.Collection("games").Doc(gid).Collection("boards").Doc(bid).Get(ctx)
 	if err != nil {
 		return b, fmt.Errorf("failed to get board: %v", err)
 	}
 
 	doc.DataTo(&b)
 	b.ID = bid
-	b, err = a.loadBoardWithPhrases(b)
+	b, err = a.loadBoardWithPhrases(ctx, b)
 	if err != nil {
 		return b, fmt.Errorf("failed to load phrases for board: %v", err)
 	}
@@ -817,10 +1546,10 @@ func (a *Agent) GetBoard(bid, gid string) (Board, error) {
 	return b, nil
 }
 
-func (a *Agent) loadBoardWithPhrases(board Board) (Board, error) {
+func (a *Agent) loadBoardWithPhrases(ctx context.Context, board Board) (Board, error) {
 
-	a.log("Adding phrases to existing board")
-	iter := a.client.Collection("games").Doc(board.Game).Collection("boards").Doc(board.ID).Collection("phrases").OrderBy("displayorder", firestore.Asc).Documents(a.ctx)
+	a.log(ctx, "Adding phrases to existing board")
+	iter := a.client.Collection("games").Doc(board.Game).Collection("boards").Doc(board.ID).Collection("phrases").OrderBy("displayorder", firestore.Asc).Documents(ctx)
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -838,9 +1567,11 @@ func (a *Agent) loadBoardWithPhrases(board Board) (Board, error) {
 }
 
 // DeleteBoard delete a specifc board from firestore
-func (a *Agent) DeleteBoard(board Board, game Game) error {
+func (a *Agent) DeleteBoard(ctx context.Context, board Board, game Game) (err error) {
+	ctx, done := a.observe(ctx, "DeleteBoard", &err)
+	defer done()
 	batch := a.client.Batch()
-	a.log("Deleting board")
+	a.log(ctx, "Deleting board")
 	bref := a.client.Collection("games").Doc(game.ID).Collection("boards").Doc(board.ID)
 	batch.Delete(bref)
 
@@ -853,11 +1584,11 @@ func (a *Agent) DeleteBoard(board Board, game Game) error {
 		batch.Set(rref, v)
 	}
 
-	a.log("removing phrases from board")
+	a.log(ctx, "removing phrases from board")
 	ref := a.client.Collection("games").Doc(game.ID).Collection("boards").Doc(board.ID).Collection("phrases")
 	for {
 		// Get a batch of documents
-		iter := ref.Limit(100).Documents(a.ctx)
+		iter := ref.Limit(100).Documents(ctx)
 
 		for {
 			doc, err := iter.Next()
@@ -868,10 +1599,10 @@ func (a *Agent) DeleteBoard(board Board, game Game) error {
 				return fmt.Errorf("failed to clean phrases from firestore: %v", err)
 			}
 
-			a.log(fmt.Sprintf("removing phrase %s from board", doc.Ref.ID))
+			a.log(ctx, fmt.Sprintf("removing phrase %s from board", doc.Ref.ID))
 			batch.Delete(doc.Ref)
 		}
-		if _, err := batch.Commit(a.ctx); err != nil {
+		if _, err := batch.Commit(ctx); err != nil {
 			return fmt.Errorf("failed to clean messages from firestore: %v", err)
 		}
 		return nil
@@ -881,9 +1612,11 @@ func (a *Agent) DeleteBoard(board Board, game Game) error {
 }
 
 // SaveBoard persists a board to firestore
-func (a *Agent) SaveBoard(board Board) (Board, error) {
+func (a *Agent) SaveBoard(ctx context.Context, board Board) (_ Board, err error) {
+	ctx, done := a.observe(ctx, "SaveBoard", &err)
+	defer done()
 
-	a.log("Starting batch operation")
+	a.log(ctx, "Starting batch operation")
 	batch := a.client.Batch()
 	bref := a.client.Collection("games").Doc(board.Game).Collection("boards").Doc(board.ID)
 	batch.Set(bref, board)
@@ -895,8 +1628,9 @@ func (a *Agent) SaveBoard(board Board) (Board, error) {
 		ref := a.client.Collection("games").Doc(board.Game).Collection("boards").Doc(board.ID).Collection("phrases").Doc(v.ID)
 		batch.Set(ref, v)
 	}
+	a.countDeals(batch, []Board{board})
 
-	if _, err := batch.Commit(a.ctx); err != nil {
+	if _, err := batch.Commit(ctx); err != nil {
 		return board, fmt.Errorf("failed to add records to database: %v", err)
 	}
 
@@ -905,26 +1639,53 @@ func (a *Agent) SaveBoard(board Board) (Board, error) {
 }
 
 // SelectPhrase records clicks on the board and the game
-func (a *Agent) SelectPhrase(board Board, phrase Phrase, record Record) error {
+func (a *Agent) SelectPhrase(ctx context.Context, board Board, phrase Phrase, record Record) error {
+	return a.SelectPhrases(ctx, board, []Phrase{phrase}, []Record{record})
+}
 
-	a.log("Starting batch operation")
+// SelectPhrases records several clicks on the board and the game in one batch.
+func (a *Agent) SelectPhrases(ctx context.Context, board Board, phrases []Phrase, records []Record) (err error) {
+	ctx, done := a.observe(ctx, "SelectPhrases", &err)
+	defer done()
+
+	a.log(ctx, "Starting batch operation")
 	batch := a.client.Batch()
 
-	a.log("Updating phrase on board")
-	bref := a.client.Collection("games").Doc(board.Game).Collection("boards").Doc(board.ID).Collection("phrases").Doc(phrase.ID)
-	batch.Set(bref, phrase)
+	a.log(ctx, "Updating phrases on board")
+	counts := make(map[string]int)
+	for _, v := range phrases {
+		bref := a.client.Collection("games").Doc(board.Game).Collection("boards").Doc(board.ID).Collection("phrases").Doc(v.ID)
+		batch.Set(bref, v)
 
-	a.log("Updating game record")
-	gref := a.client.Collection("games").Doc(board.Game).Collection("records").Doc(record.Phrase.ID)
-	batch.Set(gref, record)
+		if v.Free {
+			continue
+		}
+		if v.Selected {
+			counts[v.ID]++
+		} else {
+			counts[v.ID]--
+		}
+	}
+	a.countPhrases(batch, "selections", counts)
+
+	a.log(ctx, "Updating game records")
+	for _, v := range records {
+		gref := a.client.Collection("games").Doc(board.Game).Collection("records").Doc(v.Phrase.ID)
+		batch.Set(gref, v)
+	}
 
-	a.log("Updating board to bingo")
+	a.log(ctx, "Updating board to bingo")
 	bingoref := a.client.Collection("games").Doc(board.Game).Collection("boards").Doc(board.ID)
-	update := map[string]interface{}{"bingodeclared": board.BingoDeclared}
+	update := map[string]interface{}{
+		"bingodeclared": board.BingoDeclared,
+		"rejected":      board.Rejected,
+		"activity":      board.Activity,
+		"suspicion":     board.Suspicion,
+	}
 	batch.Set(bingoref, update, firestore.MergeAll)
 
-	a.log("Committing Batch")
-	if _, err := batch.Commit(a.ctx); err != nil {
+	a.log(ctx, "Committing Batch")
+	if _, err := batch.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to update phrase: %v", err)
 	}
 
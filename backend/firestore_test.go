@@ -37,8 +37,7 @@ const FirestoreEmulatorHost = "FIRESTORE_EMULATOR_HOST"
 func agentTestSetup() {
 	agent := Agent{}
 	agent.ProjectID = projectID
-	agent.ctx = context.Background()
-	agent.client = newFirestoreTestClient(agent.ctx)
+	agent.client = newFirestoreTestClient(context.Background())
 	a = agent
 }
 
@@ -46,16 +45,16 @@ func TestEditAdmin(t *testing.T) {
 	player := Player{}
 	player.Email = "test@example.com"
 
-	if err := a.AddAdmin(player); err != nil {
+	if err := a.AddAdmin(ctx, player); err != nil {
 		t.Errorf("Agent.AddAdmin() err want %v got %s ", nil, err)
 	}
 
-	admins, err := a.GetAdmins()
+	admins, err := a.GetAdmins(ctx)
 	if err != nil {
 		t.Errorf("Agent.GetAdmins() err want %v got %s ", nil, err)
 	}
 
-	isAdmin, err := a.IsAdmin(player.Email)
+	isAdmin, err := a.IsAdmin(ctx, player.Email)
 	if err != nil {
 		t.Errorf("Agent.IsAdmin() err want %v got %s ", nil, err)
 	}
@@ -68,16 +67,16 @@ func TestEditAdmin(t *testing.T) {
 		t.Errorf("Agent.AddAdmin() said it added the admin, but clearly didn't")
 	}
 
-	if err := a.DeleteAdmin(player); err != nil {
+	if err := a.DeleteAdmin(ctx, player); err != nil {
 		t.Errorf("Agent.DeleteAdmin() err want %v got %s ", nil, err)
 	}
 
-	adminsPostDelete, err := a.GetAdmins()
+	adminsPostDelete, err := a.GetAdmins(ctx)
 	if err != nil {
 		t.Errorf("Agent.GetAdmins() err want %v got %s ", nil, err)
 	}
 
-	isAdminPostDelete, err := a.IsAdmin(player.Email)
+	isAdminPostDelete, err := a.IsAdmin(ctx, player.Email)
 	if err != nil {
 		t.Errorf("Agent.IsAdmin() err want %v got %s ", nil, err)
 	}
@@ -94,11 +93,11 @@ func TestEditAdmin(t *testing.T) {
 func TestEditMasterPhrases(t *testing.T) {
 	phrases := getTestPhrases()
 
-	if err := a.LoadPhrases(phrases); err != nil {
+	if err := a.LoadPhrases(ctx, phrases); err != nil {
 		t.Errorf("Agent.LoadPhrases() err want %v got %s ", nil, err)
 	}
 
-	phrasesFromData, err := a.GetPhrases()
+	phrasesFromData, err := a.GetPhrases(ctx)
 	if err != nil {
 		t.Errorf("Agent.GetPhrases() err want %v got %s ", nil, err)
 	}
@@ -109,11 +108,11 @@ func TestEditMasterPhrases(t *testing.T) {
 
 	updatePhrase := phrases[0]
 
-	if err := a.UpdateMasterPhrase(updatePhrase); err != nil {
+	if err := a.UpdateMasterPhrase(ctx, updatePhrase); err != nil {
 		t.Errorf("Agent.UpdateMasterPhrase() err want %v got %s ", nil, err)
 	}
 
-	phrasesFromDataPostUpdate, err := a.GetPhrases()
+	phrasesFromDataPostUpdate, err := a.GetPhrases(ctx)
 	if err != nil {
 		t.Errorf("Agent.GetPhrases() err want %v got %s ", nil, err)
 	}
@@ -132,7 +131,7 @@ func TestUpdateGame(t *testing.T) {
 		t.Errorf("initFirestoreBaseState() err want %v got %s ", nil, err)
 	}
 
-	gameFromData, err := a.GetGame(game.ID)
+	gameFromData, err := a.GetGame(ctx, game.ID)
 	if err != nil {
 		t.Errorf("Agent.GetGame() err want %v got %s ", nil, err)
 	}
@@ -142,11 +141,11 @@ func TestUpdateGame(t *testing.T) {
 	}
 
 	game.Active = false
-	if err := a.SaveGame(game); err != nil {
+	if err := a.SaveGame(ctx, game); err != nil {
 		t.Errorf("Agent.SaveGame() err want %v got %s ", nil, err)
 	}
 
-	gameFromDataPostEdit, err := a.GetGame(game.ID)
+	gameFromDataPostEdit, err := a.GetGame(ctx, game.ID)
 	if err != nil {
 		t.Errorf("Agent.GetGame() err want %v got %s ", nil, err)
 	}
@@ -155,7 +154,7 @@ func TestUpdateGame(t *testing.T) {
 		t.Errorf("Agent.GetGame() should return an unchanged game")
 	}
 
-	if err := a.DeleteGame(gameFromDataPostEdit); err != nil {
+	if err := a.DeleteGame(ctx, gameFromDataPostEdit); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 }
@@ -175,15 +174,15 @@ func TestUpdatePhrasesForGame(t *testing.T) {
 	board.UpdatePhrase(phrase)
 	game.UpdatePhrase(phrase)
 
-	if _, err := a.SaveBoard(board); err != nil {
+	if _, err := a.SaveBoard(ctx, board); err != nil {
 		t.Errorf("Agent.SaveBoard() err want %v got %s ", nil, err)
 	}
 
-	if err := a.UpdatePhrase(game, phrase); err != nil {
+	if err := a.UpdatePhrase(ctx, game, phrase); err != nil {
 		t.Errorf("Agent.UpdatePhrase() err want %v got %s ", nil, err)
 	}
 
-	gameFromDataPostEdit, err := a.GetGame(game.ID)
+	gameFromDataPostEdit, err := a.GetGame(ctx, game.ID)
 	if err != nil {
 		t.Errorf("Agent.GetGame() err want %v got %s ", nil, err)
 	}
@@ -196,7 +195,7 @@ func TestUpdatePhrasesForGame(t *testing.T) {
 		t.Errorf("Agent.UpdatePhrase() board should be the same as board in game")
 	}
 
-	if err := a.DeleteGame(game); err != nil {
+	if err := a.DeleteGame(ctx, game); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 }
@@ -211,11 +210,11 @@ func TestSelectPhrase(t *testing.T) {
 	phrase = board.Select(phrase)
 	record := game.Select(phrase, player)
 
-	if err := a.SelectPhrase(board, phrase, record); err != nil {
+	if err := a.SelectPhrase(ctx, board, phrase, record); err != nil {
 		t.Errorf("Agent.SelectPhrase() err want %v got %s ", nil, err)
 	}
 
-	gameFromDataPostEdit, err := a.GetGame(game.ID)
+	gameFromDataPostEdit, err := a.GetGame(ctx, game.ID)
 	if err != nil {
 		t.Errorf("Agent.GetGame() err want %v got %s ", nil, err)
 	}
@@ -224,7 +223,7 @@ func TestSelectPhrase(t *testing.T) {
 		t.Errorf("Agent.GetGame() should return an unchanged game")
 	}
 
-	boardFromDataPostEdit, err := a.GetBoard(board.ID, game.ID)
+	boardFromDataPostEdit, err := a.GetBoard(ctx, board.ID, game.ID)
 	if err != nil {
 		t.Errorf("Agent.GetBoard() err want %v got %s ", nil, err)
 	}
@@ -233,7 +232,54 @@ func TestSelectPhrase(t *testing.T) {
 		t.Errorf("Agent.GetBoard() should return an unchanged board.")
 	}
 
-	if err := a.DeleteGame(gameFromDataPostEdit); err != nil {
+	if err := a.DeleteGame(ctx, gameFromDataPostEdit); err != nil {
+		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
+	}
+}
+
+func TestGetPhraseStats(t *testing.T) {
+	before, err := a.GetPhraseStats(ctx)
+	if err != nil {
+		t.Errorf("Agent.GetPhraseStats() err want %v got %s ", nil, err)
+	}
+
+	game, board, player, phrase, err := initFirestoreBaseState()
+	if err != nil {
+		t.Errorf("initFirestoreBaseState() err want %v got %s ", nil, err)
+	}
+	phrase.Selected = true
+
+	phrase = board.Select(phrase)
+	record := game.Select(phrase, player)
+
+	if err := a.SelectPhrase(ctx, board, phrase, record); err != nil {
+		t.Errorf("Agent.SelectPhrase() err want %v got %s ", nil, err)
+	}
+
+	after, err := a.GetPhraseStats(ctx)
+	if err != nil {
+		t.Errorf("Agent.GetPhraseStats() err want %v got %s ", nil, err)
+	}
+
+	find := func(ps PhraseStats) PhraseStat {
+		for _, v := range ps {
+			if v.Phrase.ID == phrase.ID {
+				return v
+			}
+		}
+		return PhraseStat{}
+	}
+
+	got := find(after)
+	want := find(before)
+	want.Boards++
+	want.Selections++
+
+	if got.Boards != want.Boards || got.Selections != want.Selections {
+		t.Errorf("Agent.GetPhraseStats() want %d/%d got %d/%d", want.Selections, want.Boards, got.Selections, got.Boards)
+	}
+
+	if err := a.DeleteGame(ctx, game); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 }
@@ -245,26 +291,26 @@ func TestGamesEditing(t *testing.T) {
 	player2.Email = "test2@example.com"
 	phrases := getTestPhrases()
 
-	if err := a.LoadPhrases(phrases); err != nil {
+	if err := a.LoadPhrases(ctx, phrases); err != nil {
 		t.Errorf("Agent.LoadPhrases() err want %v got %s ", nil, err)
 	}
 
-	game1, err := a.NewGame("test game1", player)
+	game1, err := a.NewGame(ctx, "test game1", player, GameOptions{Free: DefaultFreeSquares()})
 	if err != nil {
 		t.Errorf("Agent.NewGame() err want %v got %s ", nil, err)
 	}
 
-	game2, err := a.NewGame("test game2", player)
+	game2, err := a.NewGame(ctx, "test game2", player, GameOptions{Free: DefaultFreeSquares()})
 	if err != nil {
 		t.Errorf("Agent.NewGame() err want %v got %s ", nil, err)
 	}
 
-	game3, err := a.NewGame("test game3", player2)
+	game3, err := a.NewGame(ctx, "test game3", player2, GameOptions{Free: DefaultFreeSquares()})
 	if err != nil {
 		t.Errorf("Agent.NewGame() err want %v got %s ", nil, err)
 	}
 
-	games, err := a.GetGames(10, time.Now())
+	games, err := a.GetGames(ctx, 10, time.Now())
 	if err != nil {
 		t.Errorf("Agent.GetGames() err want %v got %s ", nil, err)
 	}
@@ -273,7 +319,7 @@ func TestGamesEditing(t *testing.T) {
 		t.Errorf("Agent.GetGames() count want %d got %d ", 3, len(games))
 	}
 
-	games2, err := a.GetGamesForKey(player2.Email)
+	games2, err := a.GetGamesForKey(ctx, player2.Email)
 	if err != nil {
 		t.Errorf("Agent.GetGames() err want %v got %s ", nil, err)
 	}
@@ -282,13 +328,13 @@ func TestGamesEditing(t *testing.T) {
 		t.Errorf("Agent.GetGames() count want %d got %d ", 1, len(games))
 	}
 
-	if err := a.DeleteGame(game1); err != nil {
+	if err := a.DeleteGame(ctx, game1); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
-	if err := a.DeleteGame(game2); err != nil {
+	if err := a.DeleteGame(ctx, game2); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
-	if err := a.DeleteGame(game3); err != nil {
+	if err := a.DeleteGame(ctx, game3); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 }
@@ -306,19 +352,19 @@ func TestBoardDelete(t *testing.T) {
 
 	board2 := game.NewBoard(player2)
 
-	if _, err := a.SaveBoard(board2); err != nil {
+	if _, err := a.SaveBoard(ctx, board2); err != nil {
 		t.Errorf("Agent.SaveBoard() err want %v got %s ", nil, err)
 	}
 
-	if err := a.SaveGame(game); err != nil {
+	if err := a.SaveGame(ctx, game); err != nil {
 		t.Errorf("Agent.SaveGame() err want %v got %s ", nil, err)
 	}
 
-	if err := a.DeleteBoard(board, game); err != nil {
+	if err := a.DeleteBoard(ctx, board, game); err != nil {
 		t.Errorf("Agent.DeleteBoard() err want %v got %s ", nil, err)
 	}
 
-	gameFromDataPostEdit, err := a.GetGame(game.ID)
+	gameFromDataPostEdit, err := a.GetGame(ctx, game.ID)
 	if err != nil {
 		t.Errorf("Agent.GetGame() err want %v got %s ", nil, err)
 	}
@@ -327,7 +373,7 @@ func TestBoardDelete(t *testing.T) {
 		t.Errorf("Agent.DeleteBoard() count want %d got %d ", 1, len(gameFromDataPostEdit.Boards))
 	}
 
-	if err := a.DeleteGame(gameFromDataPostEdit); err != nil {
+	if err := a.DeleteGame(ctx, gameFromDataPostEdit); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 
@@ -339,7 +385,7 @@ func TestGetGameForPlayer(t *testing.T) {
 		t.Errorf("initFirestoreBaseState() err want %v got %s ", nil, err)
 	}
 
-	boardForEmail, err := a.GetBoardForPlayer(game.ID, player)
+	boardForEmail, err := a.GetBoardForPlayer(ctx, game.ID, player)
 	if err != nil {
 		t.Errorf("Agent.GetBoard() err want %v got %s ", nil, err)
 	}
@@ -363,11 +409,11 @@ func TestMessageAcknowledge(t *testing.T) {
 	m1.SetAudience(player.Email)
 	messages = append(messages, m1)
 
-	if err := a.AddMessagesToGame(game, messages); err != nil {
+	if err := a.AddMessagesToGame(ctx, game, messages); err != nil {
 		t.Errorf("Agent.AddMessagesToGame() err want %v got %s ", nil, err)
 	}
 
-	if err := a.AcknowledgeMessage(game, m1); err != nil {
+	if err := a.AcknowledgeMessage(ctx, game, m1); err != nil {
 		t.Errorf("Agent.AcknowledgeMessage() err want %v got %s ", nil, err)
 	}
 
@@ -379,22 +425,22 @@ func initFirestoreBaseState() (Game, Board, Player, Phrase, error) {
 	phrases := getTestPhrases()
 	phrase := phrases[0]
 
-	if err := a.LoadPhrases(phrases); err != nil {
+	if err := a.LoadPhrases(ctx, phrases); err != nil {
 		return Game{}, Board{}, player, phrase, err
 	}
 
-	game, err := a.NewGame("test game", player)
+	game, err := a.NewGame(ctx, "test game", player, GameOptions{Free: DefaultFreeSquares()})
 	if err != nil {
 		return game, Board{}, player, phrase, err
 	}
 
 	board := game.NewBoard(player)
 
-	if _, err := a.SaveBoard(board); err != nil {
+	if _, err := a.SaveBoard(ctx, board); err != nil {
 		return game, board, player, phrase, err
 	}
 
-	if err := a.SaveGame(game); err != nil {
+	if err := a.SaveGame(ctx, game); err != nil {
 		return game, board, player, phrase, err
 	}
 	return game, board, player, phrase, nil
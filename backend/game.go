@@ -15,29 +15,36 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"html"
+	"math/rand"
 	"strings"
 	"time"
 )
 
-func getBoardForPlayer(player Player, game Game) (Board, error) {
+func getBoardForPlayer(ctx context.Context, player Player, game Game) (Board, error) {
 	var err error
+	ctx = withLogFields(ctx, game.ID, "")
+	if game.Lobby {
+		return Board{}, fmt.Errorf("game id(%s) is still in the lobby", game.ID)
+	}
 	b := Board{}
 	messages := []Message{}
-	weblog("Trying Cache")
-	b, err = cache.GetBoardForPlayer(game.ID, player.Email)
+	weblog(ctx, "Trying Cache")
+	b, err = cache.GetBoardForPlayer(ctx, game.ID, player.Email)
 	if err != nil {
 		if err == ErrCacheMiss {
-			weblog("Cache Empty trying DB")
-			b, err = a.GetBoardForPlayer(game.ID, player)
+			weblog(ctx, "Cache Empty trying DB")
+			b, err = a.GetBoardForPlayer(ctx, game.ID, player)
 			if err != nil {
 				return b, fmt.Errorf("error getting board for player: %v", err)
 			}
 		}
-		if err := cache.DeleteGamesForKey([]string{player.Email}); err != nil {
+		if err := cache.DeleteGamesForKey(ctx, []string{player.Email}); err != nil {
 			return b, fmt.Errorf("error clearing game cache for player: %v", err)
 		}
-		if err := cache.SaveBoard(b); err != nil {
+		if err := cache.SaveBoard(ctx, b); err != nil {
 			return b, fmt.Errorf("error caching board for player: %v", err)
 		}
 	}
@@ -47,16 +54,17 @@ func getBoardForPlayer(player Player, game Game) (Board, error) {
 
 	if b.ID == "" {
 		b = game.NewBoard(player)
-		b, err = a.SaveBoard(b)
+		b, err = a.SaveBoard(ctx, b)
 		if err != nil {
 			return b, fmt.Errorf("error saving board for player: %v", err)
 		}
-		if err := cache.SaveBoard(b); err != nil {
+		metrics.boardsCreated.Inc()
+		if err := cache.SaveBoard(ctx, b); err != nil {
 			return b, fmt.Errorf("error caching board for player: %v", err)
 		}
 		game.Boards[b.ID] = b
 
-		if err := cache.SaveGame(game); err != nil {
+		if err := cache.SaveGame(ctx, game); err != nil {
 			return b, fmt.Errorf("error caching game for player: %v", err)
 		}
 		m.SetText("<strong>%s</strong> got a board and joined the game.", b.Player.Name)
@@ -66,30 +74,33 @@ func getBoardForPlayer(player Player, game Game) (Board, error) {
 
 	messages = append(messages, m)
 
-	bingo := b.Bingo()
+	bingo := b.BingoDeclared
+	if !game.Claims {
+		bingo = b.Bingo()
+	}
 	if bingo {
 		msg := generateBingoMessages(b, game, false)
 		messages = append(messages, msg...)
 	}
 
-	if err := a.AddMessagesToGame(game, messages); err != nil {
+	if err := a.AddMessagesToGame(ctx, game, messages); err != nil {
 		return b, fmt.Errorf("could not send message to notify player of bingo: %s", err)
 	}
 
 	return b, nil
 }
 
-func getBoard(bid string, gid string) (Board, error) {
+func getBoard(ctx context.Context, bid string, gid string) (Board, error) {
 
-	b, err := cache.GetBoard(bid)
+	b, err := cache.GetBoard(ctx, bid)
 	if err != nil {
 		if err == ErrCacheMiss {
-			b, err = a.GetBoard(bid, gid)
+			b, err = a.GetBoard(ctx, bid, gid)
 			if err != nil {
 				return b, fmt.Errorf("error getting board: %v", err)
 			}
 		}
-		if err := cache.SaveBoard(b); err != nil {
+		if err := cache.SaveBoard(ctx, b); err != nil {
 			return b, fmt.Errorf("error caching board : %v", err)
 		}
 	}
@@ -97,27 +108,27 @@ func getBoard(bid string, gid string) (Board, error) {
 	return b, nil
 }
 
-func deleteBoard(bid, gid string) error {
-	b, err := getBoard(bid, gid)
+func deleteBoard(ctx context.Context, bid, gid string) error {
+	b, err := getBoard(ctx, bid, gid)
 	if err != nil {
 		return fmt.Errorf("could not retrieve board from firestore: %s", err)
 	}
 
-	game, err := getGame(b.Game)
+	game, err := getGame(ctx, b.Game)
 	if err != nil {
 		return fmt.Errorf("failed to get active game to delete board: %v", err)
 	}
 
 	game.DeleteBoard(b)
 
-	if err := a.DeleteBoard(b, game); err != nil {
+	if err := a.DeleteBoard(ctx, b, game); err != nil {
 		return fmt.Errorf("could not delete board from firestore: %s", err)
 	}
-	if err := cache.DeleteBoard(b); err != nil {
+	if err := cache.DeleteBoard(ctx, b); err != nil {
 		return fmt.Errorf("could not delete board from cache: %s", err)
 	}
 
-	if err := cache.SaveGame(game); err != nil {
+	if err := cache.SaveGame(ctx, game); err != nil {
 		return fmt.Errorf("could not reset game in cache: %s", err)
 	}
 
@@ -128,7 +139,7 @@ func deleteBoard(bid, gid string) error {
 	m.Operation = "reset"
 	messages = append(messages, m)
 
-	if err := a.AddMessagesToGame(game, messages); err != nil {
+	if err := a.AddMessagesToGame(ctx, game, messages); err != nil {
 		return fmt.Errorf("could not send message to delete board: %s", err)
 	}
 
@@ -148,7 +159,7 @@ func generateBingoMessages(board Board, game Game, first bool) []Message {
 	}
 
 	m1 := Message{}
-	m1.SetText(bingoMsg)
+	m1.SetText("%s", bingoMsg)
 	m1.SetAudience(board.Player.Email)
 	if first {
 		m1.SetAudience("all", board.Player.Email)
@@ -157,11 +168,19 @@ func generateBingoMessages(board Board, game Game, first bool) []Message {
 	m1.Bingo = true
 	messages = append(messages, m1)
 
+	if s := game.Suspicion(board); s.Score > 0 {
+		ms := Message{}
+		ms.SetText("<strong>%s</strong> has a %s.", board.Player.Name, s)
+		ms.SetAudience("admin")
+		ms.Bingo = true
+		messages = append(messages, ms)
+	}
+
 	reports := game.CheckBoard(board)
 	if reports.IsDubious() {
 		board.log("REPORTED BINGO IS DUBIOUS")
 		m2 := Message{}
-		m2.SetText(dubiousMsg)
+		m2.SetText("%s", dubiousMsg)
 		m2.SetAudience("admin", board.Player.Email)
 		m2.Bingo = true
 		messages = append(messages, m2)
@@ -170,11 +189,11 @@ func generateBingoMessages(board Board, game Game, first bool) []Message {
 			mr := Message{}
 
 			if v.Percent > .5 {
-				mr.SetText("<strong>%s</strong> was selected by %d of the other %d players", v.Phrase.Text, v.Count-1, v.Total-1)
+				mr.SetText("<strong>%s</strong> was selected by %d of the other %d players", html.EscapeString(v.Phrase.Text), v.Count-1, v.Total-1)
 			} else {
-				mr.SetText("<strong>%s</strong> was selected by only <strong>%d of the other %d players</strong>", v.Phrase.Text, v.Count-1, v.Total-1)
+				mr.SetText("<strong>%s</strong> was selected by only <strong>%d of the other %d players</strong>", html.EscapeString(v.Phrase.Text), v.Count-1, v.Total-1)
 				if v.Count == 1 {
-					mr.SetText("<strong>%s</strong> was selected by <strong>none</strong> of the other %d players", v.Phrase.Text, v.Total-1)
+					mr.SetText("<strong>%s</strong> was selected by <strong>none</strong> of the other %d players", html.EscapeString(v.Phrase.Text), v.Total-1)
 				}
 			}
 
@@ -186,28 +205,28 @@ func generateBingoMessages(board Board, game Game, first bool) []Message {
 	return messages
 }
 
-func getGamesForKey(key string, limit int, token time.Time) (Games, error) {
+func getGamesForKey(ctx context.Context, key string, limit int, token time.Time) (Games, error) {
 	g := Games{}
 	var err error
 
-	g, err = cache.GetGamesForKey(key)
+	g, err = cache.GetGamesForKey(ctx, key)
 	if err != nil {
 		if err == ErrCacheMiss {
 
 			if strings.Contains(key, "admin-list") {
-				g, err = a.GetGames(limit, token)
+				g, err = a.GetGames(ctx, limit, token)
 				if err != nil {
 					return g, fmt.Errorf("error getting games: %v", err)
 				}
 			} else {
-				g, err = a.GetGamesForKey(key)
+				g, err = a.GetGamesForKey(ctx, key)
 				if err != nil {
 					return g, fmt.Errorf("error getting games: %v", err)
 				}
 			}
 
 		}
-		if err := cache.SaveGamesForKey(key, g); err != nil {
+		if err := cache.SaveGamesForKey(ctx, key, g); err != nil {
 			return g, fmt.Errorf("error caching games : %v", err)
 		}
 	}
@@ -215,44 +234,44 @@ func getGamesForKey(key string, limit int, token time.Time) (Games, error) {
 	return g, nil
 }
 
-func getNewGame(name string, player Player) (Game, error) {
+func getNewGame(ctx context.Context, name string, player Player, opts GameOptions) (Game, error) {
 
-	game, err := a.NewGame(name, player)
+	game, err := a.NewGame(ctx, name, player, opts)
 	if err != nil {
 		return game, fmt.Errorf("failed to get new game: %v", err)
 	}
-	if err := cache.DeleteGamesForKey([]string{player.Email, "admin-list"}); err != nil {
+	if err := cache.DeleteGamesForKey(ctx, []string{player.Email, "admin-list"}); err != nil {
 		return game, fmt.Errorf("failed to clear cache: %v", err)
 	}
-	if err := cache.SaveGame(game); err != nil {
+	if err := cache.SaveGame(ctx, game); err != nil {
 		return game, fmt.Errorf("error caching game : %v", err)
 	}
 
 	return game, nil
 }
 
-func getGame(gid string) (Game, error) {
-	game, err := cache.GetGame(gid)
+func getGame(ctx context.Context, gid string) (Game, error) {
+	game, err := cache.GetGame(ctx, gid)
 	if err != nil {
 		if err == ErrCacheMiss {
-			game, err = a.GetGame(gid)
+			game, err = a.GetGame(ctx, gid)
 			if err != nil {
 				return Game{}, fmt.Errorf("error getting game: %v", err)
 			}
 		}
-		if err := cache.SaveGame(game); err != nil {
+		if err := cache.SaveGame(ctx, game); err != nil {
 			return Game{}, fmt.Errorf("error caching game : %v", err)
 		}
 	}
 
-	if len(game.Boards) == 0 {
-		weblog("WARNING a game was retrieved without its boards - fixing")
+	if len(game.Boards) == 0 && !game.Lobby {
+		logger.Warning(ctx, "webserver", "a game was retrieved without its boards - fixing")
 
-		game, err = a.loadGameWithBoards(game)
+		game, err = a.loadGameWithBoards(ctx, game)
 		if err != nil {
 			return Game{}, fmt.Errorf("error loading game : %v", err)
 		}
-		if err := cache.SaveGame(game); err != nil {
+		if err := cache.SaveGame(ctx, game); err != nil {
 			return Game{}, fmt.Errorf("error caching game : %v", err)
 		}
 
@@ -261,23 +280,31 @@ func getGame(gid string) (Game, error) {
 	return game, nil
 }
 
-func deactivateGame(gid string) error {
-	game, err := cache.GetGame(gid)
+func deactivateGame(ctx context.Context, gid string) error {
+	return setGameActive(ctx, gid, false)
+}
+
+func activateGame(ctx context.Context, gid string) error {
+	return setGameActive(ctx, gid, true)
+}
+
+func setGameActive(ctx context.Context, gid string, active bool) error {
+	game, err := cache.GetGame(ctx, gid)
 	if err != nil {
 		if err == ErrCacheMiss {
-			game, err = a.GetGame(gid)
+			game, err = a.GetGame(ctx, gid)
 			if err != nil {
 				return fmt.Errorf("error getting game: %v", err)
 			}
 		}
 	}
-	game.Active = false
+	game.Active = active
 
-	if err := cache.SaveGame(game); err != nil {
+	if err := cache.SaveGame(ctx, game); err != nil {
 		return fmt.Errorf("error caching game : %v", err)
 	}
 
-	if err := a.SaveGame(game); err != nil {
+	if err := a.SaveGame(ctx, game); err != nil {
 		return fmt.Errorf("error saving game to firestore : %v", err)
 	}
 
@@ -287,101 +314,581 @@ func deactivateGame(gid string) error {
 	}
 
 	msg := fmt.Sprintf("Deleting games for caches: %+v", keys)
-	cache.log(msg)
-	if err := cache.DeleteGamesForKey(keys); err != nil {
+	cache.log(ctx, msg)
+	if err := cache.DeleteGamesForKey(ctx, keys); err != nil {
 		return fmt.Errorf("error caching game : %v", err)
 	}
 
 	return nil
 }
 
-func recordSelect(bid, gid, pid string, selected bool) error {
-	p := Phrase{}
-	p.ID = pid
-	p.Selected = selected
+// generateOneAwayMessages tells a player when a selection has left their
+// board one square away from a line, naming the phrases they still need, and
+// tells admins how many boards are that close if the game wants it.
+func generateOneAwayMessages(board Board, game Game, before []Phrase) []Message {
+	messages := []Message{}
+
+	seen := make(map[string]bool)
+	for _, v := range before {
+		seen[v.ID] = true
+	}
+
+	missing := []string{}
+	for _, v := range board.OneAway() {
+		if !seen[v.ID] {
+			missing = append(missing, fmt.Sprintf("<em>%s</em>", html.EscapeString(v.Text)))
+		}
+	}
+
+	if len(missing) == 0 {
+		return messages
+	}
+
+	m := Message{}
+	m.SetText("<strong>You</strong> are one away from <em><strong>BINGO</strong></em>, you just need %s.", strings.Join(missing, " or "))
+	m.SetAudience(board.Player.Email)
+	messages = append(messages, m)
+
+	if game.OneAway {
+		count := game.CountOneAway()
+		ma := Message{}
+		ma.SetText("<strong>%d</strong> boards are one square away from <em><strong>BINGO</strong></em>.", count)
+		if count == 1 {
+			ma.SetText("<strong>1</strong> board is one square away from <em><strong>BINGO</strong></em>.")
+		}
+		ma.SetAudience("admin")
+		messages = append(messages, ma)
+	}
+
+	return messages
+}
+
+func recordSelect(ctx context.Context, bid, gid, pid string, selected bool) error {
+	_, err := setSelection(ctx, bid, gid, pid, selected, "")
+	return err
+}
+
+// setSelection sets whether a phrase is selected on a board. Setting a phrase
+// to the state it's already in, or repeating a request with the same
+// idempotency key, changes nothing. Either way the current board is returned.
+func setSelection(ctx context.Context, bid, gid, pid string, selected bool, key string) (Board, error) {
+	b, err := getBoard(ctx, bid, gid)
+	if err != nil {
+		return b, fmt.Errorf("could not get board id(%s): %s", bid, err)
+	}
+
+	return selectPhrases(ctx, b, []Selection{{Phrase: pid, Selected: selected, Key: key}})
+}
+
+// selectPhrases applies several selections to a board at once. Selections
+// that wouldn't change a square, or that repeat an idempotency key, are
+// skipped. The rest are written together, bingo is checked once they are all
+// in, and admins get one message about all of them. Each phrase can only be
+// in the batch once. The updated board is returned.
+func selectPhrases(ctx context.Context, b Board, changes []Selection) (Board, error) {
+	seen := make(map[string]bool)
+	for _, v := range changes {
+		if _, ok := b.Phrases[v.Phrase]; !ok {
+			return b, ValidationError{"p": fmt.Sprintf("phrase %s is not on this board", v.Phrase)}
+		}
+		if seen[v.Phrase] {
+			return b, ValidationError{"p": fmt.Sprintf("phrase %s is selected more than once", v.Phrase)}
+		}
+		seen[v.Phrase] = true
+	}
+
+	g, err := getGame(ctx, b.Game)
+	if err != nil {
+		return b, fmt.Errorf("could not get game id(%s): %s", b.Game, err)
+	}
+
+	now := time.Now()
+	for i := range changes {
+		changes[i].Time = now
+	}
+
+	return applySelections(ctx, b, g, changes)
+}
+
+// syncSelections merges the selections a client queued while offline into its
+// board. Those that conflict with changes made in the meantime are returned
+// rather than made.
+func syncSelections(ctx context.Context, bid, gid string, queued []Selection) (SyncResult, error) {
+	result := SyncResult{}
+
+	b, err := getBoard(ctx, bid, gid)
+	if err != nil {
+		return result, fmt.Errorf("could not get board id(%s): %s", bid, err)
+	}
+
+	g, err := getGame(ctx, b.Game)
+	if err != nil {
+		return result, fmt.Errorf("could not get game id(%s): %s", b.Game, err)
+	}
+
+	accepted, conflicts := g.Merge(b, queued, time.Now())
+
+	b, err = applySelections(ctx, b, g, accepted)
+	if err != nil {
+		return result, err
+	}
+
+	result.Board = b
+	result.Applied = accepted
+	result.Conflicts = conflicts
+	return result, nil
+}
+
+// applySelections makes the selections on a board, at the times they carry,
+// and records the result. A board that gets bingo wins at the time of the
+// latest selection made, so a batch synced after reconnecting wins when the
+// player finished it rather than when it arrived.
+func applySelections(ctx context.Context, b Board, g Game, changes []Selection) (Board, error) {
+	ctx = withLogFields(ctx, g.ID, b.ID)
+	if !g.Active {
+		return b, ValidationError{"g": "game is no longer active"}
+	}
+
+	messages := []Message{}
+	before := b.OneAway()
+	wasBingo := b.BingoDeclared
+
+	phrases := []Phrase{}
+	records := []Record{}
+	anySelected := false
+	var bingoAt time.Time
+	for _, v := range changes {
+		current := b.Phrases[v.Phrase]
+		if (v.Key != "" && b.HasKey(v.Key)) || current.Selected == v.Selected || current.Free {
+			continue
+		}
+
+		p := b.Select(Phrase{ID: v.Phrase, Selected: v.Selected})
+		b.AddActivity(p, v.Key, v.Time)
+		phrases = append(phrases, p)
+		records = append(records, g.Select(p, b.Player))
+		anySelected = anySelected || p.Selected
+		if v.Time.After(bingoAt) {
+			bingoAt = v.Time
+		}
+	}
+
+	if len(phrases) == 0 {
+		return b, nil
+	}
+
+	b.Suspicion = g.Suspicion(b).Score
+	bingo := b.Bingo()
+	if g.Claims {
+		// A claimed bingo stands while its line does, but a new line waits
+		// for the player to claim it.
+		b.BingoDeclared = wasBingo && bingo
+		bingo = false
+	}
+	g.Boards[b.ID] = b
+
+	// A dubious bingo is announced, and flagged to admins, but doesn't win
+	// until one of them confirms it.
+	dubious := bingo && g.CheckBoard(b).IsDubious()
+	won := bingo && !dubious && g.AddWinner(b, bingoAt)
+
+	scored := []Board{}
+	if g.Scoring {
+		scored = g.UpdatePoints()
+		b = g.Boards[b.ID]
+	}
+
+	if err := a.SelectPhrases(ctx, b, phrases, records); err != nil {
+		return b, fmt.Errorf("record click to firestore: %s", err)
+	}
+	metrics.selections.Add(float64(len(phrases)))
+	if bingo && !wasBingo {
+		metrics.bingos.Inc()
+	}
+
+	messages = append(messages, generateSelectionMessage(b, phrases))
+
+	if bingo {
+		msg := generateBingoMessages(b, g, true)
+		messages = append(messages, msg...)
+	} else if anySelected {
+		messages = append(messages, generateOneAwayMessages(b, g, before)...)
+	}
+
+	if err := saveBingoResult(ctx, g, b, won, scored, messages); err != nil {
+		return b, err
+	}
+
+	return b, nil
+}
+
+// generateSelectionMessage tells admins, and the player, what was selected
+// and unselected on a board, in one message however many squares changed.
+func generateSelectionMessage(board Board, phrases []Phrase) Message {
+	selected := []string{}
+	unselected := []string{}
+	for _, v := range phrases {
+		text := fmt.Sprintf("<em>%s</em>", html.EscapeString(v.Text))
+		if v.Selected {
+			selected = append(selected, text)
+			continue
+		}
+		unselected = append(unselected, text)
+	}
+
+	changes := []string{}
+	if len(selected) > 0 {
+		changes = append(changes, "selected "+joinList(selected))
+	}
+	if len(unselected) > 0 {
+		changes = append(changes, "unselected "+joinList(unselected))
+	}
+
+	m := Message{}
+	m.SetText("<strong>%s</strong> %s on their board.", board.Player.Name, strings.Join(changes, " and "))
+	m.SetAudience("admin", board.Player.Email)
+	return m
+}
+
+// joinList joins items the way a sentence lists them: "a, b and c".
+func joinList(items []string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+// claimBingo checks a player's claim of bingo against their board. Valid
+// claims are announced like any other bingo, false ones are recorded against
+// the board and cost it the game's penalty.
+func claimBingo(ctx context.Context, bid, gid string) error {
 	messages := []Message{}
 
-	b, err := getBoard(bid, gid)
+	b, err := getBoard(ctx, bid, gid)
 	if err != nil {
 		return fmt.Errorf("could not get board id(%s): %s", bid, err)
 	}
 
-	g, err := getGame(b.Game)
+	g, err := getGame(ctx, b.Game)
 	if err != nil {
 		return fmt.Errorf("could not get game id(%s): %s", b.Game, err)
 	}
 
-	p = b.Select(p)
-	r := g.Select(p, b.Player)
+	if !g.Claims {
+		return ValidationError{"g": "game does not take bingo claims"}
+	}
+
+	if !g.Active {
+		return ValidationError{"g": "game is no longer active"}
+	}
+
+	wasBingo := b.BingoDeclared
 	bingo := b.Bingo()
 
-	if err := a.SelectPhrase(b, p, r); err != nil {
-		return fmt.Errorf("record click to firestore: %s", err)
+	won := false
+	if bingo {
+		// A dubious claim is still a real line, so it's flagged to admins
+		// rather than counted as a false claim.
+		dubious := g.CheckBoard(b).IsDubious()
+		g.Boards[b.ID] = b
+		won = !dubious && g.AddWinner(b, time.Now())
+		messages = append(messages, generateBingoMessages(b, g, !wasBingo)...)
+	} else {
+		b.Rejected++
+		g.Boards[b.ID] = b
+		messages = append(messages, generateFalseClaimMessages(b, g)...)
 	}
 
-	if err := cache.SaveGame(g); err != nil {
-		return fmt.Errorf("could not cache game: %s", err)
+	changed := []Board{}
+	if g.Scoring {
+		changed = g.UpdatePoints()
+		b = g.Boards[b.ID]
 	}
 
-	if err := cache.SaveBoard(b); err != nil {
-		return fmt.Errorf("could not cache game: %s", err)
+	// The claim changed the board, so it's saved along with any others
+	// whose score changed.
+	claimed := false
+	for i, v := range changed {
+		if v.ID == b.ID {
+			changed[i] = b
+			claimed = true
+		}
+	}
+	if !claimed {
+		changed = append(changed, b)
+	}
+
+	if bingo && !wasBingo {
+		metrics.bingos.Inc()
+	}
+
+	return saveBingoResult(ctx, g, b, won, changed, messages)
+}
+
+// confirmBingo makes a board whose bingo was flagged as dubious a winner, once
+// an admin has looked at it. It wins at the time of its latest selection, as
+// it would have if it hadn't been flagged.
+func confirmBingo(ctx context.Context, bid, gid string) error {
+	b, err := getBoard(ctx, bid, gid)
+	if err != nil {
+		return fmt.Errorf("could not get board id(%s): %s", bid, err)
+	}
+
+	g, err := getGame(ctx, b.Game)
+	if err != nil {
+		return fmt.Errorf("could not get game id(%s): %s", b.Game, err)
+	}
+
+	if !g.Active {
+		return ValidationError{"g": "game is no longer active"}
+	}
+
+	if !b.BingoDeclared {
+		return ValidationError{"b": "board does not have bingo"}
+	}
+
+	bingoAt := time.Now()
+	if len(b.Activity) > 0 {
+		bingoAt = b.Activity[0].Time
+		for _, v := range b.Activity {
+			if v.Time.After(bingoAt) {
+				bingoAt = v.Time
+			}
+		}
 	}
 
-	indicator := "unselected"
-	if p.Selected {
-		indicator = "selected"
+	if !g.AddWinner(b, bingoAt) {
+		return nil
 	}
 
 	m := Message{}
-	m.SetText("<strong>%s</strong> %s <em>%s</em> on their board.", b.Player.Name, indicator, p.Text)
-	m.SetAudience("admin", b.Player.Email)
-	messages = append(messages, m)
+	m.SetText("<strong>%s</strong> had their <em><strong>BINGO</strong></em> confirmed.", b.Player.Name)
+	m.SetAudience("all", b.Player.Email)
+	m.Bingo = true
 
-	if bingo {
-		msg := generateBingoMessages(b, g, true)
-		messages = append(messages, msg...)
+	return saveBingoResult(ctx, g, b, true, []Board{}, []Message{m})
+}
+
+// saveBingoResult stores what came of a select or claim on a board: changed
+// scores, any new winner and the updated game and board. It sends the
+// messages and ends the game if it's over.
+func saveBingoResult(ctx context.Context, g Game, b Board, won bool, scored []Board, messages []Message) error {
+	if err := a.SaveBoardStatus(ctx, g, scored); err != nil {
+		return fmt.Errorf("record points to firestore: %s", err)
+	}
+
+	for _, v := range scored {
+		if err := cache.SaveBoard(ctx, v); err != nil {
+			return fmt.Errorf("could not cache board: %s", err)
+		}
+	}
+
+	if won {
+		winners, err := a.SaveWinner(ctx, g, b)
+		if err != nil {
+			return fmt.Errorf("record winner to firestore: %s", err)
+		}
+		g.Winners = winners
+	}
+
+	if err := cache.SaveGame(ctx, g); err != nil {
+		return fmt.Errorf("could not cache game: %s", err)
+	}
+
+	if err := cache.SaveBoard(ctx, b); err != nil {
+		return fmt.Errorf("could not cache game: %s", err)
+	}
+
+	// Only the winner that ends the game announces the results, not the ones
+	// that come after it.
+	over := false
+	if won {
+		before := g
+		before.Winners = g.Winners[:len(g.Winners)-1]
+		over = g.IsOver() && !before.IsOver()
+	}
+	if over {
+		messages = append(messages, generateResultsMessage(g))
 	}
 
-	if err := a.AddMessagesToGame(g, messages); err != nil {
+	if err := a.AddMessagesToGame(ctx, g, messages); err != nil {
 		return fmt.Errorf("could not send message announce bingo on select: %s", err)
 	}
 
+	if over {
+		if err := deactivateGame(ctx, g.ID); err != nil {
+			return fmt.Errorf("could not end game id(%s): %s", g.ID, err)
+		}
+	}
+
 	return nil
 }
 
-func updateMasterPhrase(phrase Phrase) error {
+// generateResultsMessage announces the end of a game to everyone, with the
+// winners in the order they got bingo.
+func generateResultsMessage(game Game) Message {
+	places := []string{}
+	for i, v := range game.Winners {
+		places = append(places, fmt.Sprintf("%d. <strong>%s</strong>", i+1, html.EscapeString(v.Player.Name)))
+	}
+
+	m := Message{}
+	m.SetText("The game is over! %s", strings.Join(places, ", "))
+	m.SetAudience("all")
+	return m
+}
+
+func updateMasterPhrase(ctx context.Context, phrase Phrase) error {
+	phrases, err := a.GetPhrases(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting master phrases : %v", err)
+	}
+
+	phrase, err = validatePhrase(phrase, phrases)
+	if err != nil {
+		return err
+	}
 
-	if err := a.UpdateMasterPhrase(phrase); err != nil {
+	if err := a.UpdateMasterPhrase(ctx, phrase); err != nil {
 		return fmt.Errorf("error updating master phrase : %v", err)
 	}
 
 	return nil
 }
 
-func updateGamePhrases(gid string, phrase Phrase) error {
+func updateGamePhrases(ctx context.Context, gid string, phrase Phrase) error {
 	messages := []Message{}
 	m := Message{}
 	m.SetText("A square has been changed and reset for all players. ")
 	m.SetAudience("all")
 	messages = append(messages, m)
 
-	g, err := getGame(gid)
+	g, err := getGame(ctx, gid)
 	if err != nil {
 		return fmt.Errorf("could not get game id(%s): %s", g.ID, err)
 	}
 
+	i, _ := g.FindRecord(phrase)
+	if i == -1 {
+		return ValidationError{"p": fmt.Sprintf("phrase %s is not in this game", phrase.ID)}
+	}
+
+	phrase, err = validatePhrase(phrase, g.Master.Phrases())
+	if err != nil {
+		return err
+	}
+
+	bingos := getBingoBoards(g)
+
+	g.UpdatePhrase(phrase)
+	// The game works out whether the phrase is free, so save its copy.
+	phrase = g.Master.Records[i].Phrase
+
+	messages = append(messages, generateRescindedMessages(bingos, g)...)
+
+	if err := a.UpdatePhrase(ctx, g, phrase); err != nil {
+		return fmt.Errorf("error saving update phrase in firebase: %v", err)
+	}
+
+	if err := cache.UpdatePhrase(ctx, g, phrase); err != nil {
+		return fmt.Errorf("error saving update phrase in cache: %v", err)
+	}
+
+	if err := a.AddMessagesToGame(ctx, g, messages); err != nil {
+		return fmt.Errorf("could not send message announce bingo on select: %s", err)
+	}
+
+	return nil
+}
+
+func updateGamePhraseText(ctx context.Context, gid string, phrase Phrase) error {
+	messages := []Message{}
+
+	g, err := getGame(ctx, gid)
+	if err != nil {
+		return fmt.Errorf("could not get game id(%s): %s", gid, err)
+	}
+
+	i, _ := g.FindRecord(phrase)
+	if i == -1 {
+		return fmt.Errorf("phrase %s is not in game id(%s)", phrase.ID, gid)
+	}
+
+	phrase, err = validatePhrase(phrase, g.Master.Phrases())
+	if err != nil {
+		return err
+	}
+
+	g.UpdatePhraseText(phrase)
+
+	m := Message{}
+	m.SetText("A square has been reworded to <em>%s</em>.", html.EscapeString(phrase.Text))
+	m.SetAudience("all")
+	messages = append(messages, m)
+
+	if err := a.UpdatePhraseText(ctx, g, phrase); err != nil {
+		return fmt.Errorf("error saving phrase text in firebase: %v", err)
+	}
+
+	if err := cache.UpdatePhrase(ctx, g, phrase); err != nil {
+		return fmt.Errorf("error saving phrase text in cache: %v", err)
+	}
+
+	if err := a.AddMessagesToGame(ctx, g, messages); err != nil {
+		return fmt.Errorf("could not send message announce reworded phrase: %s", err)
+	}
+
+	return nil
+}
+
+// generateFalseClaimMessages tells a player their claim of bingo was
+// rejected, and the admins that they made it.
+func generateFalseClaimMessages(board Board, game Game) []Message {
+	messages := []Message{}
+
+	m1 := Message{}
+	m1.SetText("Your <em><strong>BINGO</strong></em> claim was rejected, there is no complete line on your board.")
+	if game.Scoring && game.RejectionPenalty() > 0 {
+		m1.SetText("Your <em><strong>BINGO</strong></em> claim was rejected, there is no complete line on your board. It cost you %d points.", game.RejectionPenalty())
+	}
+	m1.SetAudience(board.Player.Email)
+	m1.Bingo = true
+	messages = append(messages, m1)
+
+	m2 := Message{}
+	m2.SetText("<strong>%s</strong> made a false <em><strong>BINGO</strong></em> claim, that's %d so far.", board.Player.Name, board.Rejected)
+	m2.SetAudience("admin")
+	m2.Bingo = true
+	messages = append(messages, m2)
+
+	return messages
+}
+
+// getBingoBoards returns the boards of a game that currently have bingo, so
+// they can be compared after an admin changes the game.
+func getBingoBoards(game Game) map[string]Board {
 	bingos := make(map[string]Board)
 
-	for _, v := range g.Boards {
+	for _, v := range game.Boards {
 		if v.Bingo() {
 			bingos[v.ID] = v
 		}
 	}
 
-	g.UpdatePhrase(phrase)
+	return bingos
+}
+
+// generateRescindedMessages tells the players that had bingo before an admin
+// change, and no longer do, that they lost it. Boards that gained a bingo from
+// the change get the usual bingo announcements.
+func generateRescindedMessages(bingos map[string]Board, game Game) []Message {
+	messages := []Message{}
 
-	for _, v := range bingos {
-		if !v.Bingo() {
+	for _, v := range game.Boards {
+		_, before := bingos[v.ID]
+		after := v.Bingo()
+
+		if before && !after {
 			m := Message{}
 			m.SetText("An action from the <strong>game managers</strong> has rescinded your <em><strong>BINGO</strong></em>")
 			m.SetAudience(v.Player.Email)
@@ -394,19 +901,564 @@ func updateGamePhrases(gid string, phrase Phrase) error {
 			m2.Bingo = true
 			messages = append(messages, m2)
 		}
+
+		if !before && after && !game.Claims {
+			messages = append(messages, generateBingoMessages(v, game, true)...)
+		}
 	}
 
-	if err := a.UpdatePhrase(g, phrase); err != nil {
-		return fmt.Errorf("error saving update phrase in firebase: %v", err)
+	return messages
+}
+
+func addGamePhrase(ctx context.Context, gid string, phrase Phrase, reshuffle bool) error {
+	messages := []Message{}
+
+	g, err := getGame(ctx, gid)
+	if err != nil {
+		return fmt.Errorf("could not get game id(%s): %s", gid, err)
 	}
 
-	if err := cache.UpdatePhrase(g, phrase); err != nil {
-		return fmt.Errorf("error saving update phrase in cache: %v", err)
+	phrase, err = validatePhrase(phrase, g.Master.Phrases())
+	if err != nil {
+		return err
 	}
 
-	if err := a.AddMessagesToGame(g, messages); err != nil {
-		return fmt.Errorf("could not send message announce bingo on select: %s", err)
+	bingos := getBingoBoards(g)
+
+	g.AddPhrase(phrase)
+
+	m := Message{}
+	m.SetText("<strong>%s</strong> was added to the phrases for this game.", html.EscapeString(phrase.Text))
+	m.SetAudience("admin")
+
+	boards := []Board{}
+	if reshuffle {
+		g.Reshuffle()
+		for _, v := range g.Boards {
+			boards = append(boards, v)
+		}
+		m.SetText("A new square was added and every board has been reshuffled.")
+		m.SetAudience("all")
+	}
+	messages = append(messages, m)
+	messages = append(messages, generateRescindedMessages(bingos, g)...)
+
+	if err := a.SaveGamePhrases(ctx, g, boards, []string{}); err != nil {
+		return fmt.Errorf("error saving added phrase in firebase: %v", err)
+	}
+
+	if err := cache.SaveGameAndBoards(ctx, g); err != nil {
+		return fmt.Errorf("error saving added phrase in cache: %v", err)
+	}
+
+	if err := a.AddMessagesToGame(ctx, g, messages); err != nil {
+		return fmt.Errorf("could not send message announce added phrase: %s", err)
+	}
+
+	return nil
+}
+
+func removeGamePhrase(ctx context.Context, gid string, phrase Phrase, reshuffle bool) error {
+	messages := []Message{}
+
+	g, err := getGame(ctx, gid)
+	if err != nil {
+		return fmt.Errorf("could not get game id(%s): %s", gid, err)
+	}
+
+	bingos := getBingoBoards(g)
+
+	boards, err := g.RemovePhrase(phrase)
+	if err != nil {
+		return fmt.Errorf("could not remove phrase: %s", err)
+	}
+
+	m := Message{}
+	m.SetText("A square has been removed and replaced for the players that had it.")
+	m.SetAudience("all")
+
+	if reshuffle {
+		g.Reshuffle()
+		boards = []Board{}
+		for _, v := range g.Boards {
+			boards = append(boards, v)
+		}
+		m.SetText("A square has been removed and every board has been reshuffled.")
+	}
+	messages = append(messages, m)
+	messages = append(messages, generateRescindedMessages(bingos, g)...)
+
+	if err := a.SaveGamePhrases(ctx, g, boards, []string{phrase.ID}); err != nil {
+		return fmt.Errorf("error saving removed phrase in firebase: %v", err)
+	}
+
+	if err := cache.SaveGameAndBoards(ctx, g); err != nil {
+		return fmt.Errorf("error saving removed phrase in cache: %v", err)
+	}
+
+	if err := a.AddMessagesToGame(ctx, g, messages); err != nil {
+		return fmt.Errorf("could not send message announce removed phrase: %s", err)
+	}
+
+	return nil
+}
+
+func addMasterPhrase(ctx context.Context, phrase Phrase) error {
+	phrases, err := a.GetPhrases(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting master phrases : %v", err)
+	}
+
+	phrase, err = validatePhrase(phrase, phrases)
+	if err != nil {
+		return err
+	}
+
+	if err := a.LoadPhrases(ctx, []Phrase{phrase}); err != nil {
+		return fmt.Errorf("error adding master phrase : %v", err)
+	}
+
+	return nil
+}
+
+func removeMasterPhrase(ctx context.Context, phrase Phrase) error {
+	phrases, err := a.GetPhrases(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting master phrases : %v", err)
+	}
+
+	if len(phrases)-1 < boardSize {
+		return fmt.Errorf("the master list needs at least %d phrases", boardSize)
+	}
+
+	if err := a.DeleteMasterPhrase(ctx, phrase); err != nil {
+		return fmt.Errorf("error removing master phrase : %v", err)
+	}
+
+	return nil
+}
+
+func suggestPhrase(ctx context.Context, gid, email string, phrase Phrase, master bool) (Suggestion, error) {
+	g, err := getGame(ctx, gid)
+	if err != nil {
+		return Suggestion{}, fmt.Errorf("could not get game id(%s): %s", gid, err)
+	}
+
+	player, ok := g.Players.Find(email)
+	if !ok {
+		return Suggestion{}, ErrNotAdminOrPlayer
+	}
+
+	existing := g.Master.Phrases()
+	if master {
+		existing, err = a.GetPhrases(ctx)
+		if err != nil {
+			return Suggestion{}, fmt.Errorf("error getting master phrases : %v", err)
+		}
+	}
+
+	phrase, err = validatePhrase(phrase, existing)
+	if err != nil {
+		return Suggestion{}, err
+	}
+
+	s := NewSuggestion(gid, player, phrase, master)
+	if err := a.SaveSuggestion(ctx, s); err != nil {
+		return s, fmt.Errorf("error saving suggestion: %v", err)
+	}
+
+	target := "this game"
+	if master {
+		target = "the master list"
+	}
+
+	m := Message{}
+	m.SetText("<strong>%s</strong> suggested <em>%s</em> for %s.", html.EscapeString(player.Name), html.EscapeString(phrase.Text), target)
+	m.SetAudience("admin")
+
+	if err := a.AddMessagesToGame(ctx, g, []Message{m}); err != nil {
+		return s, fmt.Errorf("could not send message announce suggestion: %s", err)
+	}
+
+	return s, nil
+}
+
+func reviewSuggestion(ctx context.Context, sid, gid string, master, approve bool) error {
+	s, err := a.GetSuggestion(ctx, sid)
+	if err != nil {
+		return fmt.Errorf("could not get suggestion id(%s): %s", sid, err)
+	}
+
+	if s.Master != master || (!master && s.Game != gid) {
+		return ErrNotAdmin
+	}
+
+	if s.Status != SuggestionPending {
+		return fmt.Errorf("suggestion id(%s) has already been %s", sid, s.Status)
+	}
+
+	s.Status = SuggestionRejected
+	verdict := "was not added"
+
+	if approve {
+		s.Status = SuggestionApproved
+		verdict = "was added to the game"
+
+		if master {
+			verdict = "was added to the master list"
+			err = addMasterPhrase(ctx, s.Phrase)
+		} else {
+			err = addGamePhrase(ctx, s.Game, s.Phrase, false)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := a.SaveSuggestion(ctx, s); err != nil {
+		return fmt.Errorf("error saving suggestion: %v", err)
+	}
+
+	g := Game{}
+	g.ID = s.Game
+
+	m := Message{}
+	m.SetText("Your suggestion <em>%s</em> %s.", html.EscapeString(s.Phrase.Text), verdict)
+	m.SetAudience(s.Player.Email)
+
+	if err := a.AddMessagesToGame(ctx, g, []Message{m}); err != nil {
+		return fmt.Errorf("could not send message announce suggestion review: %s", err)
+	}
+
+	return nil
+}
+
+func joinLobby(ctx context.Context, gid string, player Player) (Candidates, error) {
+	g, err := getGame(ctx, gid)
+	if err != nil {
+		return Candidates{}, fmt.Errorf("could not get game id(%s): %s", gid, err)
+	}
+
+	if !g.Lobby {
+		return Candidates{}, fmt.Errorf("game id(%s) has already started", gid)
+	}
+
+	if !g.Players.IsMember(player) {
+		g.Players.Add(player)
+		if err := a.AddPlayerToGame(ctx, g, player); err != nil {
+			return Candidates{}, fmt.Errorf("error adding player to lobby: %v", err)
+		}
+		if err := cache.SaveGame(ctx, g); err != nil {
+			return Candidates{}, fmt.Errorf("error caching game : %v", err)
+		}
+		if err := cache.DeleteGamesForKey(ctx, []string{player.Email}); err != nil {
+			return Candidates{}, fmt.Errorf("error clearing game cache for player: %v", err)
+		}
+
+		m := Message{}
+		m.SetText("<strong>%s</strong> joined the lobby.", html.EscapeString(player.Name))
+		m.SetAudience("all")
+		if err := a.AddMessagesToGame(ctx, g, []Message{m}); err != nil {
+			return Candidates{}, fmt.Errorf("could not send message announce player: %s", err)
+		}
+	}
+
+	candidates, err := a.GetCandidates(ctx, gid)
+	if err != nil {
+		return candidates, fmt.Errorf("error getting candidates : %v", err)
+	}
+	candidates.Obscure(player.Email)
+
+	return candidates, nil
+}
+
+func addCandidate(ctx context.Context, gid, email string, phrase Phrase) (Candidate, error) {
+	g, err := getGame(ctx, gid)
+	if err != nil {
+		return Candidate{}, fmt.Errorf("could not get game id(%s): %s", gid, err)
+	}
+
+	if !g.Lobby {
+		return Candidate{}, fmt.Errorf("game id(%s) has already started", gid)
+	}
+
+	player, ok := g.Players.Find(email)
+	if !ok {
+		return Candidate{}, ErrNotAdminOrPlayer
+	}
+
+	candidates, err := a.GetCandidates(ctx, gid)
+	if err != nil {
+		return Candidate{}, fmt.Errorf("error getting candidates : %v", err)
+	}
+
+	phrase, err = validatePhrase(phrase, append(candidates.Phrases(), g.Master.Phrases()...))
+	if err != nil {
+		return Candidate{}, err
+	}
+
+	c := NewCandidate(player, phrase)
+	if err := a.SaveCandidate(ctx, gid, c); err != nil {
+		return c, fmt.Errorf("error saving candidate: %v", err)
+	}
+
+	m := Message{}
+	m.SetText("<strong>%s</strong> put forward <em>%s</em>.", html.EscapeString(player.Name), html.EscapeString(phrase.Text))
+	m.SetAudience("all")
+
+	if err := a.AddMessagesToGame(ctx, g, []Message{m}); err != nil {
+		return c, fmt.Errorf("could not send message announce candidate: %s", err)
+	}
+
+	return c, nil
+}
+
+func voteCandidate(ctx context.Context, gid, cid, email string, up bool) error {
+	g, err := getGame(ctx, gid)
+	if err != nil {
+		return fmt.Errorf("could not get game id(%s): %s", gid, err)
+	}
+
+	if !g.Lobby {
+		return fmt.Errorf("game id(%s) has already started", gid)
+	}
+
+	player, ok := g.Players.Find(email)
+	if !ok {
+		return ErrNotAdminOrPlayer
+	}
+
+	return a.VoteCandidate(ctx, gid, cid, player, up)
+}
+
+// startGame closes the lobby of a game. The n candidates with the most votes
+// become the phrases of the game, topped up from the master list if the
+// players didn't put forward enough to fill a board, and every player in the
+// lobby is dealt a board. With n of 0 just enough candidates are taken to fill
+// a board around the free squares.
+func startGame(ctx context.Context, gid string, n int) error {
+	g, err := getGame(ctx, gid)
+	if err != nil {
+		return fmt.Errorf("could not get game id(%s): %s", gid, err)
+	}
+
+	if !g.Lobby {
+		return fmt.Errorf("game id(%s) has already started", gid)
+	}
+
+	candidates, err := a.GetCandidates(ctx, gid)
+	if err != nil {
+		return fmt.Errorf("error getting candidates : %v", err)
+	}
+
+	if n == 0 {
+		n = boardSize - len(g.Master.Records)
+	}
+
+	phrases := candidates.Top(n)
+
+	needed := boardSize - len(g.Master.Records) - len(phrases)
+	if needed > 0 {
+		master, err := a.GetPhrases(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting master phrases : %v", err)
+		}
+		existing := append(g.Master.Phrases(), phrases...)
+
+		rand.Shuffle(len(master), func(i, j int) { master[i], master[j] = master[j], master[i] })
+
+		for _, v := range master {
+			if needed == 0 {
+				break
+			}
+			if v.Free {
+				continue
+			}
+			if _, err := validatePhrase(v, existing); err != nil {
+				continue
+			}
+			existing = append(existing, v)
+			phrases = append(phrases, v)
+			needed--
+		}
+
+		if needed > 0 {
+			return fmt.Errorf("not enough phrases to fill a board: need %d more", needed)
+		}
+	}
+
+	rates := map[string]float64{}
+	if g.Balanced {
+		stats, err := a.GetPhraseStats(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting phrase stats : %v", err)
+		}
+		rates = stats.Rates()
+	}
+
+	boards := g.Start(phrases, rates)
+
+	if err := a.SaveGame(ctx, g); err != nil {
+		return fmt.Errorf("error saving game to firestore : %v", err)
+	}
+
+	if err := a.SaveGamePhrases(ctx, g, []Board{}, []string{}); err != nil {
+		return fmt.Errorf("error saving game phrases in firebase: %v", err)
+	}
+
+	for _, v := range boards {
+		if _, err := a.SaveBoard(ctx, v); err != nil {
+			return fmt.Errorf("error saving board for player: %v", err)
+		}
+	}
+	metrics.boardsCreated.Add(float64(len(boards)))
+
+	if err := cache.SaveGameAndBoards(ctx, g); err != nil {
+		return fmt.Errorf("error caching game : %v", err)
+	}
+
+	keys := []string{"admin-list"}
+	for _, v := range g.Players {
+		keys = append(keys, v.Email)
+	}
+	if err := cache.DeleteGamesForKey(ctx, keys); err != nil {
+		return fmt.Errorf("error clearing game caches : %v", err)
+	}
+
+	m := Message{}
+	m.SetText("The lobby is closed and the game has begun!")
+	m.SetAudience("all")
+	m.Operation = "reset"
+
+	if err := a.AddMessagesToGame(ctx, g, []Message{m}); err != nil {
+		return fmt.Errorf("could not send message announce game start: %s", err)
+	}
+
+	return nil
+}
+
+// newRound starts the next round of a game, clearing every selection, or
+// dealing every player a new board with redeal, and keeping the results of
+// the round that just finished.
+func newRound(ctx context.Context, gid string, redeal bool) error {
+	g, err := getGame(ctx, gid)
+	if err != nil {
+		return fmt.Errorf("could not get game id(%s): %s", gid, err)
+	}
+
+	if g.Lobby {
+		return fmt.Errorf("game id(%s) is still in the lobby", gid)
+	}
+
+	boards := g.NewRound(redeal, time.Now())
+
+	if err := a.SaveGamePhrases(ctx, g, boards, []string{}); err != nil {
+		return fmt.Errorf("error saving new round in firebase: %v", err)
+	}
+
+	// Every board starts over, so counts as another deal of its phrases.
+	if err := a.CountDeals(ctx, boards); err != nil {
+		return fmt.Errorf("error saving new round in firebase: %v", err)
+	}
+
+	if err := a.SaveRounds(ctx, g); err != nil {
+		return fmt.Errorf("error saving new round in firebase: %v", err)
+	}
+
+	if err := cache.SaveGameAndBoards(ctx, g); err != nil {
+		return fmt.Errorf("error saving new round in cache: %v", err)
+	}
+
+	keys := []string{"admin-list"}
+	for _, v := range g.Players {
+		keys = append(keys, v.Email)
+	}
+	if err := cache.DeleteGamesForKey(ctx, keys); err != nil {
+		return fmt.Errorf("error clearing game caches : %v", err)
+	}
+
+	m := Message{}
+	m.SetText("Round %d has begun!", g.Round)
+	m.SetAudience("all")
+	m.Operation = "reset"
+
+	if err := a.AddMessagesToGame(ctx, g, []Message{m}); err != nil {
+		return fmt.Errorf("could not send message announce new round: %s", err)
+	}
+
+	return nil
+}
+
+func getNewTournament(ctx context.Context, name string) (Tournament, error) {
+	t := NewTournament(name)
+	if err := a.SaveTournament(ctx, t); err != nil {
+		return t, fmt.Errorf("error saving tournament: %v", err)
+	}
+	return t, nil
+}
+
+func addTournamentGame(ctx context.Context, tid, gid string) error {
+	t, err := a.GetTournament(ctx, tid)
+	if err != nil {
+		return fmt.Errorf("could not get tournament id(%s): %s", tid, err)
+	}
+
+	if _, err := getGame(ctx, gid); err != nil {
+		return fmt.Errorf("could not get game id(%s): %s", gid, err)
+	}
+
+	t.AddGame(gid)
+
+	if err := a.SaveTournament(ctx, t); err != nil {
+		return fmt.Errorf("error saving tournament: %v", err)
+	}
+	return nil
+}
+
+func removeTournamentGame(ctx context.Context, tid, gid string) error {
+	t, err := a.GetTournament(ctx, tid)
+	if err != nil {
+		return fmt.Errorf("could not get tournament id(%s): %s", tid, err)
+	}
+
+	if err := t.RemoveGame(gid); err != nil {
+		return err
 	}
 
+	if err := a.SaveTournament(ctx, t); err != nil {
+		return fmt.Errorf("error saving tournament: %v", err)
+	}
 	return nil
 }
+
+// setTournamentGameActive opens or closes one of the games in a tournament.
+func setTournamentGameActive(ctx context.Context, tid, gid string, active bool) error {
+	t, err := a.GetTournament(ctx, tid)
+	if err != nil {
+		return fmt.Errorf("could not get tournament id(%s): %s", tid, err)
+	}
+
+	if !t.HasGame(gid) {
+		return fmt.Errorf("game id(%s) is not in tournament id(%s)", gid, tid)
+	}
+
+	return setGameActive(ctx, gid, active)
+}
+
+func getTournamentStandings(ctx context.Context, tid string) (Standings, error) {
+	t, err := a.GetTournament(ctx, tid)
+	if err != nil {
+		return Standings{}, fmt.Errorf("could not get tournament id(%s): %s", tid, err)
+	}
+
+	games := []Game{}
+	for _, gid := range t.Games {
+		g, err := getGame(ctx, gid)
+		if err != nil {
+			return Standings{}, fmt.Errorf("could not get game id(%s): %s", gid, err)
+		}
+		games = append(games, g)
+	}
+
+	return NewStandings(games), nil
+}
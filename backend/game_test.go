@@ -15,6 +15,7 @@
 package main
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -25,38 +26,38 @@ func TestGetBoardForPlayer(t *testing.T) {
 		t.Errorf("initFirestoreBaseState() err want %v got %s ", nil, err)
 	}
 
-	boardFromFirestore, err := getBoardForPlayer(player, game)
+	boardFromFirestore, err := getBoardForPlayer(ctx, player, game)
 	if err != nil {
-		t.Errorf("getBoardForPlayer() err want %v got %s ", nil, err)
+		t.Errorf("getBoardForPlayer(ctx) err want %v got %s ", nil, err)
 	}
 
 	if !boardEquals(board, boardFromFirestore) {
-		t.Errorf("getBoardForPlayer() boardFromFirestore should return an unchanged board.")
+		t.Errorf("getBoardForPlayer(ctx) boardFromFirestore should return an unchanged board.")
 	}
 
-	boardFromCache, err := getBoardForPlayer(player, game)
+	boardFromCache, err := getBoardForPlayer(ctx, player, game)
 	if err != nil {
-		t.Errorf("getBoardForPlayer() err want %v got %s ", nil, err)
+		t.Errorf("getBoardForPlayer(ctx) err want %v got %s ", nil, err)
 	}
 
 	if !boardEquals(board, boardFromCache) {
-		t.Errorf("getBoardForPlayer() boardFromCache should return an unchanged board.")
+		t.Errorf("getBoardForPlayer(ctx) boardFromCache should return an unchanged board.")
 	}
 
-	if err := deleteBoard(board.ID, game.ID); err != nil {
-		t.Errorf("deleteBoard() err want %v got %s ", nil, err)
+	if err := deleteBoard(ctx, board.ID, game.ID); err != nil {
+		t.Errorf("deleteBoard(ctx) err want %v got %s ", nil, err)
 	}
 
-	boardFromFirestoreNew, err := getBoardForPlayer(player, game)
+	boardFromFirestoreNew, err := getBoardForPlayer(ctx, player, game)
 	if err != nil {
-		t.Errorf("getBoardForPlayer() err want %v got %s ", nil, err)
+		t.Errorf("getBoardForPlayer(ctx) err want %v got %s ", nil, err)
 	}
 
 	if boardEquals(board, boardFromFirestoreNew) {
-		t.Errorf("getBoardForPlayer() boardFromFirestoreNew should return an changed board.")
+		t.Errorf("getBoardForPlayer(ctx) boardFromFirestoreNew should return an changed board.")
 	}
 
-	if err := a.DeleteGame(game); err != nil {
+	if err := a.DeleteGame(ctx, game); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 }
@@ -67,34 +68,34 @@ func TestGetBoard(t *testing.T) {
 		t.Errorf("initFirestoreBaseState() err want %v got %s ", nil, err)
 	}
 
-	boardFromFirestore, err := getBoard(board.ID, game.ID)
+	boardFromFirestore, err := getBoard(ctx, board.ID, game.ID)
 	if err != nil {
-		t.Errorf("getBoard() err want %v got %s ", nil, err)
+		t.Errorf("getBoard(ctx) err want %v got %s ", nil, err)
 	}
 
 	if !boardEquals(board, boardFromFirestore) {
-		t.Errorf("getBoard() boardFromFirestore should return an unchanged board.")
+		t.Errorf("getBoard(ctx) boardFromFirestore should return an unchanged board.")
 	}
 
-	boardFromCache, err := getBoard(board.ID, game.ID)
+	boardFromCache, err := getBoard(ctx, board.ID, game.ID)
 	if err != nil {
-		t.Errorf("getBoard() err want %v got %s ", nil, err)
+		t.Errorf("getBoard(ctx) err want %v got %s ", nil, err)
 	}
 
 	if !boardEquals(board, boardFromCache) {
-		t.Errorf("getBoard() boardFromCache should return an unchanged board.")
+		t.Errorf("getBoard(ctx) boardFromCache should return an unchanged board.")
 	}
 
-	if err := deleteBoard(board.ID, game.ID); err != nil {
-		t.Errorf("deleteBoard() err want %v got %s ", nil, err)
+	if err := deleteBoard(ctx, board.ID, game.ID); err != nil {
+		t.Errorf("deleteBoard(ctx) err want %v got %s ", nil, err)
 	}
 
-	_, err = getBoard(board.ID, game.ID)
+	_, err = getBoard(ctx, board.ID, game.ID)
 	if err == nil {
-		t.Errorf("getBoard() err want err got %s ", err)
+		t.Errorf("getBoard(ctx) err want err got %s ", err)
 	}
 
-	if err := a.DeleteGame(game); err != nil {
+	if err := a.DeleteGame(ctx, game); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 }
@@ -108,21 +109,21 @@ func TestBingo(t *testing.T) {
 	bingoPhrases := getBingoPhrases(board)
 
 	for _, v := range bingoPhrases {
-		if err != recordSelect(board.ID, game.ID, v.ID, true) {
-			t.Errorf("recordSelect() err want %v got %s ", nil, err)
+		if err != recordSelect(ctx, board.ID, game.ID, v.ID, true) {
+			t.Errorf("recordSelect(ctx) err want %v got %s ", nil, err)
 		}
 	}
 
-	boardAfterBingo, err := getBoard(board.ID, game.ID)
+	boardAfterBingo, err := getBoard(ctx, board.ID, game.ID)
 	if err != nil {
-		t.Errorf("getBoard() err want %v got %s ", nil, err)
+		t.Errorf("getBoard(ctx) err want %v got %s ", nil, err)
 	}
 
 	if !boardAfterBingo.BingoDeclared {
 		t.Errorf("Should have created a bingo")
 	}
 
-	if err := a.DeleteGame(game); err != nil {
+	if err := a.DeleteGame(ctx, game); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 }
@@ -136,8 +137,8 @@ func TestGenerateBingoMessages(t *testing.T) {
 	bingoPhrases := getBingoPhrases(board)
 
 	for _, v := range bingoPhrases {
-		if err != recordSelect(board.ID, game.ID, v.ID, true) {
-			t.Errorf("recordSelect() err want %v got %s ", nil, err)
+		if err != recordSelect(ctx, board.ID, game.ID, v.ID, true) {
+			t.Errorf("recordSelect(ctx) err want %v got %s ", nil, err)
 		}
 	}
 
@@ -151,11 +152,499 @@ func TestGenerateBingoMessages(t *testing.T) {
 		t.Errorf("generateBingoMessages should have a bingo")
 	}
 
-	if err := a.DeleteGame(game); err != nil {
+	if err := a.DeleteGame(ctx, game); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 }
 
+func TestGenerateOneAwayMessages(t *testing.T) {
+	pl := Player{"Test", "test@example.com"}
+	game := NewGame("test name", pl, getTestPhrases())
+	game.OneAway = true
+	b := game.NewBoard(pl)
+
+	squares := make(map[int]Phrase)
+	for _, v := range b.Phrases {
+		squares[v.DisplayOrder] = v
+	}
+
+	for _, i := range []int{10, 11} {
+		b.Select(Phrase{ID: squares[i].ID, Selected: true})
+	}
+	before := b.OneAway()
+
+	if messages := generateOneAwayMessages(b, game, before); len(messages) != 0 {
+		t.Errorf("generateOneAwayMessages() want %d messages got %d", 0, len(messages))
+	}
+
+	b.Select(Phrase{ID: squares[13].ID, Selected: true})
+	game.Boards[b.ID] = b
+
+	messages := generateOneAwayMessages(b, game, before)
+	if len(messages) != 2 {
+		t.Fatalf("generateOneAwayMessages() want %d messages got %d", 2, len(messages))
+	}
+
+	if !strings.Contains(messages[0].Text, squares[14].Text) {
+		t.Errorf("generateOneAwayMessages() want missing phrase %s in %s", squares[14].Text, messages[0].Text)
+	}
+
+	if messages[1].Audience[0] != "admin" {
+		t.Errorf("generateOneAwayMessages() want admin count message got %+v", messages[1])
+	}
+
+	if messages := generateOneAwayMessages(b, game, b.OneAway()); len(messages) != 0 {
+		t.Errorf("generateOneAwayMessages() repeated want %d messages got %d", 0, len(messages))
+	}
+}
+
+func TestEndAfterWinners(t *testing.T) {
+	player := Player{"Test", "endafter@example.com"}
+
+	game, err := getNewGame(ctx, "End After Game", player, GameOptions{Free: DefaultFreeSquares(), EndAfter: 1})
+	if err != nil {
+		t.Errorf("getNewGame(ctx) err want %v got %s ", nil, err)
+	}
+
+	board, err := getBoardForPlayer(ctx, player, game)
+	if err != nil {
+		t.Errorf("getBoardForPlayer(ctx) err want %v got %s ", nil, err)
+	}
+
+	for _, v := range getBingoPhrases(board) {
+		if err := recordSelect(ctx, board.ID, game.ID, v.ID, true); err != nil {
+			t.Errorf("recordSelect(ctx) err want %v got %s ", nil, err)
+		}
+	}
+
+	ended, err := a.GetGame(ctx, game.ID)
+	if err != nil {
+		t.Errorf("Agent.GetGame() err want %v got %s ", nil, err)
+	}
+
+	if len(ended.Winners) != 1 || ended.Winners[0].Board != board.ID {
+		t.Errorf("recordSelect(ctx) winners want %s got %+v", board.ID, ended.Winners)
+	}
+
+	if ended.Active {
+		t.Errorf("recordSelect(ctx) expected the game to end after its first winner")
+	}
+
+	for _, v := range board.Phrases {
+		if v.Selected {
+			continue
+		}
+		if err := recordSelect(ctx, board.ID, game.ID, v.ID, true); err == nil {
+			t.Errorf("recordSelect(ctx) expected an error selecting on an ended game")
+		}
+		break
+	}
+
+	if err := a.DeleteGame(ctx, ended); err != nil {
+		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
+	}
+}
+
+func TestGenerateBingoMessagesSuspicion(t *testing.T) {
+	player := Player{"Test", "test@example.com"}
+	game := NewGame("test name", player, getTestPhrases())
+	board := game.NewBoard(player)
+
+	for _, v := range getBingoPhrases(board) {
+		v.Selected = true
+		p := board.Select(v)
+		game.Select(p, player)
+		board.AddActivity(p, "", board.Joined)
+	}
+
+	messages := generateBingoMessages(board, game, true)
+
+	found := false
+	for _, v := range messages {
+		if strings.Contains(v.Text, "suspicion score") {
+			found = true
+			if len(v.Audience) != 1 || v.Audience[0] != "admin" {
+				t.Errorf("generateBingoMessages() suspicion audience want admin got %v", v.Audience)
+			}
+		}
+	}
+
+	if !found {
+		t.Errorf("generateBingoMessages() expected a suspicion message got %+v", messages)
+	}
+}
+
+func TestSetSelection(t *testing.T) {
+	game, board, _, _, err := initFirestoreBaseState()
+	if err != nil {
+		t.Errorf("initFirestoreBaseState() err want %v got %s ", nil, err)
+	}
+
+	phrase := getBingoPhrases(board)[0]
+
+	cases := []struct {
+		label    string
+		selected bool
+		key      string
+		want     bool
+	}{
+		{"select", true, "1", true},
+		{"unselect", false, "2", false},
+		{"retried select", true, "1", false},
+		{"select again", true, "3", true},
+		{"repeated select", true, "4", true},
+	}
+
+	for _, c := range cases {
+		got, err := setSelection(ctx, board.ID, game.ID, phrase.ID, c.selected, c.key)
+		if err != nil {
+			t.Errorf("%s: setSelection(ctx) err want %v got %s ", c.label, nil, err)
+		}
+
+		if got.Phrases[phrase.ID].Selected != c.want {
+			t.Errorf("%s: setSelection(ctx) selected want %t got %t", c.label, c.want, got.Phrases[phrase.ID].Selected)
+		}
+	}
+
+	g, err := getGame(ctx, game.ID)
+	if err != nil {
+		t.Errorf("getGame(ctx) err want %v got %s ", nil, err)
+	}
+
+	if _, r := g.FindRecord(phrase); len(r.Players) != 1 || !r.Phrase.Selected {
+		t.Errorf("setSelection(ctx) record want %d player got %+v", 1, r)
+	}
+
+	if _, err := setSelection(ctx, board.ID, game.ID, "notonboard", true, ""); err == nil {
+		t.Errorf("setSelection(ctx) expected an error for a phrase not on the board")
+	}
+
+	if err := a.DeleteGame(ctx, game); err != nil {
+		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
+	}
+}
+
+func TestSelectPhrases(t *testing.T) {
+	game, board, _, _, err := initFirestoreBaseState()
+	if err != nil {
+		t.Errorf("initFirestoreBaseState() err want %v got %s ", nil, err)
+	}
+
+	selections := []Selection{}
+	for _, v := range getBingoPhrases(board) {
+		selections = append(selections, Selection{Phrase: v.ID, Selected: true})
+	}
+
+	bad := append([]Selection{{Phrase: "notonboard", Selected: true}}, selections...)
+	if _, err := selectPhrases(ctx, board, bad); err == nil {
+		t.Errorf("selectPhrases(ctx) expected an error for a phrase not on the board")
+	}
+
+	twice := append([]Selection{selections[0]}, selections...)
+	if _, err := selectPhrases(ctx, board, twice); err == nil {
+		t.Errorf("selectPhrases(ctx) expected an error for a phrase selected twice")
+	}
+
+	got, err := selectPhrases(ctx, board, selections)
+	if err != nil {
+		t.Errorf("selectPhrases(ctx) err want %v got %s ", nil, err)
+	}
+
+	if !got.BingoDeclared {
+		t.Errorf("selectPhrases(ctx) expected a bingo")
+	}
+
+	saved, err := a.GetBoard(ctx, board.ID, game.ID)
+	if err != nil {
+		t.Errorf("Agent.GetBoard() err want %v got %s ", nil, err)
+	}
+
+	for _, v := range selections {
+		if !saved.Phrases[v.Phrase].Selected {
+			t.Errorf("selectPhrases(ctx) phrase %s not saved as selected", v.Phrase)
+		}
+	}
+
+	g, err := getGame(ctx, game.ID)
+	if err != nil {
+		t.Errorf("getGame(ctx) err want %v got %s ", nil, err)
+	}
+
+	if len(g.Winners) != 1 {
+		t.Errorf("selectPhrases(ctx) winners want %d got %d", 1, len(g.Winners))
+	}
+
+	if err := a.DeleteGame(ctx, g); err != nil {
+		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
+	}
+}
+
+func TestSyncSelections(t *testing.T) {
+	game, board, _, _, err := initFirestoreBaseState()
+	if err != nil {
+		t.Errorf("initFirestoreBaseState() err want %v got %s ", nil, err)
+	}
+
+	queued := []Selection{{Phrase: "notonboard", Selected: true, Time: board.Joined}}
+	for i, v := range getBingoPhrases(board) {
+		queued = append(queued, Selection{Phrase: v.ID, Selected: true, Time: board.Joined.Add(time.Duration(i+1) * time.Second)})
+	}
+	completed := queued[len(queued)-1].Time
+
+	result, err := syncSelections(ctx, board.ID, game.ID, queued)
+	if err != nil {
+		t.Errorf("syncSelections(ctx) err want %v got %s ", nil, err)
+	}
+
+	if len(result.Applied) != len(queued)-1 || len(result.Conflicts) != 1 {
+		t.Errorf("syncSelections(ctx) want %d applied %d conflicts got %d %d", len(queued)-1, 1, len(result.Applied), len(result.Conflicts))
+	}
+
+	if !result.Board.BingoDeclared {
+		t.Errorf("syncSelections(ctx) expected a bingo")
+	}
+
+	g, err := getGame(ctx, game.ID)
+	if err != nil {
+		t.Errorf("getGame(ctx) err want %v got %s ", nil, err)
+	}
+
+	if len(g.Winners) != 1 || !g.Winners[0].Time.Equal(completed.UTC().Truncate(time.Millisecond)) {
+		t.Errorf("syncSelections(ctx) want winner at %s got %+v", completed, g.Winners)
+	}
+
+	if err := a.DeleteGame(ctx, g); err != nil {
+		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
+	}
+}
+
+func TestGenerateSelectionMessage(t *testing.T) {
+	board := Board{Player: Player{"Test", "test@example.com"}}
+
+	cases := []struct {
+		label   string
+		phrases []Phrase
+		want    string
+	}{
+		{"one", []Phrase{{Text: "a", Selected: true}}, "<strong>Test</strong> selected <em>a</em> on their board."},
+		{"unselected", []Phrase{{Text: "a"}}, "<strong>Test</strong> unselected <em>a</em> on their board."},
+		{"several", []Phrase{{Text: "a", Selected: true}, {Text: "b", Selected: true}, {Text: "c", Selected: true}}, "<strong>Test</strong> selected <em>a</em>, <em>b</em> and <em>c</em> on their board."},
+		{"mixed", []Phrase{{Text: "a", Selected: true}, {Text: "b"}, {Text: "c", Selected: true}}, "<strong>Test</strong> selected <em>a</em> and <em>c</em> and unselected <em>b</em> on their board."},
+	}
+
+	for _, c := range cases {
+		got := generateSelectionMessage(board, c.phrases)
+		if got.Text != c.want {
+			t.Errorf("%s: generateSelectionMessage() want %s got %s", c.label, c.want, got.Text)
+		}
+	}
+}
+
+func TestClaimBingo(t *testing.T) {
+	player := Player{"Test", "claims@example.com"}
+
+	game, err := getNewGame(ctx, "Claims Game", player, GameOptions{Free: DefaultFreeSquares(), Scoring: true, Claims: true, Penalty: 15})
+	if err != nil {
+		t.Errorf("getNewGame(ctx) err want %v got %s ", nil, err)
+	}
+
+	board, err := getBoardForPlayer(ctx, player, game)
+	if err != nil {
+		t.Errorf("getBoardForPlayer(ctx) err want %v got %s ", nil, err)
+	}
+
+	if err := claimBingo(ctx, board.ID, game.ID); err != nil {
+		t.Errorf("claimBingo(ctx) err want %v got %s ", nil, err)
+	}
+
+	for _, v := range getBingoPhrases(board) {
+		if err := recordSelect(ctx, board.ID, game.ID, v.ID, true); err != nil {
+			t.Errorf("recordSelect(ctx) err want %v got %s ", nil, err)
+		}
+	}
+
+	unclaimed, err := a.GetBoard(ctx, board.ID, game.ID)
+	if err != nil {
+		t.Errorf("Agent.GetBoard() err want %v got %s ", nil, err)
+	}
+
+	if unclaimed.BingoDeclared || unclaimed.Rejected != 1 || unclaimed.Points != -15 {
+		t.Errorf("claimBingo(ctx) false claim got declared %t rejected %d points %d", unclaimed.BingoDeclared, unclaimed.Rejected, unclaimed.Points)
+	}
+
+	if err := claimBingo(ctx, board.ID, game.ID); err != nil {
+		t.Errorf("claimBingo(ctx) err want %v got %s ", nil, err)
+	}
+
+	claimed, err := a.GetBoard(ctx, board.ID, game.ID)
+	if err != nil {
+		t.Errorf("Agent.GetBoard() err want %v got %s ", nil, err)
+	}
+
+	if !claimed.BingoDeclared || claimed.Rejected != 1 {
+		t.Errorf("claimBingo(ctx) valid claim got declared %t rejected %d", claimed.BingoDeclared, claimed.Rejected)
+	}
+
+	g, err := a.GetGame(ctx, game.ID)
+	if err != nil {
+		t.Errorf("Agent.GetGame() err want %v got %s ", nil, err)
+	}
+
+	if len(g.Winners) != 1 || g.Winners[0].Board != board.ID {
+		t.Errorf("claimBingo(ctx) winners want %s got %+v", board.ID, g.Winners)
+	}
+
+	if err := a.DeleteGame(ctx, g); err != nil {
+		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
+	}
+
+	plain, plainBoard, _, _, err := initFirestoreBaseState()
+	if err != nil {
+		t.Errorf("initFirestoreBaseState() err want %v got %s ", nil, err)
+	}
+
+	if err := claimBingo(ctx, plainBoard.ID, plain.ID); err == nil {
+		t.Errorf("claimBingo(ctx) expected an error claiming in a game without claims")
+	}
+
+	if err := a.DeleteGame(ctx, plain); err != nil {
+		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
+	}
+}
+
+func TestNewRound(t *testing.T) {
+	game, board, player, _, err := initFirestoreBaseState()
+	if err != nil {
+		t.Errorf("initFirestoreBaseState() err want %v got %s ", nil, err)
+	}
+
+	for _, v := range getBingoPhrases(board) {
+		if err := recordSelect(ctx, board.ID, game.ID, v.ID, true); err != nil {
+			t.Errorf("recordSelect(ctx) err want %v got %s ", nil, err)
+		}
+	}
+
+	if err := newRound(ctx, game.ID, false); err != nil {
+		t.Errorf("newRound(ctx) err want %v got %s ", nil, err)
+	}
+
+	next, err := a.GetGame(ctx, game.ID)
+	if err != nil {
+		t.Errorf("Agent.GetGame() err want %v got %s ", nil, err)
+	}
+
+	if next.Round != 2 || len(next.Rounds) != 1 || len(next.Rounds[0].Winners) != 1 {
+		t.Errorf("newRound(ctx) want round %d after %d winner got %d %+v", 2, 1, next.Round, next.Rounds)
+	}
+
+	b, err := a.GetBoardForPlayer(ctx, game.ID, player)
+	if err != nil {
+		t.Errorf("Agent.GetBoardForPlayer() err want %v got %s ", nil, err)
+	}
+
+	if b.BingoDeclared {
+		t.Errorf("newRound(ctx) expected board without bingo")
+	}
+
+	for _, v := range b.Phrases {
+		if v.Selected != v.Free {
+			t.Errorf("newRound(ctx) phrase %s selected %t", v.ID, v.Selected)
+		}
+	}
+
+	if err := a.DeleteGame(ctx, next); err != nil {
+		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
+	}
+}
+
+func TestTournament(t *testing.T) {
+	game, _, _, _, err := initFirestoreBaseState()
+	if err != nil {
+		t.Errorf("initFirestoreBaseState() err want %v got %s ", nil, err)
+	}
+
+	tournament, err := getNewTournament(ctx, "Test Tournament")
+	if err != nil {
+		t.Errorf("getNewTournament(ctx) err want %v got %s ", nil, err)
+	}
+
+	if err := setTournamentGameActive(ctx, tournament.ID, game.ID, false); err == nil {
+		t.Errorf("setTournamentGameActive(ctx) expected an error for a game not in the tournament")
+	}
+
+	if err := addTournamentGame(ctx, tournament.ID, game.ID); err != nil {
+		t.Errorf("addTournamentGame(ctx) err want %v got %s ", nil, err)
+	}
+
+	if err := setTournamentGameActive(ctx, tournament.ID, game.ID, false); err != nil {
+		t.Errorf("setTournamentGameActive(ctx) err want %v got %s ", nil, err)
+	}
+
+	closed, err := a.GetGame(ctx, game.ID)
+	if err != nil {
+		t.Errorf("Agent.GetGame() err want %v got %s ", nil, err)
+	}
+
+	if closed.Active {
+		t.Errorf("setTournamentGameActive(ctx) expected the game to be closed")
+	}
+
+	if err := setTournamentGameActive(ctx, tournament.ID, game.ID, true); err != nil {
+		t.Errorf("setTournamentGameActive(ctx) err want %v got %s ", nil, err)
+	}
+
+	standings, err := getTournamentStandings(ctx, tournament.ID)
+	if err != nil {
+		t.Errorf("getTournamentStandings(ctx) err want %v got %s ", nil, err)
+	}
+
+	if len(standings) != 1 || standings[0].Games != 1 {
+		t.Errorf("getTournamentStandings(ctx) got %+v", standings)
+	}
+
+	if err := removeTournamentGame(ctx, tournament.ID, game.ID); err != nil {
+		t.Errorf("removeTournamentGame(ctx) err want %v got %s ", nil, err)
+	}
+
+	if err := addTournamentGame(ctx, tournament.ID, game.ID); err != nil {
+		t.Errorf("addTournamentGame(ctx) err want %v got %s ", nil, err)
+	}
+
+	if err := a.DeleteGame(ctx, game); err != nil {
+		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
+	}
+
+	standings, err = getTournamentStandings(ctx, tournament.ID)
+	if err != nil {
+		t.Errorf("getTournamentStandings(ctx) after deleting a game err want %v got %s ", nil, err)
+	}
+
+	if len(standings) != 0 {
+		t.Errorf("getTournamentStandings(ctx) after deleting a game got %+v", standings)
+	}
+
+	if err := a.DeleteTournament(ctx, tournament); err != nil {
+		t.Errorf("Agent.DeleteTournament() err want %v got %s ", nil, err)
+	}
+}
+
+func TestGenerateResultsMessage(t *testing.T) {
+	game := Game{}
+	game.AddWinner(Board{ID: "1", Player: Player{Name: "First"}}, time.Now())
+	game.AddWinner(Board{ID: "2", Player: Player{Name: "<b>Second</b>"}}, time.Now())
+
+	m := generateResultsMessage(game)
+
+	want := "The game is over! 1. <strong>First</strong>, 2. <strong>&lt;b&gt;Second&lt;/b&gt;</strong>"
+	if m.Text != want {
+		t.Errorf("generateResultsMessage() want %s got %s", want, m.Text)
+	}
+
+	if m.Audience[0] != "all" {
+		t.Errorf("generateResultsMessage() audience want %s got %s", "all", m.Audience[0])
+	}
+}
+
 func TestDubiousBingoMessages(t *testing.T) {
 	game, board, _, _, err := initFirestoreBaseState()
 	if err != nil {
@@ -171,52 +660,52 @@ func TestDubiousBingoMessages(t *testing.T) {
 	player4 := Player{}
 	player4.Email = "test4@example.com"
 
-	_, err = getBoardForPlayer(player2, game)
+	_, err = getBoardForPlayer(ctx, player2, game)
 	if err != nil {
-		t.Errorf("getBoardForPlayer() err want %v got %s ", nil, err)
+		t.Errorf("getBoardForPlayer(ctx) err want %v got %s ", nil, err)
 	}
 
-	game, err = getGame(game.ID)
+	game, err = getGame(ctx, game.ID)
 	if err != nil {
-		t.Errorf("getGame() err want %v got %s ", nil, err)
+		t.Errorf("getGame(ctx) err want %v got %s ", nil, err)
 	}
 
-	_, err = getBoardForPlayer(player3, game)
+	_, err = getBoardForPlayer(ctx, player3, game)
 	if err != nil {
-		t.Errorf("getBoardForPlayer() err want %v got %s ", nil, err)
+		t.Errorf("getBoardForPlayer(ctx) err want %v got %s ", nil, err)
 	}
 
-	game, err = getGame(game.ID)
+	game, err = getGame(ctx, game.ID)
 	if err != nil {
-		t.Errorf("getGame() err want %v got %s ", nil, err)
+		t.Errorf("getGame(ctx) err want %v got %s ", nil, err)
 	}
 
-	_, err = getBoardForPlayer(player4, game)
+	_, err = getBoardForPlayer(ctx, player4, game)
 	if err != nil {
-		t.Errorf("getBoardForPlayer() err want %v got %s ", nil, err)
+		t.Errorf("getBoardForPlayer(ctx) err want %v got %s ", nil, err)
 	}
 
-	game, err = getGame(game.ID)
+	game, err = getGame(ctx, game.ID)
 	if err != nil {
-		t.Errorf("getGame() err want %v got %s ", nil, err)
+		t.Errorf("getGame(ctx) err want %v got %s ", nil, err)
 	}
 
 	bingoPhrases := getBingoPhrases(board)
 
 	for _, v := range bingoPhrases {
-		if err != recordSelect(board.ID, game.ID, v.ID, true) {
-			t.Errorf("recordSelect() err want %v got %s ", nil, err)
+		if err != recordSelect(ctx, board.ID, game.ID, v.ID, true) {
+			t.Errorf("recordSelect(ctx) err want %v got %s ", nil, err)
 		}
 	}
 
-	gameUpdated, err := getGame(game.ID)
+	gameUpdated, err := getGame(ctx, game.ID)
 	if err != nil {
-		t.Errorf("getGame() err want %v got %s ", nil, err)
+		t.Errorf("getGame(ctx) err want %v got %s ", nil, err)
 	}
 
-	boardUpdated, err := getBoard(board.ID, game.ID)
+	boardUpdated, err := getBoard(ctx, board.ID, game.ID)
 	if err != nil {
-		t.Errorf("getBoard() err want %v got %s ", nil, err)
+		t.Errorf("getBoard(ctx) err want %v got %s ", nil, err)
 	}
 
 	messages := generateBingoMessages(boardUpdated, gameUpdated, true)
@@ -225,7 +714,66 @@ func TestDubiousBingoMessages(t *testing.T) {
 		t.Errorf("generateBingoMessages should have created a glut of messages")
 	}
 
-	if err := a.DeleteGame(game); err != nil {
+	if err := a.DeleteGame(ctx, game); err != nil {
+		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
+	}
+}
+
+func TestConfirmBingo(t *testing.T) {
+	game, board, _, _, err := initFirestoreBaseState()
+	if err != nil {
+		t.Errorf("initFirestoreBaseState() err want %v got %s ", nil, err)
+	}
+
+	for _, email := range []string{"test2@example.com", "test3@example.com"} {
+		player := Player{}
+		player.Email = email
+
+		if _, err := getBoardForPlayer(ctx, player, game); err != nil {
+			t.Errorf("getBoardForPlayer(ctx) err want %v got %s ", nil, err)
+		}
+
+		game, err = getGame(ctx, game.ID)
+		if err != nil {
+			t.Errorf("getGame(ctx) err want %v got %s ", nil, err)
+		}
+	}
+
+	if err := confirmBingo(ctx, board.ID, game.ID); err == nil {
+		t.Errorf("confirmBingo(ctx) expected an error confirming a board without bingo")
+	}
+
+	for _, v := range getBingoPhrases(board) {
+		if err := recordSelect(ctx, board.ID, game.ID, v.ID, true); err != nil {
+			t.Errorf("recordSelect(ctx) err want %v got %s ", nil, err)
+		}
+	}
+
+	dubious, err := a.GetGame(ctx, game.ID)
+	if err != nil {
+		t.Errorf("Agent.GetGame() err want %v got %s ", nil, err)
+	}
+
+	if len(dubious.Winners) != 0 {
+		t.Errorf("recordSelect(ctx) dubious bingo want no winners got %+v", dubious.Winners)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := confirmBingo(ctx, board.ID, game.ID); err != nil {
+			t.Errorf("confirmBingo(ctx) err want %v got %s ", nil, err)
+		}
+	}
+
+	confirmed, err := a.GetGame(ctx, game.ID)
+	if err != nil {
+		t.Errorf("Agent.GetGame() err want %v got %s ", nil, err)
+	}
+
+	if len(confirmed.Winners) != 1 || confirmed.Winners[0].Board != board.ID {
+		t.Errorf("confirmBingo(ctx) winners want %s got %+v", board.ID, confirmed.Winners)
+	}
+
+	if err := a.DeleteGame(ctx, game); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 }
@@ -235,16 +783,16 @@ func TestGamesUniqueID(t *testing.T) {
 	player.Email = "test@example.com"
 	phrases := getTestPhrases()
 
-	if err := a.LoadPhrases(phrases); err != nil {
+	if err := a.LoadPhrases(ctx, phrases); err != nil {
 		t.Errorf("Agent.LoadPhrases() err want %v got %s ", nil, err)
 	}
 
-	game, err := a.NewGame("test game", player)
+	game, err := a.NewGame(ctx, "test game", player, GameOptions{Free: DefaultFreeSquares()})
 	if err != nil {
 		t.Errorf("Agent.LoadPhrases() err want %v got %s ", nil, err)
 	}
 
-	game2, err := a.NewGame("test game2", player)
+	game2, err := a.NewGame(ctx, "test game2", player, GameOptions{Free: DefaultFreeSquares()})
 	if err != nil {
 		t.Errorf("Agent.LoadPhrases() err want %v got %s ", nil, err)
 	}
@@ -253,11 +801,11 @@ func TestGamesUniqueID(t *testing.T) {
 		t.Errorf("Expected different games to have different ids. ")
 	}
 
-	if err := a.DeleteGame(game); err != nil {
+	if err := a.DeleteGame(ctx, game); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 
-	if err := a.DeleteGame(game2); err != nil {
+	if err := a.DeleteGame(ctx, game2); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 }
@@ -268,12 +816,12 @@ func TestGetGames(t *testing.T) {
 		t.Errorf("initFirestoreBaseState() err want %v got %s ", nil, err)
 	}
 
-	game2, err := a.NewGame("test game2", player)
+	game2, err := a.NewGame(ctx, "test game2", player, GameOptions{Free: DefaultFreeSquares()})
 	if err != nil {
 		t.Errorf("Agent.NewGame() err want %v got %s ", nil, err)
 	}
 
-	gamesFromFirestore, err := getGamesForKey("admin-list", 10, time.Now())
+	gamesFromFirestore, err := getGamesForKey(ctx, "admin-list", 10, time.Now())
 	if err != nil {
 		t.Errorf("Agent.GetGames() err want %v got %s ", nil, err)
 	}
@@ -282,7 +830,7 @@ func TestGetGames(t *testing.T) {
 		t.Errorf("Agent.GetGames() count want %d got %d ", 2, len(gamesFromFirestore))
 	}
 
-	gamesFromCache, err := getGamesForKey("admin-list", 10, time.Now())
+	gamesFromCache, err := getGamesForKey(ctx, "admin-list", 10, time.Now())
 	if err != nil {
 		t.Errorf("Agent.GetGames() err want %v got %s ", nil, err)
 	}
@@ -291,11 +839,11 @@ func TestGetGames(t *testing.T) {
 		t.Errorf("Agent.GetGames() count want %d got %d ", 2, len(gamesFromCache))
 	}
 
-	if err := a.DeleteGame(game); err != nil {
+	if err := a.DeleteGame(ctx, game); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 
-	if err := a.DeleteGame(game2); err != nil {
+	if err := a.DeleteGame(ctx, game2); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 }
@@ -306,12 +854,12 @@ func TestGetGamesForPlayer(t *testing.T) {
 		t.Errorf("initFirestoreBaseState() err want %v got %s ", nil, err)
 	}
 
-	game2, err := a.NewGame("test game", player)
+	game2, err := a.NewGame(ctx, "test game", player, GameOptions{Free: DefaultFreeSquares()})
 	if err != nil {
 		t.Errorf("Agent.NewGame() err want %v got %s ", nil, err)
 	}
 
-	gamesFromFirestore, err := getGamesForKey(player.Email, 10, time.Now())
+	gamesFromFirestore, err := getGamesForKey(ctx, player.Email, 10, time.Now())
 	if err != nil {
 		t.Errorf("Agent.GetGames() err want %v got %s ", nil, err)
 	}
@@ -320,7 +868,7 @@ func TestGetGamesForPlayer(t *testing.T) {
 		t.Errorf("Agent.GetGames() count want %d got %d ", 2, len(gamesFromFirestore))
 	}
 
-	gamesFromCache, err := getGamesForKey(player.Email, 10, time.Now())
+	gamesFromCache, err := getGamesForKey(ctx, player.Email, 10, time.Now())
 	if err != nil {
 		t.Errorf("Agent.GetGames() err want %v got %s ", nil, err)
 	}
@@ -329,11 +877,11 @@ func TestGetGamesForPlayer(t *testing.T) {
 		t.Errorf("Agent.GetGames() count want %d got %d ", 2, len(gamesFromCache))
 	}
 
-	if err := a.DeleteGame(game); err != nil {
+	if err := a.DeleteGame(ctx, game); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 
-	if err := a.DeleteGame(game2); err != nil {
+	if err := a.DeleteGame(ctx, game2); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 }
@@ -344,11 +892,11 @@ func TestDeactivateGame(t *testing.T) {
 		t.Errorf("initFirestoreBaseState() err want %v got %s ", nil, err)
 	}
 
-	if err := deactivateGame(game.ID); err != nil {
-		t.Errorf("deactivateGame() err want %v got %s ", nil, err)
+	if err := deactivateGame(ctx, game.ID); err != nil {
+		t.Errorf("deactivateGame(ctx) err want %v got %s ", nil, err)
 	}
 
-	gamesFromCache, err := getGame(game.ID)
+	gamesFromCache, err := getGame(ctx, game.ID)
 	if err != nil {
 		t.Errorf("Agent.GetGames() err want %v got %s ", nil, err)
 	}
@@ -357,11 +905,11 @@ func TestDeactivateGame(t *testing.T) {
 		t.Errorf("deactivateGame return want %t got %t ", false, gamesFromCache.Active)
 	}
 
-	if err := cache.DeleteGame(gamesFromCache); err != nil {
-		t.Errorf("cache.DeleteGame() err want %v got %s ", nil, err)
+	if err := cache.DeleteGame(ctx, gamesFromCache); err != nil {
+		t.Errorf("cache.DeleteGame(ctx) err want %v got %s ", nil, err)
 	}
 
-	gamesFromFirestore, err := getGame(game.ID)
+	gamesFromFirestore, err := getGame(ctx, game.ID)
 	if err != nil {
 		t.Errorf("getGames() err want %v got %s ", nil, err)
 	}
@@ -370,7 +918,7 @@ func TestDeactivateGame(t *testing.T) {
 		t.Errorf("deactivateGame return want %t got %t ", false, gamesFromFirestore.Active)
 	}
 
-	if err := a.DeleteGame(game); err != nil {
+	if err := a.DeleteGame(ctx, game); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 }
@@ -382,21 +930,21 @@ func TestUpdateGamePhrases(t *testing.T) {
 	}
 	phrase.Text = "I changed it in the db"
 
-	if err := updateGamePhrases(game.ID, phrase); err != nil {
-		t.Errorf("updateGamePhrases() err want %v got %s ", nil, err)
+	if err := updateGamePhrases(ctx, game.ID, phrase); err != nil {
+		t.Errorf("updateGamePhrases(ctx) err want %v got %s ", nil, err)
 	}
 
-	gamesFromCache, err := getGame(game.ID)
+	gamesFromCache, err := getGame(ctx, game.ID)
 	if err != nil {
 		t.Errorf("Agent.GetGames() err want %v got %s ", nil, err)
 	}
 
 	_, record := gamesFromCache.FindRecord(phrase)
 	if record.Phrase.Text != phrase.Text {
-		t.Errorf("updateGamePhrases() text want %s got %s ", phrase.Text, record.Phrase.Text)
+		t.Errorf("updateGamePhrases(ctx) text want %s got %s ", phrase.Text, record.Phrase.Text)
 	}
 
-	if err := a.DeleteGame(game); err != nil {
+	if err := a.DeleteGame(ctx, game); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 }
@@ -411,14 +959,14 @@ func TestUpdateGamePhrasesWithBingo(t *testing.T) {
 	bingoPhrases := getBingoPhrases(board)
 
 	for _, v := range bingoPhrases {
-		if err != recordSelect(board.ID, game.ID, v.ID, true) {
-			t.Errorf("recordSelect() err want %v got %s ", nil, err)
+		if err != recordSelect(ctx, board.ID, game.ID, v.ID, true) {
+			t.Errorf("recordSelect(ctx) err want %v got %s ", nil, err)
 		}
 	}
 
-	boardAfterBingo, err := getBoard(board.ID, game.ID)
+	boardAfterBingo, err := getBoard(ctx, board.ID, game.ID)
 	if err != nil {
-		t.Errorf("getBoard() err want %v got %s ", nil, err)
+		t.Errorf("getBoard(ctx) err want %v got %s ", nil, err)
 	}
 
 	if !boardAfterBingo.BingoDeclared {
@@ -428,33 +976,278 @@ func TestUpdateGamePhrasesWithBingo(t *testing.T) {
 	phrase := bingoPhrases[0]
 	phrase.Text = "I changed it in the db"
 
-	if err := updateGamePhrases(game.ID, phrase); err != nil {
-		t.Errorf("updateGamePhrases() err want %v got %s ", nil, err)
+	if err := updateGamePhrases(ctx, game.ID, phrase); err != nil {
+		t.Errorf("updateGamePhrases(ctx) err want %v got %s ", nil, err)
 	}
 
-	boardAfterBingoReverted, err := getBoard(board.ID, game.ID)
+	boardAfterBingoReverted, err := getBoard(ctx, board.ID, game.ID)
 	if err != nil {
-		t.Errorf("getBoard() err want %v got %s ", nil, err)
+		t.Errorf("getBoard(ctx) err want %v got %s ", nil, err)
 	}
 
 	if boardAfterBingoReverted.BingoDeclared {
 		t.Errorf("Should have reverted a bingo")
 	}
 
-	if err := recordSelect(board.ID, game.ID, phrase.ID, true); err != nil {
-		t.Errorf("updateGamePhrases() err want %v got %s ", nil, err)
+	if err := recordSelect(ctx, board.ID, game.ID, phrase.ID, true); err != nil {
+		t.Errorf("updateGamePhrases(ctx) err want %v got %s ", nil, err)
 	}
 
-	boardAfterBingoRevertedThenRedone, err := getBoard(board.ID, game.ID)
+	boardAfterBingoRevertedThenRedone, err := getBoard(ctx, board.ID, game.ID)
 	if err != nil {
-		t.Errorf("getBoard() err want %v got %s ", nil, err)
+		t.Errorf("getBoard(ctx) err want %v got %s ", nil, err)
 	}
 
 	if !boardAfterBingoRevertedThenRedone.BingoDeclared {
 		t.Errorf("Should have created a bingo")
 	}
 
-	if err := a.DeleteGame(game); err != nil {
+	if err := a.DeleteGame(ctx, game); err != nil {
+		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
+	}
+}
+
+func TestUpdateGamePhraseTextKeepsBingo(t *testing.T) {
+	game, board, _, _, err := initFirestoreBaseState()
+	if err != nil {
+		t.Errorf("initFirestoreBaseState() err want %v got %s ", nil, err)
+	}
+
+	bingoPhrases := getBingoPhrases(board)
+
+	for _, v := range bingoPhrases {
+		if err != recordSelect(ctx, board.ID, game.ID, v.ID, true) {
+			t.Errorf("recordSelect(ctx) err want %v got %s ", nil, err)
+		}
+	}
+
+	phrase := bingoPhrases[0]
+	phrase.Text = "I fixed a typo"
+
+	if err := updateGamePhraseText(ctx, game.ID, phrase); err != nil {
+		t.Errorf("updateGamePhraseText(ctx) err want %v got %s ", nil, err)
+	}
+
+	if err := cache.Clear(ctx); err != nil {
+		t.Errorf("Cache.Clear() err want %v got %s ", nil, err)
+	}
+
+	boardFromFirestore, err := getBoard(ctx, board.ID, game.ID)
+	if err != nil {
+		t.Errorf("getBoard(ctx) err want %v got %s ", nil, err)
+	}
+
+	if !boardFromFirestore.BingoDeclared {
+		t.Errorf("updateGamePhraseText(ctx) should not have rescinded the bingo")
+	}
+
+	got := boardFromFirestore.Phrases[phrase.ID]
+	if got.Text != phrase.Text || !got.Selected {
+		t.Errorf("updateGamePhraseText(ctx) board phrase got %+v, want selected with text %s", got, phrase.Text)
+	}
+
+	if err := a.DeleteGame(ctx, game); err != nil {
+		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
+	}
+}
+
+func TestAddAndRemoveGamePhrase(t *testing.T) {
+	game, board, _, phrase, err := initFirestoreBaseState()
+	if err != nil {
+		t.Errorf("initFirestoreBaseState() err want %v got %s ", nil, err)
+	}
+
+	added := Phrase{}
+	added.ID = "26"
+	added.Text = "Filler 26"
+
+	if err := addGamePhrase(ctx, game.ID, added, false); err != nil {
+		t.Errorf("addGamePhrase(ctx) err want %v got %s ", nil, err)
+	}
+
+	if err := removeGamePhrase(ctx, game.ID, phrase, false); err != nil {
+		t.Errorf("removeGamePhrase(ctx) err want %v got %s ", nil, err)
+	}
+
+	if err := cache.Clear(ctx); err != nil {
+		t.Errorf("Cache.Clear() err want %v got %s ", nil, err)
+	}
+
+	gameFromFirestore, err := getGame(ctx, game.ID)
+	if err != nil {
+		t.Errorf("getGame(ctx) err want %v got %s ", nil, err)
+	}
+
+	if i, _ := gameFromFirestore.FindRecord(phrase); i != -1 {
+		t.Errorf("removeGamePhrase(ctx) expected phrase to be gone from the records")
+	}
+
+	boardFromFirestore, err := getBoard(ctx, board.ID, game.ID)
+	if err != nil {
+		t.Errorf("getBoard(ctx) err want %v got %s ", nil, err)
+	}
+
+	if _, ok := boardFromFirestore.Phrases[added.ID]; !ok {
+		t.Errorf("removeGamePhrase(ctx) expected added phrase to replace the removed one")
+	}
+
+	if len(boardFromFirestore.Phrases) != boardSize {
+		t.Errorf("removeGamePhrase(ctx) squares got %d, want %d", len(boardFromFirestore.Phrases), boardSize)
+	}
+
+	if err := a.DeleteGame(ctx, game); err != nil {
+		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
+	}
+}
+
+func TestReviewSuggestions(t *testing.T) {
+	game, _, player, _, err := initFirestoreBaseState()
+	if err != nil {
+		t.Errorf("initFirestoreBaseState() err want %v got %s ", nil, err)
+	}
+
+	approved := Phrase{}
+	approved.ID = "26"
+	approved.Text = "Someone says 'synergy'"
+
+	rejected := Phrase{}
+	rejected.ID = "27"
+	rejected.Text = "Someone says 'paradigm'"
+
+	s1, err := suggestPhrase(ctx, game.ID, player.Email, approved, false)
+	if err != nil {
+		t.Errorf("suggestPhrase(ctx) err want %v got %s ", nil, err)
+	}
+
+	s2, err := suggestPhrase(ctx, game.ID, player.Email, rejected, false)
+	if err != nil {
+		t.Errorf("suggestPhrase(ctx) err want %v got %s ", nil, err)
+	}
+
+	if _, err := suggestPhrase(ctx, game.ID, "stranger@example.com", rejected, false); err != ErrNotAdminOrPlayer {
+		t.Errorf("suggestPhrase(ctx) err want %v got %s ", ErrNotAdminOrPlayer, err)
+	}
+
+	pending, err := a.GetPendingSuggestions(ctx, game.ID, false)
+	if err != nil {
+		t.Errorf("Agent.GetPendingSuggestions() err want %v got %s ", nil, err)
+	}
+
+	if len(pending) != 2 {
+		t.Errorf("Agent.GetPendingSuggestions() count want %d got %d ", 2, len(pending))
+	}
+
+	if err := reviewSuggestion(ctx, s1.ID, game.ID, true, true); err != ErrNotAdmin {
+		t.Errorf("reviewSuggestion(ctx) err want %v got %s ", ErrNotAdmin, err)
+	}
+
+	if err := reviewSuggestion(ctx, s1.ID, game.ID, false, true); err != nil {
+		t.Errorf("reviewSuggestion(ctx) err want %v got %s ", nil, err)
+	}
+
+	if err := reviewSuggestion(ctx, s2.ID, game.ID, false, false); err != nil {
+		t.Errorf("reviewSuggestion(ctx) err want %v got %s ", nil, err)
+	}
+
+	if err := reviewSuggestion(ctx, s2.ID, game.ID, false, true); err == nil {
+		t.Errorf("reviewSuggestion(ctx) expected an error reviewing a suggestion twice")
+	}
+
+	gameUpdated, err := getGame(ctx, game.ID)
+	if err != nil {
+		t.Errorf("getGame(ctx) err want %v got %s ", nil, err)
+	}
+
+	if i, _ := gameUpdated.FindRecord(approved); i == -1 {
+		t.Errorf("reviewSuggestion(ctx) expected approved phrase in the game")
+	}
+
+	if i, _ := gameUpdated.FindRecord(rejected); i != -1 {
+		t.Errorf("reviewSuggestion(ctx) expected rejected phrase not in the game")
+	}
+
+	pendingAfter, err := a.GetPendingSuggestions(ctx, game.ID, false)
+	if err != nil {
+		t.Errorf("Agent.GetPendingSuggestions() err want %v got %s ", nil, err)
+	}
+
+	if len(pendingAfter) != 0 {
+		t.Errorf("Agent.GetPendingSuggestions() count want %d got %d ", 0, len(pendingAfter))
+	}
+
+	if err := a.DeleteGame(ctx, game); err != nil {
+		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
+	}
+}
+
+func TestLobbyGame(t *testing.T) {
+	admin := Player{"Admin", "lobbyadmin@example.com"}
+	player := Player{"Player", "lobbyplayer@example.com"}
+
+	game, err := getNewGame(ctx, "Lobby Game", admin, GameOptions{Free: DefaultFreeSquares(), Lobby: true})
+	if err != nil {
+		t.Errorf("getNewGame(ctx) err want %v got %s ", nil, err)
+	}
+
+	if _, err := getBoardForPlayer(ctx, player, game); err == nil {
+		t.Errorf("getBoardForPlayer(ctx) expected an error while in the lobby")
+	}
+
+	if _, err := joinLobby(ctx, game.ID, player); err != nil {
+		t.Errorf("joinLobby(ctx) err want %v got %s ", nil, err)
+	}
+
+	c1, err := addCandidate(ctx, game.ID, player.Email, Phrase{ID: "lobby-1", Text: "Someone says 'synergy'"})
+	if err != nil {
+		t.Errorf("addCandidate(ctx) err want %v got %s ", nil, err)
+	}
+
+	if _, err := addCandidate(ctx, game.ID, player.Email, Phrase{ID: "lobby-2", Text: "someone says 'SYNERGY'"}); err == nil {
+		t.Errorf("addCandidate(ctx) expected an error adding a duplicate candidate")
+	}
+
+	if _, err := addCandidate(ctx, game.ID, "stranger@example.com", Phrase{ID: "lobby-3", Text: "Someone says 'paradigm'"}); err != ErrNotAdminOrPlayer {
+		t.Errorf("addCandidate(ctx) err want %v got %s ", ErrNotAdminOrPlayer, err)
+	}
+
+	if err := voteCandidate(ctx, game.ID, c1.ID, admin.Email, true); err != nil {
+		t.Errorf("voteCandidate(ctx) err want %v got %s ", nil, err)
+	}
+
+	if err := voteCandidate(ctx, game.ID, c1.ID, player.Email, true); err == nil {
+		t.Errorf("voteCandidate(ctx) expected an error voting for your own phrase")
+	}
+
+	if err := startGame(ctx, game.ID, 0); err != nil {
+		t.Errorf("startGame(ctx) err want %v got %s ", nil, err)
+	}
+
+	if err := startGame(ctx, game.ID, 0); err == nil {
+		t.Errorf("startGame(ctx) expected an error starting a game twice")
+	}
+
+	started, err := getGame(ctx, game.ID)
+	if err != nil {
+		t.Errorf("getGame(ctx) err want %v got %s ", nil, err)
+	}
+
+	if started.Lobby {
+		t.Errorf("startGame(ctx) expected game out of the lobby")
+	}
+
+	if len(started.Master.Records) != boardSize {
+		t.Errorf("startGame(ctx) records want %d got %d", boardSize, len(started.Master.Records))
+	}
+
+	if i, _ := started.FindRecord(c1.Phrase); i == -1 {
+		t.Errorf("startGame(ctx) expected voted candidate in the game")
+	}
+
+	if len(started.Boards) != 2 {
+		t.Errorf("startGame(ctx) boards want %d got %d", 2, len(started.Boards))
+	}
+
+	if err := a.DeleteGame(ctx, started); err != nil {
 		t.Errorf("Agent.DeleteGame() err want %v got %s ", nil, err)
 	}
 }
@@ -0,0 +1,370 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Level is how important a log entry is.
+type Level int
+
+// The levels map onto the severities Cloud Logging understands.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarning
+	LevelError
+	// LevelOff turns logging off entirely.
+	LevelOff
+)
+
+var levelNames = map[Level]string{
+	LevelDebug:   "DEBUG",
+	LevelInfo:    "INFO",
+	LevelWarning: "WARNING",
+	LevelError:   "ERROR",
+	LevelOff:     "OFF",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel turns the name of a level, in any case, into a Level.
+func ParseLevel(s string) (Level, error) {
+	for k, v := range levelNames {
+		if strings.EqualFold(s, v) {
+			return k, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level: %s", s)
+}
+
+var logger = NewLogger(os.Stderr, LevelInfo, true)
+
+// Logger writes levelled log entries, as JSON that Cloud Logging reads
+// the severity, message and trace out of, or as plain lines for reading in a
+// terminal.
+type Logger struct {
+	mu    sync.Mutex
+	out   io.Writer
+	level Level
+	json  bool
+	now   func() time.Time
+}
+
+// NewLogger returns a logger that writes entries at level or above to out.
+func NewLogger(out io.Writer, level Level, json bool) *Logger {
+	l := &Logger{}
+	l.out = out
+	l.level = level
+	l.json = json
+	l.now = time.Now
+	return l
+}
+
+// configureLogger sets up logging from the LOGLEVEL and LOGFORMAT
+// environment variables.
+func configureLogger() error {
+	level := LevelInfo
+	if s := os.Getenv("LOGLEVEL"); s != "" {
+		l, err := ParseLevel(s)
+		if err != nil {
+			return err
+		}
+		level = l
+	}
+
+	format := os.Getenv("LOGFORMAT")
+	if format != "" && format != "json" && format != "text" {
+		return fmt.Errorf("unknown log format: %s", format)
+	}
+
+	logger = NewLogger(os.Stderr, level, format != "text")
+	return nil
+}
+
+// Enabled reports whether entries at level are written.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// Log writes an entry with the request fields carried by ctx.
+func (l *Logger) Log(ctx context.Context, level Level, component, msg string) {
+	l.write(ctx, level, component, msg, nil)
+}
+
+func (l *Logger) write(ctx context.Context, level Level, component, msg string, req *httpRequest) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	e := entry{}
+	e.Severity = level.String()
+	e.Message = msg
+	e.Time = l.now().UTC().Format(time.RFC3339Nano)
+	e.Component = component
+	e.HTTPRequest = req
+	if f, ok := logFieldsFrom(ctx); ok {
+		e.logFields = f
+	}
+
+	line := ""
+	if l.json {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		line = string(b)
+	} else {
+		line = e.String()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintln(l.out, line)
+}
+
+// Debug writes an entry at LevelDebug.
+func (l *Logger) Debug(ctx context.Context, component, msg string) {
+	l.Log(ctx, LevelDebug, component, msg)
+}
+
+// Info writes an entry at LevelInfo.
+func (l *Logger) Info(ctx context.Context, component, msg string) {
+	l.Log(ctx, LevelInfo, component, msg)
+}
+
+// Warning writes an entry at LevelWarning.
+func (l *Logger) Warning(ctx context.Context, component, msg string) {
+	l.Log(ctx, LevelWarning, component, msg)
+}
+
+// Error writes an entry at LevelError.
+func (l *Logger) Error(ctx context.Context, component, msg string) {
+	l.Log(ctx, LevelError, component, msg)
+}
+
+// Fatal writes an entry at LevelError and exits.
+func (l *Logger) Fatal(ctx context.Context, component, msg string) {
+	l.write(ctx, LevelError, component, msg, nil)
+	os.Exit(1)
+}
+
+// entry is a log entry in the shape of Cloud Logging's structured logs. The
+// special fields are lifted out into the LogEntry, and the rest end up in
+// its jsonPayload.
+type entry struct {
+	Severity    string       `json:"severity"`
+	Message     string       `json:"message"`
+	Time        string       `json:"time"`
+	Component   string       `json:"component"`
+	HTTPRequest *httpRequest `json:"httpRequest,omitempty"`
+	logFields
+}
+
+// httpRequest is the request an access log entry is for, as Cloud Logging
+// expects to find it.
+type httpRequest struct {
+	RequestMethod string `json:"requestMethod"`
+	RequestURL    string `json:"requestUrl"`
+	Status        int    `json:"status"`
+	Latency       string `json:"latency"`
+	UserAgent     string `json:"userAgent,omitempty"`
+	RemoteIP      string `json:"remoteIp,omitempty"`
+}
+
+func (e entry) String() string {
+	fields := map[string]string{
+		"request": e.RequestID,
+		"game":    e.Game,
+		"board":   e.Board,
+		"user":    e.User,
+	}
+
+	keys := []string{}
+	for k, v := range fields {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("%s %-7s %-9s : %s", e.Time, e.Severity, strings.Title(e.Component), e.Message))
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf(" %s=%s", k, fields[k]))
+	}
+	return sb.String()
+}
+
+// logFields are the details of the request an entry was written for.
+type logFields struct {
+	RequestID string `json:"requestId,omitempty"`
+	Trace     string `json:"logging.googleapis.com/trace,omitempty"`
+	SpanID    string `json:"logging.googleapis.com/spanId,omitempty"`
+	Game      string `json:"game,omitempty"`
+	Board     string `json:"board,omitempty"`
+	User      string `json:"user,omitempty"`
+}
+
+type logFieldsKey struct{}
+
+func logFieldsFrom(ctx context.Context) (logFields, bool) {
+	if ctx == nil {
+		return logFields{}, false
+	}
+	f, ok := ctx.Value(logFieldsKey{}).(logFields)
+	return f, ok
+}
+
+// withLogFields returns a copy of ctx that tags entries with the game and
+// board, leaving what's already there if either is empty.
+func withLogFields(ctx context.Context, gid, bid string) context.Context {
+	f, _ := logFieldsFrom(ctx)
+	if gid != "" {
+		f.Game = gid
+	}
+	if bid != "" {
+		f.Board = bid
+	}
+	return context.WithValue(ctx, logFieldsKey{}, f)
+}
+
+// logRequest gives a request an ID, sent back in the X-Request-Id header,
+// and tags everything logged while handling it with that ID, the user and
+// the game and board it's for. The returned writer should be used for the
+// response and the func called once it's written, to log how it went.
+func logRequest(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request, func()) {
+	start := time.Now()
+
+	f := logFields{}
+	f.RequestID = requestID(r)
+	f.Game = r.URL.Query().Get("g")
+	f.Board = r.URL.Query().Get("b")
+
+	if email, err := getPlayerEmail(r); err == nil {
+		f.User = email
+	}
+
+	if sc := trace.SpanContextFromContext(r.Context()); sc.IsSampled() {
+		f.SpanID = sc.SpanID().String()
+		if projectID != "" {
+			f.Trace = fmt.Sprintf("projects/%s/traces/%s", projectID, sc.TraceID())
+		}
+	} else if id := traceID(r); id != "" && projectID != "" {
+		f.Trace = fmt.Sprintf("projects/%s/traces/%s", projectID, id)
+	}
+
+	w.Header().Set("X-Request-Id", f.RequestID)
+	r = r.WithContext(context.WithValue(r.Context(), logFieldsKey{}, f))
+	rec := &responseRecorder{w, http.StatusOK, nil}
+
+	return rec, r, func() {
+		req := &httpRequest{}
+		req.RequestMethod = r.Method
+		req.RequestURL = r.URL.String()
+		req.Status = rec.status
+		req.Latency = fmt.Sprintf("%.3fs", time.Since(start).Seconds())
+		req.UserAgent = r.UserAgent()
+		req.RemoteIP = r.RemoteAddr
+
+		level := LevelInfo
+		msg := fmt.Sprintf("%s %s %d", r.Method, r.URL.Path, rec.status)
+		switch {
+		case rec.status >= http.StatusInternalServerError:
+			level = LevelError
+		case rec.status >= http.StatusBadRequest:
+			level = LevelWarning
+		}
+		if rec.status >= http.StatusBadRequest {
+			msg = fmt.Sprintf("%s: %s", msg, rec.body)
+		}
+
+		logger.write(r.Context(), level, "webserver", msg, req)
+	}
+}
+
+// responseRecorder remembers the status of a response, and the body of one
+// that failed, so they can be logged.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   []byte
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	rr.status = status
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.status >= http.StatusBadRequest {
+		rr.body = append(rr.body, b...)
+	}
+	return rr.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the writer underneath.
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+// requestIDRegexp is what an ID given with a request must look like to be
+// trusted, anything else could be used to forge log entries or responses.
+var requestIDRegexp = regexp.MustCompile(`^[A-Za-z0-9-]{1,64}$`)
+
+// requestID uses the ID a proxy in front of the app gave the request, or
+// makes one up if there isn't one that looks safe.
+func requestID(r *http.Request) string {
+	if id := r.Header.Get("X-Request-Id"); requestIDRegexp.MatchString(id) {
+		return id
+	}
+
+	if id := traceID(r); requestIDRegexp.MatchString(id) {
+		return id
+	}
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// traceID pulls the trace out of the X-Cloud-Trace-Context header that App
+// Engine adds, which looks like TRACE_ID/SPAN_ID;o=TRACE_TRUE.
+func traceID(r *http.Request) string {
+	header := r.Header.Get("X-Cloud-Trace-Context")
+	if header == "" {
+		return ""
+	}
+	return strings.SplitN(header, "/", 2)[0]
+}
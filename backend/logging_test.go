@@ -0,0 +1,205 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testLogger(out *bytes.Buffer, level Level, json bool) *Logger {
+	l := NewLogger(out, level, json)
+	l.now = func() time.Time { return time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC) }
+	return l
+}
+
+func TestParseLevel(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Level
+		err  bool
+	}{
+		{"debug", LevelDebug, false},
+		{"INFO", LevelInfo, false},
+		{"Warning", LevelWarning, false},
+		{"error", LevelError, false},
+		{"off", LevelOff, false},
+		{"loud", LevelInfo, true},
+	}
+
+	for _, c := range cases {
+		got, err := ParseLevel(c.in)
+		if (err != nil) != c.err {
+			t.Errorf("ParseLevel(%s) err want %t got %v", c.in, c.err, err)
+		}
+		if got != c.want {
+			t.Errorf("ParseLevel(%s) want %s got %s", c.in, c.want, got)
+		}
+	}
+}
+
+func TestLoggerLevels(t *testing.T) {
+	out := &bytes.Buffer{}
+	l := testLogger(out, LevelWarning, true)
+
+	l.Debug(context.Background(), "cache", "debug")
+	l.Info(context.Background(), "cache", "info")
+	l.Warning(context.Background(), "cache", "warning")
+	l.Error(context.Background(), "cache", "error")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Logger levels want %d entries got %d: %s", 2, len(lines), out.String())
+	}
+
+	if !strings.Contains(lines[0], `"severity":"WARNING"`) {
+		t.Errorf("Logger.Warning() want severity %s got %s", "WARNING", lines[0])
+	}
+}
+
+func TestLoggerJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	l := testLogger(out, LevelDebug, true)
+
+	ctx := context.WithValue(context.Background(), logFieldsKey{}, logFields{RequestID: "abc", User: "test@example.com"})
+	ctx = withLogFields(ctx, "game1", "board1")
+	l.Info(ctx, "firestore", "Getting Games")
+
+	got := map[string]string{}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("Logger.Info() could not decode %s: %s", out.String(), err)
+	}
+
+	want := map[string]string{
+		"severity":  "INFO",
+		"message":   "Getting Games",
+		"time":      "2020-06-01T12:00:00Z",
+		"component": "firestore",
+		"requestId": "abc",
+		"game":      "game1",
+		"board":     "board1",
+		"user":      "test@example.com",
+	}
+
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Logger.Info() %s want %s got %s", k, v, got[k])
+		}
+	}
+}
+
+func TestLoggerText(t *testing.T) {
+	out := &bytes.Buffer{}
+	l := testLogger(out, LevelDebug, false)
+
+	l.Info(withLogFields(context.Background(), "game1", ""), "cache", "Successfully saved game to cache")
+
+	want := "2020-06-01T12:00:00Z INFO    Cache     : Successfully saved game to cache game=game1\n"
+	if out.String() != want {
+		t.Errorf("Logger.Info() text want %q got %q", want, out.String())
+	}
+}
+
+func TestLogRequest(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger = testLogger(out, LevelDebug, true)
+	defer func() { logger = NewLogger(ioutil.Discard, LevelOff, true) }()
+
+	h := requestMiddleware(JSONHandler(func(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+		f, _ := logFieldsFrom(r.Context())
+		if f.RequestID != "req-1" || f.Game != "game1" || f.Board != "board1" {
+			t.Errorf("logRequest() fields want %s %s %s got %+v", "req-1", "game1", "board1", f)
+		}
+		return Game{}, fmt.Errorf("failed")
+	}, "none"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/board?g=game1&b=board1", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("X-Request-Id"); got != "req-1" {
+		t.Errorf("logRequest() X-Request-Id want %s got %s", "req-1", got)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	last := struct {
+		Severity    string      `json:"severity"`
+		Message     string      `json:"message"`
+		RequestID   string      `json:"requestId"`
+		HTTPRequest httpRequest `json:"httpRequest"`
+	}{}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil {
+		t.Fatalf("logRequest() could not decode %s: %s", lines[len(lines)-1], err)
+	}
+
+	if last.Severity != "ERROR" || last.HTTPRequest.Status != http.StatusInternalServerError {
+		t.Errorf("logRequest() want %s %d got %s %d", "ERROR", http.StatusInternalServerError, last.Severity, last.HTTPRequest.Status)
+	}
+
+	if last.RequestID != "req-1" {
+		t.Errorf("logRequest() requestId want %s got %s", "req-1", last.RequestID)
+	}
+
+	if !strings.Contains(last.Message, "failed") {
+		t.Errorf("logRequest() message want error body got %s", last.Message)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/game", nil)
+	req.Header.Set("X-Cloud-Trace-Context", "105445aa7843bc8bf206b12000100000/1;o=1")
+	if got := requestID(req); got != "105445aa7843bc8bf206b12000100000" {
+		t.Errorf("requestID() trace want %s got %s", "105445aa7843bc8bf206b12000100000", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/game", nil)
+	if got := requestID(req); len(got) != 16 {
+		t.Errorf("requestID() generated want %d chars got %s", 16, got)
+	}
+
+	cases := []struct {
+		header string
+		want   bool
+	}{
+		{"req-1", true},
+		{"0a1B-2c3D", true},
+		{strings.Repeat("a", 64), true},
+		{strings.Repeat("a", 65), false},
+		{"req 1", false},
+		{"req-1\nfake log entry", false},
+		{"<script>", false},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/game", nil)
+		req.Header.Set("X-Request-Id", c.header)
+		got := requestID(req)
+		if (got == c.header) != c.want {
+			t.Errorf("requestID(%q) kept want %t got %s", c.header, c.want, got)
+		}
+		if !requestIDRegexp.MatchString(got) {
+			t.Errorf("requestID(%q) want a safe ID got %q", c.header, got)
+		}
+	}
+}
@@ -16,8 +16,8 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"strconv"
@@ -26,21 +26,18 @@ import (
 
 	"github.com/gorilla/mux"
 	"golang.org/x/oauth2/google"
-	"google.golang.org/api/cloudresourcemanager/v1"
 	"google.golang.org/api/compute/v1"
-	"google.golang.org/api/idtoken"
 )
 
 var (
-	randseedfunc  = randomseed
-	a             Agent
-	cache         *Cache
-	cacheEnabled  = true
-	port          = ":8080"
-	noisy         = true
-	projectID     = ""
-	projectNumber = ""
-	ctx           = context.Background()
+	randseedfunc = randomseed
+	a            Agent
+	cache        *Cache
+	limiter      RateLimiter
+	cacheEnabled = true
+	port         = ":8080"
+	projectID    = ""
+	ctx          = context.Background()
 	// ErrNotAdmin is an error that indicates that the user is not an admin
 	ErrNotAdmin = fmt.Errorf("not an admin or game admin")
 	// ErrNotAdminOrPlayer is an error that indicates that the user is not an
@@ -51,44 +48,105 @@ var (
 func main() {
 	var err error
 
+	if err := configureLogger(); err != nil {
+		logger.Fatal(ctx, "webserver", err.Error())
+	}
+
+	shutdownTracing, err := configureTracing(ctx)
+	if err != nil {
+		logger.Fatal(ctx, "webserver", err.Error())
+	}
+	defer shutdownTracing(ctx)
+
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		if err := simulateCommand(os.Args[2:], os.Stdin, os.Stdout); err != nil {
+			logger.Fatal(ctx, "simulation", err.Error())
+		}
+		return
+	}
+
 	redisHost := os.Getenv("REDISHOST")
 	redisPort := os.Getenv("REDISPORT")
+	loadBlocklist(os.Getenv("PHRASEBLOCKLIST"))
 
 	projectID, err = getProjectID()
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal(ctx, "webserver", err.Error())
 	}
 
-	projectNumber, err = getProjectNumber(projectID)
+	auth, err := NewAuthenticator(os.Getenv("AUTH"))
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal(ctx, "webserver", err.Error())
 	}
+	authenticator = TokenAuthenticator{auth}
 
 	a, err = NewAgent(ctx, projectID)
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal(ctx, "webserver", err.Error())
 	}
 
 	cache, err = NewCache(redisHost, redisPort, cacheEnabled)
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal(ctx, "webserver", err.Error())
+	}
+
+	metrics.AddActiveGames(a.CountActiveGames)
+
+	if os.Getenv("RATELIMIT") != "off" {
+		limiter = NewRateLimiter(cache)
 	}
 
 	r := mux.NewRouter()
+	r.Use(requestMiddleware)
 	r.HandleFunc("/healthz", handleHealth)
+	r.Handle("/metrics", MetricsHandler())
 	r.Handle("/api/board", JSONHandler(boardGetHandle, "none"))
 	r.Handle("/api/board/delete", PrefetechHandler(boardDeleteHandle, http.MethodDelete, "none"))
 	r.Handle("/api/record", SimpleHandler(recordSelectHandle, "none"))
+	r.Handle("/api/board/select", JSONHandler(boardSelectHandle, "none"))
+	r.Handle("/api/board/select/batch", JSONHandler(boardSelectBatchHandle, "none"))
+	r.Handle("/api/board/sync", JSONHandler(boardSyncHandle, "none"))
+	r.Handle("/api/board/claim", PrefetechHandler(boardClaimHandle, http.MethodPost, "none"))
+	r.Handle("/api/board/confirm", PrefetechHandler(boardConfirmHandle, http.MethodPost, "game"))
 	r.Handle("/api/game", JSONHandler(gameGetHandle, "none"))
+	r.Handle("/api/game/scoreboard", JSONHandler(gameScoreboardHandle, "none"))
+	r.Handle("/api/game/suspicion", JSONHandler(gameSuspicionHandle, "game"))
 	r.Handle("/api/game/new", JSONHandler(gameNewHandle, "none"))
 	r.Handle("/api/game/list", JSONHandler(gameListHandle, "global"))
 	r.Handle("/api/player/game/list", JSONHandler(playerGameListHandle, "none"))
 	r.Handle("/api/game/admin/add", PrefetechHandler(gameAdminAddHandle, http.MethodPost, "game"))
 	r.Handle("/api/game/admin/remove", PrefetechHandler(gameAdminDeleteHandle, http.MethodDelete, "game"))
+	r.Handle("/api/game/round/new", PrefetechHandler(gameRoundNewHandle, http.MethodPost, "game"))
 	r.Handle("/api/game/deactivate", SimpleHandler(gameDeactivateHandle, "game"))
 	r.Handle("/api/game/purge", SimpleHandler(purgeHandle, "none"))
 	r.Handle("/api/game/phrase/update", SimpleHandler(gamePhraseUpdateHandle, "game"))
+	r.Handle("/api/game/phrase/add", PrefetechHandler(gamePhraseAddHandle, http.MethodPost, "game"))
+	r.Handle("/api/game/phrase/remove", PrefetechHandler(gamePhraseRemoveHandle, http.MethodDelete, "game"))
+	r.Handle("/api/phrase/list", JSONHandler(masterPhraseListHandle, "global"))
 	r.Handle("/api/phrase/update", SimpleHandler(masterPhraseUpdateHandle, "global"))
+	r.Handle("/api/phrase/add", PrefetechHandler(masterPhraseAddHandle, http.MethodPost, "global"))
+	r.Handle("/api/phrase/remove", PrefetechHandler(masterPhraseRemoveHandle, http.MethodDelete, "global"))
+	r.Handle("/api/game/suggestion/list", JSONHandler(gameSuggestionListHandle, "game"))
+	r.Handle("/api/game/suggestion/approve", PrefetechHandler(gameSuggestionApproveHandle, http.MethodPost, "game"))
+	r.Handle("/api/game/suggestion/reject", PrefetechHandler(gameSuggestionRejectHandle, http.MethodPost, "game"))
+	r.Handle("/api/suggestion/new", PrefetechHandler(suggestionNewHandle, http.MethodPost, "none"))
+	r.Handle("/api/suggestion/list", JSONHandler(suggestionListHandle, "global"))
+	r.Handle("/api/suggestion/approve", PrefetechHandler(suggestionApproveHandle, http.MethodPost, "global"))
+	r.Handle("/api/suggestion/reject", PrefetechHandler(suggestionRejectHandle, http.MethodPost, "global"))
+	r.Handle("/api/game/lobby", JSONHandler(lobbyGetHandle, "none"))
+	r.Handle("/api/game/lobby/candidate", PrefetechHandler(lobbyCandidateHandle, http.MethodPost, "none"))
+	r.Handle("/api/game/lobby/vote", PrefetechHandler(lobbyVoteHandle, http.MethodPost, "none"))
+	r.Handle("/api/game/start", PrefetechHandler(gameStartHandle, http.MethodPost, "game"))
+	r.Handle("/api/simulate", JSONHandler(simulateHandle, "global"))
+	r.Handle("/api/tournament", JSONHandler(tournamentGetHandle, "none"))
+	r.Handle("/api/tournament/new", JSONHandler(tournamentNewHandle, "global"))
+	r.Handle("/api/tournament/list", JSONHandler(tournamentListHandle, "global"))
+	r.Handle("/api/tournament/delete", PrefetechHandler(tournamentDeleteHandle, http.MethodDelete, "global"))
+	r.Handle("/api/tournament/standings", JSONHandler(tournamentStandingsHandle, "none"))
+	r.Handle("/api/tournament/game/add", PrefetechHandler(tournamentGameAddHandle, http.MethodPost, "global"))
+	r.Handle("/api/tournament/game/remove", PrefetechHandler(tournamentGameRemoveHandle, http.MethodDelete, "global"))
+	r.Handle("/api/tournament/game/open", PrefetechHandler(tournamentGameOpenHandle, http.MethodPost, "global"))
+	r.Handle("/api/tournament/game/close", PrefetechHandler(tournamentGameCloseHandle, http.MethodPost, "global"))
 	r.Handle("/api/game/isadmin", AdminHandler(isGameAdminHandle))
 	r.Handle("/api/player/identify", JSONHandler(iapUsernameGetHandle, "none"))
 	r.Handle("/api/player/isadmin", AdminHandler(isAdminHandle))
@@ -97,6 +155,13 @@ func main() {
 	r.Handle("/api/admin/list", JSONHandler(adminListHandle, "global"))
 	r.Handle("/api/message/receive", PrefetechHandler(messageAcknowledgeHandle, http.MethodPost, "none"))
 	r.Handle("/api/cache/clear", SimpleHandler(clearCacheHandle, "global"))
+	r.Handle("/api/token/new", JSONHandler(tokenNewHandle, "global"))
+	r.Handle("/api/token/list", JSONHandler(tokenListHandle, "global"))
+	r.Handle("/api/token/revoke", PrefetechHandler(tokenRevokeHandle, http.MethodPost, "global"))
+
+	if _, ok := auth.(DevAuthenticator); ok {
+		r.Handle("/api/player/login", JSONHandler(devLoginHandle, "none"))
+	}
 
 	routes := []string{"login", "invite", "game", "manage", "gamenew", "gamepicker", "admin"}
 
@@ -110,13 +175,29 @@ func main() {
 	r.PathPrefix("/").HandlerFunc(fs)
 
 	http.Handle("/", r)
-	weblog(fmt.Sprintf("Starting server on port %s\n", port))
+	logger.Info(ctx, "webserver", fmt.Sprintf("Starting server on port %s", port))
 	if err := http.ListenAndServe(port, nil); err != nil {
-		log.Fatal(err)
+		logger.Fatal(ctx, "webserver", err.Error())
 	}
 
 }
 
+// requestMiddleware traces, logs and counts every request the router
+// handles, before the handler for its route gets it.
+func requestMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w, r, traced := traceRequest(w, r)
+		defer traced()
+		w, r, logged := logRequest(w, r)
+		defer logged()
+		weblog(r.Context(), fmt.Sprintf("%s called", r.URL.Path))
+		w, done := observeRequest(w, r)
+		defer done()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 // ErrorEmitter is a http.Handler that emits an error for much better reporting
 type ErrorEmitter func(http.ResponseWriter, *http.Request) error
 
@@ -129,7 +210,13 @@ type AdminEmitter func(http.ResponseWriter, *http.Request) (int, error)
 // AdminHandler is a http.Handler checks the conditions of a isadmin request
 func AdminHandler(h AdminEmitter) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		weblog(fmt.Sprintf("%s called", r.URL.Path))
+		if err := RateLimitChecker(w, r); err != nil {
+			return
+		}
+
+		if err := IsAdminChecker(w, r, "none"); err != nil {
+			return
+		}
 
 		_, err := h(w, r)
 
@@ -150,7 +237,9 @@ func AdminHandler(h AdminEmitter) http.Handler {
 // SimpleHandler is a http.Handler thta does a simple request
 func SimpleHandler(h ErrorEmitter, adminlevel string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		weblog(fmt.Sprintf("%s called", r.URL.Path))
+		if err := RateLimitChecker(w, r); err != nil {
+			return
+		}
 
 		if err := IsAdminChecker(w, r, adminlevel); err != nil {
 			return
@@ -159,7 +248,7 @@ func SimpleHandler(h ErrorEmitter, adminlevel string) http.Handler {
 		err := h(w, r)
 
 		if err != nil {
-			writeError(w, err.Error())
+			writeErrorMsg(w, err)
 			return
 		}
 		writeSuccess(w, "ok")
@@ -168,6 +257,10 @@ func SimpleHandler(h ErrorEmitter, adminlevel string) http.Handler {
 
 // IsAdminChecker does the proper test for whether or not something is an admin
 func IsAdminChecker(w http.ResponseWriter, r *http.Request, adminlevel string) error {
+	if err := TokenScopeChecker(w, r, adminlevel); err != nil {
+		return err
+	}
+
 	switch adminlevel {
 	case "game":
 		queries, err := getQueries(r, "g")
@@ -178,19 +271,19 @@ func IsAdminChecker(w http.ResponseWriter, r *http.Request, adminlevel string) e
 
 		statusCode, err := isAdmin(r, queries["g"])
 		if err != nil {
-			weblog(fmt.Sprintf("IsAdminCheck failed in the handler"))
+			weblog(r.Context(), "IsAdminCheck failed in the handler")
 			writeResponse(w, statusCode, fmt.Sprintf("{\"error\":\"%s\"}", err))
 			return err
 		}
-		weblog(fmt.Sprintf("IsAdminCheck passed in the handler"))
+		weblog(r.Context(), "IsAdminCheck passed in the handler")
 	case "global":
 		statusCode, err := isGlobalAdmin(r)
 		if err != nil {
-			weblog(fmt.Sprintf("IsGlobalCheck failed in the handler"))
+			weblog(r.Context(), "IsGlobalCheck failed in the handler")
 			writeResponse(w, statusCode, fmt.Sprintf("{\"error\":\"%s\"}", err))
 			return err
 		}
-		weblog(fmt.Sprintf("IsGlobalCheck passed in the handler"))
+		weblog(r.Context(), "IsGlobalCheck passed in the handler")
 	default:
 		return nil
 	}
@@ -200,7 +293,9 @@ func IsAdminChecker(w http.ResponseWriter, r *http.Request, adminlevel string) e
 // JSONHandler is a http.Handler that handles returning json
 func JSONHandler(h JSONEmitter, adminlevel string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		weblog(fmt.Sprintf("%s called", r.URL.Path))
+		if err := RateLimitChecker(w, r); err != nil {
+			return
+		}
 
 		if err := IsAdminChecker(w, r, adminlevel); err != nil {
 			return
@@ -209,7 +304,7 @@ func JSONHandler(h JSONEmitter, adminlevel string) http.Handler {
 		jsonProducer, err := h(w, r)
 
 		if err != nil {
-			writeError(w, err.Error())
+			writeErrorMsg(w, err)
 			return
 		}
 		writeJSON(w, jsonProducer)
@@ -220,8 +315,6 @@ func JSONHandler(h JSONEmitter, adminlevel string) http.Handler {
 // PrefetechHandler is a http.Handler that handles preflight requests
 func PrefetechHandler(h ErrorEmitter, method string, adminlevel string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		weblog(fmt.Sprintf("%s called", r.URL.Path))
-
 		if r.Method == http.MethodOptions {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 			w.Header().Set("Access-Control-Allow-Methods", method)
@@ -235,16 +328,16 @@ func PrefetechHandler(h ErrorEmitter, method string, adminlevel string) http.Han
 			return
 		}
 
+		if err := RateLimitChecker(w, r); err != nil {
+			return
+		}
+
 		if err := IsAdminChecker(w, r, adminlevel); err != nil {
 			return
 		}
 
 		if err := h(w, r); err != nil {
-			if err == ErrNotAdminOrPlayer {
-				writeResponse(w, http.StatusForbidden, fmt.Sprintf("{\"error\":\"%s\"}", err))
-			}
-
-			writeError(w, err.Error())
+			writeErrorMsg(w, err)
 			return
 		}
 		writeSuccess(w, "ok")
@@ -252,7 +345,7 @@ func PrefetechHandler(h ErrorEmitter, method string, adminlevel string) http.Han
 }
 
 func clearCacheHandle(w http.ResponseWriter, r *http.Request) error {
-	return cache.Clear()
+	return cache.Clear(r.Context())
 }
 
 func isAdminHandle(w http.ResponseWriter, r *http.Request) (int, error) {
@@ -271,7 +364,7 @@ func isGameAdminHandle(w http.ResponseWriter, r *http.Request) (int, error) {
 }
 
 func purgeHandle(w http.ResponseWriter, r *http.Request) error {
-	return a.PurgeOldGames()
+	return a.PurgeOldGames(r.Context())
 }
 
 func gamePhraseUpdateHandle(w http.ResponseWriter, r *http.Request) error {
@@ -284,7 +377,11 @@ func gamePhraseUpdateHandle(w http.ResponseWriter, r *http.Request) error {
 	phrase.ID = queries["p"]
 	phrase.Text = queries["text"]
 
-	return updateGamePhrases(queries["g"], phrase)
+	if getOptionalQuery(r, "preserve") == "true" {
+		return updateGamePhraseText(r.Context(), queries["g"], phrase)
+	}
+
+	return updateGamePhrases(r.Context(), queries["g"], phrase)
 }
 
 func masterPhraseUpdateHandle(w http.ResponseWriter, r *http.Request) error {
@@ -297,7 +394,314 @@ func masterPhraseUpdateHandle(w http.ResponseWriter, r *http.Request) error {
 	phrase.ID = queries["p"]
 	phrase.Text = queries["text"]
 
-	return updateMasterPhrase(phrase)
+	return updateMasterPhrase(r.Context(), phrase)
+}
+
+func gamePhraseAddHandle(w http.ResponseWriter, r *http.Request) error {
+	queries, err := getQueries(r, "g", "text")
+	if err != nil {
+		return err
+	}
+
+	phrase := Phrase{}
+	phrase.ID = uniqueID()
+	phrase.Text = queries["text"]
+
+	reshuffle := getOptionalQuery(r, "mode") == "reshuffle"
+
+	return addGamePhrase(r.Context(), queries["g"], phrase, reshuffle)
+}
+
+func gamePhraseRemoveHandle(w http.ResponseWriter, r *http.Request) error {
+	queries, err := getQueries(r, "g", "p")
+	if err != nil {
+		return err
+	}
+
+	phrase := Phrase{}
+	phrase.ID = queries["p"]
+
+	reshuffle := getOptionalQuery(r, "mode") == "reshuffle"
+
+	return removeGamePhrase(r.Context(), queries["g"], phrase, reshuffle)
+}
+
+func masterPhraseListHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+	stats, err := a.GetPhraseStats(r.Context())
+	if err != nil {
+		return PhraseStats{}, err
+	}
+
+	if getOptionalQuery(r, "sort") == "difficulty" {
+		stats.SortByDifficulty()
+	}
+
+	return stats, nil
+}
+
+func masterPhraseAddHandle(w http.ResponseWriter, r *http.Request) error {
+	queries, err := getQueries(r, "text")
+	if err != nil {
+		return err
+	}
+
+	phrase := Phrase{}
+	phrase.ID = uniqueID()
+	phrase.Text = queries["text"]
+
+	return addMasterPhrase(r.Context(), phrase)
+}
+
+func masterPhraseRemoveHandle(w http.ResponseWriter, r *http.Request) error {
+	queries, err := getQueries(r, "p")
+	if err != nil {
+		return err
+	}
+
+	phrase := Phrase{}
+	phrase.ID = queries["p"]
+
+	return removeMasterPhrase(r.Context(), phrase)
+}
+
+func suggestionNewHandle(w http.ResponseWriter, r *http.Request) error {
+	email, err := getPlayerEmail(r)
+	if err != nil {
+		return err
+	}
+
+	queries, err := getQueries(r, "g", "text")
+	if err != nil {
+		return err
+	}
+
+	phrase := Phrase{}
+	phrase.ID = uniqueID()
+	phrase.Text = queries["text"]
+
+	master := getOptionalQuery(r, "target") == "master"
+
+	_, err = suggestPhrase(r.Context(), queries["g"], email, phrase, master)
+	return err
+}
+
+func suggestionListHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+	return a.GetPendingSuggestions(r.Context(), "", true)
+}
+
+func gameSuggestionListHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+	queries, err := getQueries(r, "g")
+	if err != nil {
+		return Suggestions{}, err
+	}
+
+	return a.GetPendingSuggestions(r.Context(), queries["g"], false)
+}
+
+func suggestionApproveHandle(w http.ResponseWriter, r *http.Request) error {
+	queries, err := getQueries(r, "s")
+	if err != nil {
+		return err
+	}
+
+	return reviewSuggestion(r.Context(), queries["s"], "", true, true)
+}
+
+func suggestionRejectHandle(w http.ResponseWriter, r *http.Request) error {
+	queries, err := getQueries(r, "s")
+	if err != nil {
+		return err
+	}
+
+	return reviewSuggestion(r.Context(), queries["s"], "", true, false)
+}
+
+func gameSuggestionApproveHandle(w http.ResponseWriter, r *http.Request) error {
+	queries, err := getQueries(r, "s", "g")
+	if err != nil {
+		return err
+	}
+
+	return reviewSuggestion(r.Context(), queries["s"], queries["g"], false, true)
+}
+
+func gameSuggestionRejectHandle(w http.ResponseWriter, r *http.Request) error {
+	queries, err := getQueries(r, "s", "g")
+	if err != nil {
+		return err
+	}
+
+	return reviewSuggestion(r.Context(), queries["s"], queries["g"], false, false)
+}
+
+func lobbyGetHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+	email, err := getPlayerEmail(r)
+	if err != nil {
+		return Candidates{}, err
+	}
+
+	queries, err := getQueries(r, "g", "name")
+	if err != nil {
+		return Candidates{}, err
+	}
+
+	p := Player{Name: queries["name"], Email: email}
+
+	return joinLobby(r.Context(), queries["g"], p)
+}
+
+func lobbyCandidateHandle(w http.ResponseWriter, r *http.Request) error {
+	email, err := getPlayerEmail(r)
+	if err != nil {
+		return err
+	}
+
+	queries, err := getQueries(r, "g", "text")
+	if err != nil {
+		return err
+	}
+
+	phrase := Phrase{}
+	phrase.ID = uniqueID()
+	phrase.Text = queries["text"]
+
+	_, err = addCandidate(r.Context(), queries["g"], email, phrase)
+	return err
+}
+
+func lobbyVoteHandle(w http.ResponseWriter, r *http.Request) error {
+	email, err := getPlayerEmail(r)
+	if err != nil {
+		return err
+	}
+
+	queries, err := getQueries(r, "g", "c")
+	if err != nil {
+		return err
+	}
+
+	up := getOptionalQuery(r, "vote") != "false"
+
+	return voteCandidate(r.Context(), queries["g"], queries["c"], email, up)
+}
+
+func gameStartHandle(w http.ResponseWriter, r *http.Request) error {
+	queries, err := getQueries(r, "g")
+	if err != nil {
+		return err
+	}
+
+	n := 0
+	if v := getOptionalQuery(r, "n"); v != "" {
+		n, err = strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("query parameter 'n' must be a positive number")
+		}
+	}
+
+	return startGame(r.Context(), queries["g"], n)
+}
+
+func simulateHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+	s := Simulation{}
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		return SimulationResult{}, fmt.Errorf("could not parse simulation: %s", err)
+	}
+
+	return s.Run()
+}
+
+func tournamentGetHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+	queries, err := getQueries(r, "t")
+	if err != nil {
+		return Tournament{}, err
+	}
+
+	return a.GetTournament(r.Context(), queries["t"])
+}
+
+func tournamentNewHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+	queries, err := getQueries(r, "name")
+	if err != nil {
+		return Tournament{}, err
+	}
+
+	return getNewTournament(r.Context(), queries["name"])
+}
+
+func tournamentListHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+	return a.GetTournaments(r.Context())
+}
+
+func tournamentStandingsHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+	email, err := getPlayerEmail(r)
+	if err != nil {
+		return Standings{}, err
+	}
+
+	queries, err := getQueries(r, "t")
+	if err != nil {
+		return Standings{}, err
+	}
+
+	standings, err := getTournamentStandings(r.Context(), queries["t"])
+	if err != nil {
+		return Standings{}, err
+	}
+
+	if _, err := isGlobalAdmin(r); err != nil {
+		if err != ErrNotAdmin {
+			return Standings{}, err
+		}
+		standings.Obscure(email)
+	}
+
+	return standings, nil
+}
+
+func tournamentGameAddHandle(w http.ResponseWriter, r *http.Request) error {
+	queries, err := getQueries(r, "t", "g")
+	if err != nil {
+		return err
+	}
+
+	return addTournamentGame(r.Context(), queries["t"], queries["g"])
+}
+
+func tournamentDeleteHandle(w http.ResponseWriter, r *http.Request) error {
+	queries, err := getQueries(r, "t")
+	if err != nil {
+		return err
+	}
+
+	return a.DeleteTournament(r.Context(), Tournament{ID: queries["t"]})
+}
+
+func tournamentGameRemoveHandle(w http.ResponseWriter, r *http.Request) error {
+	queries, err := getQueries(r, "t", "g")
+	if err != nil {
+		return err
+	}
+
+	return removeTournamentGame(r.Context(), queries["t"], queries["g"])
+}
+
+func tournamentGameOpenHandle(w http.ResponseWriter, r *http.Request) error {
+	queries, err := getQueries(r, "t", "g")
+	if err != nil {
+		return err
+	}
+
+	return setTournamentGameActive(r.Context(), queries["t"], queries["g"], true)
+}
+
+func tournamentGameCloseHandle(w http.ResponseWriter, r *http.Request) error {
+	queries, err := getQueries(r, "t", "g")
+	if err != nil {
+		return err
+	}
+
+	return setTournamentGameActive(r.Context(), queries["t"], queries["g"], false)
 }
 
 func iapUsernameGetHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
@@ -310,6 +714,55 @@ func iapUsernameGetHandle(w http.ResponseWriter, r *http.Request) (JSONProducer,
 	return Player{"", email}, nil
 }
 
+func tokenNewHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+	queries, err := getQueries(r, "kind", "scope")
+	if err != nil {
+		return IssuedToken{}, err
+	}
+
+	email, err := getPlayerEmail(r)
+	if err != nil {
+		return IssuedToken{}, err
+	}
+
+	name := getOptionalQuery(r, "name")
+	player := getOptionalQuery(r, "email")
+
+	return issueToken(r.Context(), queries["kind"], name, player, queries["scope"], email)
+}
+
+func tokenListHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+	return a.GetTokens(r.Context())
+}
+
+func tokenRevokeHandle(w http.ResponseWriter, r *http.Request) error {
+	queries, err := getQueries(r, "t")
+	if err != nil {
+		return err
+	}
+
+	return revokeToken(r.Context(), queries["t"])
+}
+
+// devLoginHandle switches who the requests from a browser are from, when
+// running with dev auth.
+func devLoginHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+	queries, err := getQueries(r, "email")
+	if err != nil {
+		return Player{}, err
+	}
+
+	cookie := &http.Cookie{}
+	cookie.Name = devCookie
+	cookie.Value = queries["email"]
+	cookie.Path = "/"
+	cookie.HttpOnly = true
+	cookie.SameSite = http.SameSiteLaxMode
+	http.SetCookie(w, cookie)
+
+	return Player{"", queries["email"]}, nil
+}
+
 func messageAcknowledgeHandle(w http.ResponseWriter, r *http.Request) error {
 
 	queries, err := getQueries(r, "m", "g")
@@ -322,7 +775,7 @@ func messageAcknowledgeHandle(w http.ResponseWriter, r *http.Request) error {
 	g.ID = queries["g"]
 	m.ID = queries["m"]
 
-	return a.AcknowledgeMessage(g, m)
+	return a.AcknowledgeMessage(r.Context(), g, m)
 }
 
 // TODO: Change to handle things passed from request.
@@ -332,7 +785,7 @@ func playerGameListHandle(w http.ResponseWriter, r *http.Request) (JSONProducer,
 	if err != nil {
 		return Games{}, err
 	}
-	return getGamesForKey(email, 10, time.Now())
+	return getGamesForKey(r.Context(), email, 10, time.Now())
 }
 
 func gameListHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
@@ -359,7 +812,7 @@ func gameListHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error
 
 	key := fmt.Sprintf("admin-list-%d-%d", limit, tokenint)
 
-	return getGamesForKey(key, limit, token)
+	return getGamesForKey(r.Context(), key, limit, token)
 }
 
 func boardGetHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
@@ -375,12 +828,12 @@ func boardGetHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error
 
 	p := Player{Name: queries["name"], Email: email}
 
-	g, err := getGame(queries["g"])
+	g, err := getGame(r.Context(), queries["g"])
 	if err != nil {
 		return Board{}, err
 	}
 
-	return getBoardForPlayer(p, g)
+	return getBoardForPlayer(r.Context(), p, g)
 }
 
 func boardDeleteHandle(w http.ResponseWriter, r *http.Request) error {
@@ -389,7 +842,7 @@ func boardDeleteHandle(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	board, err := getBoard(queries["b"], queries["g"])
+	board, err := getBoard(r.Context(), queries["b"], queries["g"])
 	if err != nil {
 		return err
 	}
@@ -399,15 +852,159 @@ func boardDeleteHandle(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	if _, err := isAdmin(r, queries["g"]); err != nil && err != ErrNotAdmin {
+	_, err = isAdmin(r, queries["g"])
+	if err != nil && err != ErrNotAdmin {
+		return err
+	}
+
+	if board.Player.Email != email && err == ErrNotAdmin {
+		return ErrNotAdminOrPlayer
+	}
+
+	return deleteBoard(r.Context(), queries["b"], queries["g"])
+}
+
+func boardSelectHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+	if r.Method != http.MethodPost {
+		return Board{}, fmt.Errorf("must use http method %s you had %s", http.MethodPost, r.Method)
+	}
+
+	queries, err := getQueries(r, "b", "g", "p", "selected")
+	if err != nil {
+		return Board{}, err
+	}
+
+	if queries["selected"] != "true" && queries["selected"] != "false" {
+		return Board{}, ValidationError{"selected": "must be true or false"}
+	}
+
+	board, err := getBoard(r.Context(), queries["b"], queries["g"])
+	if err != nil {
+		return Board{}, err
+	}
+
+	email, err := getPlayerEmail(r)
+	if err != nil {
+		return Board{}, err
+	}
+
+	if board.Player.Email != email {
+		return Board{}, ErrNotAdminOrPlayer
+	}
+
+	selected := queries["selected"] == "true"
+	return setSelection(r.Context(), queries["b"], queries["g"], queries["p"], selected, getOptionalQuery(r, "key"))
+}
+
+func boardSelectBatchHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+	if r.Method != http.MethodPost {
+		return Board{}, fmt.Errorf("must use http method %s you had %s", http.MethodPost, r.Method)
+	}
+
+	selections := []Selection{}
+	if err := json.NewDecoder(r.Body).Decode(&selections); err != nil {
+		return Board{}, ValidationError{"selections": fmt.Sprintf("could not parse selections: %s", err)}
+	}
+
+	// The body is the selections, so the board comes in the url.
+	queries := map[string]string{"b": getOptionalQuery(r, "b"), "g": getOptionalQuery(r, "g")}
+	for _, v := range []string{"b", "g"} {
+		if queries[v] == "" {
+			return Board{}, fmt.Errorf("query parameter '%s' is missing", v)
+		}
+	}
+
+	if len(selections) > boardSize {
+		return Board{}, ValidationError{"selections": fmt.Sprintf("can't have more than %d selections", boardSize)}
+	}
+
+	board, err := getBoard(r.Context(), queries["b"], queries["g"])
+	if err != nil {
+		return Board{}, err
+	}
+
+	email, err := getPlayerEmail(r)
+	if err != nil {
+		return Board{}, err
+	}
+
+	if board.Player.Email != email {
+		return Board{}, ErrNotAdminOrPlayer
+	}
+
+	return selectPhrases(r.Context(), board, selections)
+}
+
+func boardSyncHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+	if r.Method != http.MethodPost {
+		return SyncResult{}, fmt.Errorf("must use http method %s you had %s", http.MethodPost, r.Method)
+	}
+
+	queued := []Selection{}
+	if err := json.NewDecoder(r.Body).Decode(&queued); err != nil {
+		return SyncResult{}, ValidationError{"selections": fmt.Sprintf("could not parse selections: %s", err)}
+	}
+
+	// The board only remembers its latest activityLimit selections, so more
+	// than that couldn't be checked against what happened in the meantime.
+	if len(queued) > activityLimit {
+		return SyncResult{}, ValidationError{"selections": fmt.Sprintf("can't have more than %d selections", activityLimit)}
+	}
+
+	queries := map[string]string{"b": getOptionalQuery(r, "b"), "g": getOptionalQuery(r, "g")}
+	for _, v := range []string{"b", "g"} {
+		if queries[v] == "" {
+			return SyncResult{}, fmt.Errorf("query parameter '%s' is missing", v)
+		}
+	}
+
+	board, err := getBoard(r.Context(), queries["b"], queries["g"])
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	email, err := getPlayerEmail(r)
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	if board.Player.Email != email {
+		return SyncResult{}, ErrNotAdminOrPlayer
+	}
+
+	return syncSelections(r.Context(), queries["b"], queries["g"], queued)
+}
+
+func boardClaimHandle(w http.ResponseWriter, r *http.Request) error {
+	queries, err := getQueries(r, "b", "g")
+	if err != nil {
+		return err
+	}
+
+	board, err := getBoard(r.Context(), queries["b"], queries["g"])
+	if err != nil {
+		return err
+	}
+
+	email, err := getPlayerEmail(r)
+	if err != nil {
 		return err
 	}
 
-	if !(board.Player.Email == email) && err == ErrNotAdmin {
+	if board.Player.Email != email {
 		return ErrNotAdminOrPlayer
 	}
 
-	return deleteBoard(queries["b"], queries["g"])
+	return claimBingo(r.Context(), queries["b"], queries["g"])
+}
+
+func boardConfirmHandle(w http.ResponseWriter, r *http.Request) error {
+	queries, err := getQueries(r, "b", "g")
+	if err != nil {
+		return err
+	}
+
+	return confirmBingo(r.Context(), queries["b"], queries["g"])
 }
 
 func gameNewHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
@@ -423,7 +1020,82 @@ func gameNewHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error)
 
 	p := Player{Name: queries["pname"], Email: email}
 
-	return getNewGame(queries["name"], p)
+	free, err := getFreeSquares(r)
+	if err != nil {
+		return Game{}, err
+	}
+
+	opts := GameOptions{}
+	opts.Free = free
+	opts.Lobby = getOptionalQuery(r, "lobby") == "true"
+	opts.Balanced = getOptionalQuery(r, "balanced") == "true"
+	opts.OneAway = getOptionalQuery(r, "oneaway") == "true"
+	opts.Scoring = getOptionalQuery(r, "scoring") == "true"
+	opts.Claims = getOptionalQuery(r, "claims") == "true"
+	opts.Penalty = pointsPerRejection
+
+	if v := getOptionalQuery(r, "endafter"); v != "" {
+		opts.EndAfter, err = strconv.Atoi(v)
+		if err != nil || opts.EndAfter < 0 {
+			return Game{}, fmt.Errorf("query parameter 'endafter' must be a positive number")
+		}
+	}
+
+	if v := getOptionalQuery(r, "penalty"); v != "" {
+		opts.Penalty, err = strconv.Atoi(v)
+		if err != nil || opts.Penalty < 0 {
+			return Game{}, fmt.Errorf("query parameter 'penalty' must be a positive number")
+		}
+	}
+
+	return getNewGame(r.Context(), queries["name"], p, opts)
+}
+
+// getFreeSquares reads the free square options for a new game. With no 'free'
+// parameter the game gets the classic center square, 'free=none' means no
+// free square, and any other value is the text of the free square(s) placed
+// at the comma separated positions in 'freepos'.
+func getFreeSquares(r *http.Request) ([]Phrase, error) {
+	free := []Phrase{}
+	text := getOptionalQuery(r, "free")
+
+	switch text {
+	case "":
+		return DefaultFreeSquares(), nil
+	case "none":
+		return free, nil
+	}
+
+	// The text goes on every board like any other phrase, so it's held to
+	// the same rules.
+	p, err := validatePhrase(Phrase{Text: text}, []Phrase{})
+	if err != nil {
+		if verr, ok := err.(ValidationError); ok {
+			return free, ValidationError{"free": verr["text"]}
+		}
+		return free, err
+	}
+	text = p.Text
+
+	positions := getOptionalQuery(r, "freepos")
+	if positions == "" {
+		positions = strconv.Itoa(boardCenter)
+	}
+
+	seen := make(map[int]bool)
+	for _, v := range strings.Split(positions, ",") {
+		pos, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || pos < 0 || pos >= boardSize {
+			return free, fmt.Errorf("query parameter 'freepos' must be a list of squares from 0 to %d", boardSize-1)
+		}
+		if seen[pos] {
+			return free, fmt.Errorf("query parameter 'freepos' has square %d more than once", pos)
+		}
+		seen[pos] = true
+		free = append(free, NewFreePhrase(text, pos))
+	}
+
+	return free, nil
 }
 
 func gameGetHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
@@ -437,7 +1109,7 @@ func gameGetHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error)
 		return Game{}, err
 	}
 
-	game, err := getGame(queries["g"])
+	game, err := getGame(r.Context(), queries["g"])
 	if err != nil {
 		return Game{}, err
 	}
@@ -451,13 +1123,72 @@ func gameGetHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error)
 	return game, nil
 }
 
+func gameSuspicionHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+	queries, err := getQueries(r, "g")
+	if err != nil {
+		return Suspicions{}, err
+	}
+
+	game, err := getGame(r.Context(), queries["g"])
+	if err != nil {
+		return Suspicions{}, err
+	}
+
+	return game.Suspicions(), nil
+}
+
+func gameScoreboardHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+	email, err := getPlayerEmail(r)
+	if err != nil {
+		return Scoreboard{}, err
+	}
+
+	queries, err := getQueries(r, "g")
+	if err != nil {
+		return Scoreboard{}, err
+	}
+
+	game, err := getGame(r.Context(), queries["g"])
+	if err != nil {
+		return Scoreboard{}, err
+	}
+
+	if !game.Scoring {
+		return Scoreboard{}, fmt.Errorf("game id(%s) does not keep score", game.ID)
+	}
+
+	sb := game.Scoreboard()
+
+	if _, err := isAdmin(r, queries["g"]); err != nil {
+		if err != ErrNotAdmin {
+			return Scoreboard{}, err
+		}
+		if _, ok := game.Players.Find(email); !ok {
+			return Scoreboard{}, ErrNotAdminOrPlayer
+		}
+		sb.Obscure(email)
+	}
+	return sb, nil
+}
+
+func gameRoundNewHandle(w http.ResponseWriter, r *http.Request) error {
+	queries, err := getQueries(r, "g")
+	if err != nil {
+		return err
+	}
+
+	redeal := getOptionalQuery(r, "mode") == "redeal"
+
+	return newRound(r.Context(), queries["g"], redeal)
+}
+
 func gameDeactivateHandle(w http.ResponseWriter, r *http.Request) error {
 	queries, err := getQueries(r, "g")
 	if err != nil {
 		return err
 	}
 
-	return deactivateGame(queries["g"])
+	return deactivateGame(r.Context(), queries["g"])
 }
 
 func recordSelectHandle(w http.ResponseWriter, r *http.Request) error {
@@ -467,7 +1198,7 @@ func recordSelectHandle(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	selected := queries["selected"] == "true"
-	return recordSelect(queries["b"], queries["g"], queries["p"], selected)
+	return recordSelect(r.Context(), queries["b"], queries["g"], queries["p"], selected)
 }
 
 func gameAdminAddHandle(w http.ResponseWriter, r *http.Request) error {
@@ -477,7 +1208,7 @@ func gameAdminAddHandle(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	game, err := getGame(queries["g"])
+	game, err := getGame(r.Context(), queries["g"])
 	if err != nil {
 		return err
 	}
@@ -485,11 +1216,11 @@ func gameAdminAddHandle(w http.ResponseWriter, r *http.Request) error {
 	p.Email = queries["email"]
 	game.Admins.Add(p)
 
-	if err := cache.SaveGame(game); err != nil {
+	if err := cache.SaveGame(r.Context(), game); err != nil {
 		return err
 	}
 
-	return a.SaveGame(game)
+	return a.SaveGame(r.Context(), game)
 }
 
 func gameAdminDeleteHandle(w http.ResponseWriter, r *http.Request) error {
@@ -498,18 +1229,18 @@ func gameAdminDeleteHandle(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	game, err := getGame(queries["g"])
+	game, err := getGame(r.Context(), queries["g"])
 	if err != nil {
 		return err
 	}
 	p := Player{"", queries["email"]}
 	game.Admins.Remove(p)
 
-	if err := cache.SaveGame(game); err != nil {
+	if err := cache.SaveGame(r.Context(), game); err != nil {
 		return err
 	}
 
-	return a.SaveGame(game)
+	return a.SaveGame(r.Context(), game)
 }
 
 func adminAddHandle(w http.ResponseWriter, r *http.Request) error {
@@ -521,7 +1252,7 @@ func adminAddHandle(w http.ResponseWriter, r *http.Request) error {
 
 	p := Player{"", queries["email"]}
 
-	return a.AddAdmin(p)
+	return a.AddAdmin(r.Context(), p)
 }
 
 func adminDeleteHandle(w http.ResponseWriter, r *http.Request) error {
@@ -532,11 +1263,11 @@ func adminDeleteHandle(w http.ResponseWriter, r *http.Request) error {
 
 	p := Player{"", queries["email"]}
 
-	return a.DeleteAdmin(p)
+	return a.DeleteAdmin(r.Context(), p)
 }
 
 func adminListHandle(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
-	return a.GetAdmins()
+	return a.GetAdmins(r.Context())
 }
 
 func handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -577,17 +1308,44 @@ func writeError(w http.ResponseWriter, msg string) {
 }
 
 func writeErrorMsg(w http.ResponseWriter, err error) {
+	if verr, ok := err.(ValidationError); ok {
+		writeValidationError(w, verr)
+		return
+	}
+
+	if _, ok := err.(AuthError); ok {
+		writeResponse(w, http.StatusUnauthorized, fmt.Sprintf("{\"error\":\"%s\"}", err))
+		return
+	}
+
+	if err == ErrNotAdmin || err == ErrNotAdminOrPlayer {
+		writeResponse(w, http.StatusForbidden, fmt.Sprintf("{\"error\":\"%s\"}", err))
+		return
+	}
+
 	s := fmt.Sprintf("{\"error\":\"%s\"}", err)
 	writeResponse(w, http.StatusInternalServerError, s)
 	return
 }
 
-func writeResponse(w http.ResponseWriter, code int, msg string) {
+func writeValidationError(w http.ResponseWriter, verr ValidationError) {
+	body := struct {
+		Error  string            `json:"error"`
+		Fields map[string]string `json:"fields"`
+	}{verr.Error(), verr}
 
-	if code != http.StatusOK {
-		weblog(fmt.Sprintf(msg))
+	bytes, err := json.Marshal(body)
+	if err != nil {
+		writeError(w, err.Error())
+		return
 	}
 
+	writeResponse(w, http.StatusBadRequest, string(bytes))
+	return
+}
+
+func writeResponse(w http.ResponseWriter, code int, msg string) {
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.Header().Add("Access-Control-Allow-Origin", "*")
 	w.WriteHeader(code)
@@ -657,6 +1415,16 @@ func getQueries(r *http.Request, queries ...string) (map[string]string, error) {
 	return results, nil
 }
 
+// getOptionalQuery returns the value of a query parameter that can be left
+// out, treating missing and undefined values as empty.
+func getOptionalQuery(r *http.Request, query string) string {
+	result := r.FormValue(query)
+	if result == "undefined" {
+		return ""
+	}
+	return result
+}
+
 func getProjectID() (string, error) {
 	credentials, err := google.FindDefaultCredentials(ctx, compute.ComputeScope)
 	if err != nil {
@@ -665,30 +1433,6 @@ func getProjectID() (string, error) {
 	return credentials.ProjectID, nil
 }
 
-func getProjectNumber(projectID string) (string, error) {
-	c, err := google.DefaultClient(ctx, cloudresourcemanager.CloudPlatformScope)
-	if err != nil {
-		return "", fmt.Errorf("could not get cloudresourcemanager client: %v", err)
-	}
-
-	svc, err := cloudresourcemanager.New(c)
-	if err != nil {
-		return "", fmt.Errorf("could not get cloudresourcemanager service: %v", err)
-	}
-
-	resp, err := svc.Projects.Get(projectID).Context(ctx).Do()
-	if err != nil {
-		return "", fmt.Errorf("could not get project data: %v", err)
-	}
-
-	return strconv.Itoa(int(resp.ProjectNumber)), nil
-}
-
-func validateJWT(iapJWT, projectNumber, projectID string) (*idtoken.Payload, error) {
-	aud := fmt.Sprintf("/projects/%s/apps/%s", projectNumber, projectID)
-	return idtoken.Validate(ctx, iapJWT, aud)
-}
-
 func isAdmin(r *http.Request, gid string) (int, error) {
 
 	isGameAdm, err := isGameAdmin(r, gid)
@@ -713,11 +1457,25 @@ func isAdmin(r *http.Request, gid string) (int, error) {
 }
 
 func isGlobalAdmin(r *http.Request) (int, error) {
+	// A token acts as an admin only if its scope says so, whoever it's for.
+	// Service tokens aren't anyone in the admin list, so their scope is all
+	// that makes them an admin.
+	t, ok, err := requestToken(r)
+	if err != nil {
+		return authStatus(err), err
+	}
+	if ok && !t.Allows(ScopeGlobalAdmin) {
+		return http.StatusForbidden, ErrNotAdmin
+	}
+	if ok && t.Kind == tokenService {
+		return http.StatusOK, nil
+	}
+
 	email, err := getPlayerEmail(r)
 	if err != nil {
-		return http.StatusInternalServerError, err
+		return authStatus(err), err
 	}
-	result, err := a.IsAdmin(email)
+	result, err := a.IsAdmin(r.Context(), email)
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
@@ -728,12 +1486,20 @@ func isGlobalAdmin(r *http.Request) (int, error) {
 }
 
 func isGameAdmin(r *http.Request, gid string) (int, error) {
+	t, ok, err := requestToken(r)
+	if err != nil {
+		return authStatus(err), err
+	}
+	if ok && !t.Allows(ScopeGameAdmin) {
+		return http.StatusForbidden, ErrNotAdmin
+	}
+
 	email, err := getPlayerEmail(r)
 	if err != nil {
-		return http.StatusInternalServerError, err
+		return authStatus(err), err
 	}
 
-	game, err := getGame(gid)
+	game, err := getGame(r.Context(), gid)
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
@@ -747,46 +1513,17 @@ func isGameAdmin(r *http.Request, gid string) (int, error) {
 	return http.StatusForbidden, ErrNotAdmin
 }
 
-func getPlayerEmail(r *http.Request) (string, error) {
-	email, err := getValidatedEmail(r)
-	if err != nil {
-		return "", err
-	}
-
-	// If it's not behind IAP, it's developemnt
-	if email == "" {
-		username := os.Getenv("USER")
-		email = fmt.Sprintf("%s@google.com", username)
-
+// authStatus is the status to respond with when the user can't be worked
+// out.
+func authStatus(err error) int {
+	if _, ok := err.(AuthError); ok {
+		return http.StatusUnauthorized
 	}
-
-	return email, nil
+	return http.StatusInternalServerError
 }
 
-func getValidatedEmail(r *http.Request) (string, error) {
-	arr := r.Header.Get("X-Goog-Authenticated-User-Email")
-
-	email := getEmailFromString(arr)
-	if arr == "" {
-		return "", nil
-	}
-
-	jwt := r.Header.Get("X-Goog-IAP-JWT-Assertion")
-
-	payload, err := validateJWT(jwt, projectNumber, projectID)
-	if err != nil {
-		return "", fmt.Errorf("could not validate IAP JWT: %s", err)
-	}
-
-	var ok bool
-	email, ok = payload.Claims["email"].(string)
-
-	if !ok {
-		return "", fmt.Errorf("could not get email from IAP JWT")
-	}
-
-	return email, nil
-
+func getPlayerEmail(r *http.Request) (string, error) {
+	return authenticator.Email(r)
 }
 
 func getEmailFromString(arr string) string {
@@ -803,8 +1540,6 @@ func getEmailFromString(arr string) string {
 	return email
 }
 
-func weblog(msg string) {
-	if noisy {
-		log.Printf("Webserver : %s", msg)
-	}
+func weblog(ctx context.Context, msg string) {
+	logger.Debug(ctx, "webserver", msg)
 }
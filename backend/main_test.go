@@ -17,6 +17,7 @@ package main
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httptest"
@@ -99,7 +100,7 @@ func TestMain(m *testing.M) {
 
 	agentTestSetup()
 	cacheTestSetup()
-	noisy = false
+	logger = NewLogger(ioutil.Discard, LevelOff, true)
 
 	// now it's running, we can run our unit tests
 	result = m.Run()
@@ -122,6 +123,96 @@ func TestGetUsername(t *testing.T) {
 	}
 }
 
+func TestGetFreeSquares(t *testing.T) {
+	cases := []struct {
+		in        string
+		positions []int
+		text      string
+		err       bool
+	}{
+		{"/api/game/new", []int{12}, "FREE", false},
+		{"/api/game/new?free=none", []int{}, "", false},
+		{"/api/game/new?free=Synergy", []int{12}, "Synergy", false},
+		{"/api/game/new?free=Synergy&freepos=0,4,20,24", []int{0, 4, 20, 24}, "Synergy", false},
+		{"/api/game/new?free=Synergy&freepos=25", []int{}, "", true},
+		{"/api/game/new?free=Synergy&freepos=1,1", []int{}, "", true},
+		{"/api/game/new?free=%3Cb%3ESynergy%3C%2Fb%3E", []int{}, "", true},
+		{"/api/game/new?free=" + strings.Repeat("a", maxPhraseLength+1), []int{}, "", true},
+	}
+
+	for _, c := range cases {
+		req, err := http.NewRequest("GET", c.in, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := getFreeSquares(req)
+		if (err != nil) != c.err {
+			t.Errorf("getFreeSquares(%s) err got %v, want err %t", c.in, err, c.err)
+			continue
+		}
+
+		if c.err {
+			continue
+		}
+
+		if len(got) != len(c.positions) {
+			t.Errorf("getFreeSquares(%s) count got %d, want %d", c.in, len(got), len(c.positions))
+			continue
+		}
+
+		for i, v := range got {
+			if v.DisplayOrder != c.positions[i] || v.Text != c.text || !v.Free {
+				t.Errorf("getFreeSquares(%s) got %+v, want text %s at %d", c.in, v, c.text, c.positions[i])
+			}
+		}
+	}
+}
+
+func TestValidationErrorResponse(t *testing.T) {
+	handler := SimpleHandler(func(w http.ResponseWriter, r *http.Request) error {
+		return ValidationError{"text": "must not be empty"}
+	}, "none")
+
+	req, err := http.NewRequest("GET", "/api/phrase/update", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+
+	expected := `{"error":"text must not be empty","fields":{"text":"must not be empty"}}`
+	if rr.Body.String() != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v",
+			rr.Body.String(), expected)
+	}
+}
+
+func TestNotAdminOrPlayerResponse(t *testing.T) {
+	handler := JSONHandler(func(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+		return Scoreboard{}, ErrNotAdminOrPlayer
+	}, "none")
+
+	req, err := http.NewRequest("GET", "/api/game/scoreboard", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusForbidden {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusForbidden)
+	}
+}
+
 // func TestGetQueries(t *testing.T) {
 // 	emptyreq, _ := http.NewRequest("GET", "/", nil)
 // 	req, _ := http.NewRequest("GET", "/?g=12345678&email=test@example.com", nil)
@@ -266,17 +357,17 @@ func TestGlobalAuthSuccessGetHandlers(t *testing.T) {
 	player1 := Player{"", fmt.Sprintf("%s@google.com", os.Getenv("USER"))}
 	player2 := Player{"", fmt.Sprintf("%s@google.com", "other")}
 
-	game1, err := getNewGame("Test Game 1", player1)
+	game1, err := getNewGame(ctx, "Test Game 1", player1, GameOptions{Free: DefaultFreeSquares()})
 	if err != nil {
 		t.Errorf("error in setting up games for testing %v", err)
 	}
 
-	game2, err := getNewGame("Test Game 2", player2)
+	game2, err := getNewGame(ctx, "Test Game 2", player2, GameOptions{Free: DefaultFreeSquares()})
 	if err != nil {
 		t.Errorf("error in setting up games for testing %v", err)
 	}
 
-	if err := a.AddAdmin(player1); err != nil {
+	if err := a.AddAdmin(ctx, player1); err != nil {
 		t.Errorf("error in setting up adin for testing %v", err)
 	}
 
@@ -0,0 +1,218 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// activeGamesInterval is how long the count of active games is kept before
+// Firestore is asked again, so scrapes don't cost a read per game every time.
+const activeGamesInterval = time.Minute
+
+// latencyBuckets are the upper bounds, in seconds, of the latency histograms.
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var metrics = NewMetrics()
+
+// Metrics keeps the counters, gauges and histograms the server exposes to
+// Prometheus, in a registry of their own.
+type Metrics struct {
+	registry          *prometheus.Registry
+	requests          *prometheus.CounterVec
+	requestDuration   *prometheus.HistogramVec
+	cacheRequests     *prometheus.CounterVec
+	firestoreDuration *prometheus.HistogramVec
+	firestoreErrors   *prometheus.CounterVec
+	boardsCreated     prometheus.Counter
+	selections        prometheus.Counter
+	bingos            prometheus.Counter
+}
+
+// NewMetrics returns metrics with every one the server reports already
+// registered, along with the usual Go runtime and process ones.
+func NewMetrics() *Metrics {
+	m := &Metrics{}
+	m.registry = prometheus.NewRegistry()
+
+	m.requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bingo_http_requests_total",
+		Help: "HTTP requests by route, method and status.",
+	}, []string{"route", "method", "status"})
+	m.requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bingo_http_request_duration_seconds",
+		Help:    "HTTP request latency by route and method.",
+		Buckets: latencyBuckets,
+	}, []string{"route", "method"})
+	m.cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bingo_cache_requests_total",
+		Help: "Cache lookups by operation and result.",
+	}, []string{"operation", "result"})
+	m.firestoreDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bingo_firestore_operation_duration_seconds",
+		Help:    "Firestore operation latency by Agent method.",
+		Buckets: latencyBuckets,
+	}, []string{"operation"})
+	m.firestoreErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bingo_firestore_errors_total",
+		Help: "Firestore operation errors by Agent method.",
+	}, []string{"operation"})
+	m.boardsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "bingo_boards_created_total",
+		Help: "Boards dealt to players.",
+	})
+	m.selections = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "bingo_selections_total",
+		Help: "Squares selected or unselected by players.",
+	})
+	m.bingos = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "bingo_bingos_total",
+		Help: "Bingos declared on boards.",
+	})
+
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.cacheRequests,
+		m.firestoreDuration,
+		m.firestoreErrors,
+		m.boardsCreated,
+		m.selections,
+		m.bingos,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// AddActiveGames adds the gauge of games that are still being played, counted
+// by the func given.
+func (m *Metrics) AddActiveGames(count func(context.Context) (int, error)) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "bingo_active_games",
+		Help: "Games that are still being played.",
+	}, activeGamesCollector(count)))
+}
+
+// statusRecorder remembers the status code written to a response so it can
+// be counted.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the writer underneath, for the
+// interfaces, like http.Flusher, that the recorder doesn't have.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// observeRequest starts timing a request. The returned writer should be used
+// for the response and the func called once it's written.
+func observeRequest(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, func()) {
+	start := time.Now()
+	rec := &statusRecorder{w, http.StatusOK}
+	route := requestRoute(r)
+
+	return rec, func() {
+		metrics.requests.WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).Inc()
+		metrics.requestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// requestRoute is the route a request matched, so that static files under a
+// prefix are counted together rather than a series for every path.
+func requestRoute(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return r.URL.Path
+}
+
+// observeCache counts the result of a cache lookup.
+func observeCache(operation string, err error) {
+	result := "hit"
+	if err == ErrCacheMiss {
+		result = "miss"
+	} else if err != nil {
+		result = "error"
+	}
+	metrics.cacheRequests.WithLabelValues(operation, result).Inc()
+}
+
+// activeGamesCollector keeps the count of active games, asking Firestore at
+// most once every activeGamesInterval.
+func activeGamesCollector(count func(context.Context) (int, error)) func() float64 {
+	var mu sync.Mutex
+	var last time.Time
+	var n int
+
+	return func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if time.Since(last) < activeGamesInterval {
+			return float64(n)
+		}
+
+		current, err := count(context.Background())
+		if err != nil {
+			logger.Error(context.Background(), "webserver", fmt.Sprintf("could not count active games: %s", err))
+			return float64(n)
+		}
+		last = time.Now()
+		n = current
+		return float64(n)
+	}
+}
+
+// MetricsHandler serves the metrics only to global admins and tokens with the
+// metrics scope, so they aren't open to anyone who finds the route. Scrapers
+// use a metrics token.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t, ok, err := requestToken(r)
+		if err != nil {
+			writeResponse(w, http.StatusUnauthorized, fmt.Sprintf("{\"error\":\"%s\"}", err))
+			return
+		}
+
+		if !ok || t.Scope != ScopeMetrics {
+			if err := IsAdminChecker(w, r, "global"); err != nil {
+				return
+			}
+		}
+
+		promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,169 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsRegistry(t *testing.T) {
+	m := NewMetrics()
+	m.requests.WithLabelValues("/api/game", "GET", "200").Inc()
+	m.bingos.Inc()
+	m.firestoreDuration.WithLabelValues("GetGame").Observe(0.02)
+
+	want := `
+# HELP bingo_bingos_total Bingos declared on boards.
+# TYPE bingo_bingos_total counter
+bingo_bingos_total 1
+# HELP bingo_http_requests_total HTTP requests by route, method and status.
+# TYPE bingo_http_requests_total counter
+bingo_http_requests_total{method="GET",route="/api/game",status="200"} 1
+`
+	if err := testutil.GatherAndCompare(m.registry, strings.NewReader(want), "bingo_bingos_total", "bingo_http_requests_total"); err != nil {
+		t.Errorf("NewMetrics() %s", err)
+	}
+
+	if got := testutil.CollectAndCount(m.firestoreDuration); got != 1 {
+		t.Errorf("NewMetrics() firestore series want %d got %d", 1, got)
+	}
+}
+
+func TestObserveRequest(t *testing.T) {
+	metrics = NewMetrics()
+
+	h := requestMiddleware(JSONHandler(func(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+		return Game{}, fmt.Errorf("failed")
+	}, "none"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got := testutil.ToFloat64(metrics.requests.WithLabelValues("/api/test", "GET", "500")); got != 1 {
+		t.Errorf("observeRequest() requests want %d got %v", 1, got)
+	}
+
+	if got := testutil.CollectAndCount(metrics.requestDuration); got != 1 {
+		t.Errorf("observeRequest() duration series want %d got %d", 1, got)
+	}
+}
+
+func TestRequestMiddlewareFlush(t *testing.T) {
+	metrics = NewMetrics()
+
+	h := requestMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("ResponseController.Flush() err want %v got %s", nil, err)
+		}
+	}))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/test", nil))
+
+	if !rr.Flushed {
+		t.Errorf("requestMiddleware() want the response flushed through the recorders")
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	metrics = NewMetrics()
+
+	store, raw, teardown := tokenTestSetup(t, ScopePlay)
+	defer teardown()
+
+	scraper, scraperRaw, err := NewToken(tokenService, "Scraper", "", ScopeMetrics, "admin@example.com")
+	if err != nil {
+		t.Fatalf("could not make token: %s", err)
+	}
+	store.tokens[scraper.ID] = scraper
+
+	cases := []struct {
+		label string
+		token string
+		want  int
+	}{
+		{"metrics token", scraperRaw, http.StatusOK},
+		{"play token", raw, http.StatusForbidden},
+		{"unknown token", "bingo_nope_nope", http.StatusUnauthorized},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		rr := httptest.NewRecorder()
+		MetricsHandler().ServeHTTP(rr, req)
+
+		if rr.Code != c.want {
+			t.Errorf("%s: MetricsHandler() status want %d got %d", c.label, c.want, rr.Code)
+		}
+	}
+}
+
+func TestObserveCache(t *testing.T) {
+	metrics = NewMetrics()
+
+	observeCache("GetGame", nil)
+	observeCache("GetGame", ErrCacheMiss)
+	observeCache("GetGame", fmt.Errorf("connection refused"))
+
+	for _, v := range []string{"hit", "miss", "error"} {
+		if got := testutil.ToFloat64(metrics.cacheRequests.WithLabelValues("GetGame", v)); got != 1 {
+			t.Errorf("observeCache() %s want %d got %v", v, 1, got)
+		}
+	}
+}
+
+func TestActiveGamesCollector(t *testing.T) {
+	calls := 0
+	collect := activeGamesCollector(func(context.Context) (int, error) {
+		calls++
+		return 7, nil
+	})
+
+	collect()
+	got := collect()
+
+	if calls != 1 {
+		t.Errorf("activeGamesCollector() want %d count got %d", 1, calls)
+	}
+
+	if got != 7 {
+		t.Errorf("activeGamesCollector() want gauge of %d got %v", 7, got)
+	}
+}
+
+func TestRequestRoute(t *testing.T) {
+	metrics = NewMetrics()
+
+	r := mux.NewRouter()
+	r.Use(requestMiddleware)
+	r.PathPrefix("/game").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, v := range []string{"/game/abc", "/game/def"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, v, nil))
+	}
+
+	if got := testutil.ToFloat64(metrics.requests.WithLabelValues("/game", "GET", "200")); got != 2 {
+		t.Errorf("requestRoute() want %d requests counted against %s got %v", 2, "/game", got)
+	}
+}
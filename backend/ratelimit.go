@@ -0,0 +1,273 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// maxBuckets is how many users and routes the local limiter tracks before it
+// forgets the one that made a request longest ago.
+const maxBuckets = 10000
+
+// ErrRateLimited is an error that indicates that the user has used up their
+// budget of requests for a route.
+var ErrRateLimited = fmt.Errorf("too many requests")
+
+// Budget is how many requests a user can make to a route in a period.
+type Budget struct {
+	Requests int
+	Per      time.Duration
+}
+
+// defaultBudget applies to every route without one of its own.
+var defaultBudget = Budget{120, time.Minute}
+
+// routeBudgets are the routes that cause more writes and messages, or more
+// work, than most.
+var routeBudgets = map[string]Budget{
+	"/api/record":               {60, time.Minute},
+	"/api/board/select":         {60, time.Minute},
+	"/api/board/select/batch":   {20, time.Minute},
+	"/api/board/sync":           {10, time.Minute},
+	"/api/board/claim":          {10, time.Minute},
+	"/api/board/delete":         {10, time.Minute},
+	"/api/game/new":             {10, time.Minute},
+	"/api/game/lobby/candidate": {10, time.Minute},
+	"/api/game/lobby/vote":      {60, time.Minute},
+	"/api/suggestion/new":       {10, time.Minute},
+	"/api/simulate":             {5, time.Minute},
+	"/api/message/receive":      {300, time.Minute},
+}
+
+// budgetFor returns the budget of a route.
+func budgetFor(route string) Budget {
+	if b, ok := routeBudgets[route]; ok {
+		return b
+	}
+	return defaultBudget
+}
+
+// RateLimiter decides if a key can make another request within a budget, and
+// if not, how long until it can.
+type RateLimiter interface {
+	Allow(key string, budget Budget) (bool, time.Duration, error)
+}
+
+// NewRateLimiter returns a limiter that shares its counts through Redis when
+// the cache is enabled, so every instance of the app sees the same budgets,
+// or keeps them in memory when it isn't.
+func NewRateLimiter(c *Cache) RateLimiter {
+	if c != nil && c.enabled {
+		return &RedisLimiter{pool: c.redisPool}
+	}
+	return NewLocalLimiter()
+}
+
+// RateLimitChecker holds a request to the budget of its route for the user
+// making it, writing a 429 with a Retry-After header if it's used up. Users
+// that can't be identified are limited by their address.
+func RateLimitChecker(w http.ResponseWriter, r *http.Request) error {
+	if limiter == nil {
+		return nil
+	}
+
+	key, err := getPlayerEmail(r)
+	if err != nil {
+		key = clientAddr(r, onGoogleCloud())
+	}
+
+	allowed, wait, err := limiter.Allow(r.URL.Path+":"+key, budgetFor(r.URL.Path))
+	if err != nil {
+		// Better to let requests through than to take the app down with the
+		// limiter.
+		logger.Error(r.Context(), "webserver", fmt.Sprintf("could not check rate limit: %s", err))
+		return nil
+	}
+
+	if !allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter(wait)))
+		writeResponse(w, http.StatusTooManyRequests, fmt.Sprintf("{\"error\":\"%s\"}", ErrRateLimited))
+		return ErrRateLimited
+	}
+
+	return nil
+}
+
+// clientAddr is the address a request came from, without the port, which
+// changes with every connection. Behind Google's front end every request
+// comes from the proxy, so it's the address the front end saw instead: App
+// Engine passes it in X-Appengine-User-Ip, and Cloud Run appends it to
+// X-Forwarded-For. The earlier hops come from the client and can be anything.
+func clientAddr(r *http.Request, proxied bool) string {
+	if proxied {
+		if ip := strings.TrimSpace(r.Header.Get("X-Appengine-User-Ip")); ip != "" {
+			return ip
+		}
+
+		hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+		if hop := strings.TrimSpace(hops[len(hops)-1]); hop != "" {
+			return hop
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// onGoogleCloud tells if the app is running on App Engine or Cloud Run.
+func onGoogleCloud() bool {
+	return os.Getenv("GAE_ENV") != "" || os.Getenv("K_SERVICE") != ""
+}
+
+// retryAfter rounds a wait up to the whole seconds the Retry-After header
+// needs.
+func retryAfter(wait time.Duration) int {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+type tokenBucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// LocalLimiter is a token bucket per key, kept in memory. A bucket holds a
+// budget's worth of requests and refills at the rate of the budget. Buckets
+// are kept in the order they were last used, so that past max the one used
+// longest ago, which is the likeliest to be full again, can be dropped.
+type LocalLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	order   *list.List
+	max     int
+	now     func() time.Time
+}
+
+// NewLocalLimiter returns an initialized in memory limiter.
+func NewLocalLimiter() *LocalLimiter {
+	l := &LocalLimiter{}
+	l.buckets = make(map[string]*list.Element)
+	l.order = list.New()
+	l.max = maxBuckets
+	l.now = time.Now
+	return l
+}
+
+// Allow takes a token from the key's bucket if there is one.
+func (l *LocalLimiter) Allow(key string, budget Budget) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	capacity := float64(budget.Requests)
+	rate := capacity / budget.Per.Seconds()
+
+	b := l.bucket(key, capacity, now)
+
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	return false, wait, nil
+}
+
+// bucket returns the key's bucket, marked as the most recently used, making a
+// full one if there isn't one, and forgetting the least recently used if that
+// takes the limiter past max.
+func (l *LocalLimiter) bucket(key string, capacity float64, now time.Time) *tokenBucket {
+	if e, ok := l.buckets[key]; ok {
+		l.order.MoveToFront(e)
+		return e.Value.(*tokenBucket)
+	}
+
+	b := &tokenBucket{key, capacity, now}
+	l.buckets[key] = l.order.PushFront(b)
+
+	for l.order.Len() > l.max {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.buckets, oldest.Value.(*tokenBucket).key)
+	}
+
+	return b
+}
+
+// RedisLimiter counts requests per key in fixed windows kept in Redis.
+type RedisLimiter struct {
+	pool RedisPool
+}
+
+// Allow counts the request against the key's current window.
+func (l *RedisLimiter) Allow(key string, budget Budget) (bool, time.Duration, error) {
+	conn := l.pool.Get()
+	defer conn.Close()
+
+	k := "ratelimit:" + key
+	ms := int64(budget.Per / time.Millisecond)
+
+	count, err := redis.Int(conn.Do("INCR", k))
+	if err != nil {
+		return true, 0, fmt.Errorf("could not count request: %s", err)
+	}
+
+	if count == 1 {
+		if _, err := conn.Do("PEXPIRE", k, ms); err != nil {
+			return true, 0, fmt.Errorf("could not set rate limit window: %s", err)
+		}
+	}
+
+	if count <= budget.Requests {
+		return true, 0, nil
+	}
+
+	ttl, err := redis.Int64(conn.Do("PTTL", k))
+	if err != nil {
+		return false, budget.Per, fmt.Errorf("could not get rate limit window: %s", err)
+	}
+
+	// A window without an expiry would never reset, so start it over.
+	if ttl < 0 {
+		if _, err := conn.Do("PEXPIRE", k, ms); err != nil {
+			return false, budget.Per, fmt.Errorf("could not set rate limit window: %s", err)
+		}
+		ttl = ms
+	}
+
+	return false, time.Duration(ttl) * time.Millisecond, nil
+}
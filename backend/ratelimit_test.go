@@ -0,0 +1,221 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+type countingPool struct {
+	counts map[string]int64
+	ttls   map[string]int64
+}
+
+func (p *countingPool) Get() redis.Conn {
+	return countingConnection{p}
+}
+
+type countingConnection struct {
+	pool *countingPool
+}
+
+func (c countingConnection) Close() error { return nil }
+func (c countingConnection) Err() error   { return nil }
+func (c countingConnection) Flush() error { return nil }
+
+func (c countingConnection) Send(commandName string, args ...interface{}) error { return nil }
+func (c countingConnection) Receive() (interface{}, error)                      { return nil, nil }
+
+func (c countingConnection) Do(commandName string, args ...interface{}) (interface{}, error) {
+	key := args[0].(string)
+	switch commandName {
+	case "INCR":
+		c.pool.counts[key]++
+		return c.pool.counts[key], nil
+	case "PEXPIRE":
+		c.pool.ttls[key] = args[1].(int64)
+		return int64(1), nil
+	case "PTTL":
+		if ttl, ok := c.pool.ttls[key]; ok {
+			return ttl, nil
+		}
+		return int64(-1), nil
+	}
+	return nil, nil
+}
+
+func TestLocalLimiter(t *testing.T) {
+	now := time.Now()
+	l := NewLocalLimiter()
+	l.now = func() time.Time { return now }
+	budget := Budget{2, time.Minute}
+
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := l.Allow("test@example.com", budget); !ok {
+			t.Errorf("LocalLimiter.Allow() request %d want allowed", i)
+		}
+	}
+
+	ok, wait, _ := l.Allow("test@example.com", budget)
+	if ok || wait != 30*time.Second {
+		t.Errorf("LocalLimiter.Allow() over budget want %t %s got %t %s", false, 30*time.Second, ok, wait)
+	}
+
+	if ok, _, _ := l.Allow("other@example.com", budget); !ok {
+		t.Errorf("LocalLimiter.Allow() other key want allowed")
+	}
+
+	now = now.Add(30 * time.Second)
+	if ok, _, _ := l.Allow("test@example.com", budget); !ok {
+		t.Errorf("LocalLimiter.Allow() after refill want allowed")
+	}
+}
+
+func TestLocalLimiterMax(t *testing.T) {
+	now := time.Now()
+	l := NewLocalLimiter()
+	l.now = func() time.Time { return now }
+	l.max = 2
+	budget := Budget{1, time.Minute}
+
+	l.Allow("one@example.com", budget)
+	l.Allow("two@example.com", budget)
+	l.Allow("one@example.com", budget)
+	l.Allow("three@example.com", budget)
+
+	if len(l.buckets) != 2 || l.order.Len() != 2 {
+		t.Errorf("LocalLimiter.Allow() past max want %d buckets got %d", 2, len(l.buckets))
+	}
+
+	if _, ok := l.buckets["two@example.com"]; ok {
+		t.Errorf("LocalLimiter.Allow() past max want least recently used bucket forgotten")
+	}
+
+	if ok, _, _ := l.Allow("one@example.com", budget); ok {
+		t.Errorf("LocalLimiter.Allow() past max want recently used bucket kept")
+	}
+}
+
+func TestRedisLimiter(t *testing.T) {
+	pool := &countingPool{make(map[string]int64), make(map[string]int64)}
+	l := &RedisLimiter{pool}
+	budget := Budget{2, time.Minute}
+
+	for i := 0; i < 2; i++ {
+		if ok, _, err := l.Allow("test@example.com", budget); !ok || err != nil {
+			t.Errorf("RedisLimiter.Allow() request %d want allowed got %t %v", i, ok, err)
+		}
+	}
+
+	if pool.ttls["ratelimit:test@example.com"] != 60000 {
+		t.Errorf("RedisLimiter.Allow() window want %d got %d", 60000, pool.ttls["ratelimit:test@example.com"])
+	}
+
+	ok, wait, err := l.Allow("test@example.com", budget)
+	if ok || wait != time.Minute || err != nil {
+		t.Errorf("RedisLimiter.Allow() over budget want %t %s got %t %s %v", false, time.Minute, ok, wait, err)
+	}
+}
+
+func TestRateLimitChecker(t *testing.T) {
+	limiter = NewLocalLimiter()
+	defer func() { limiter = nil }()
+
+	budget := budgetFor("/api/simulate")
+	for i := 0; i < budget.Requests; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/simulate", nil)
+		rr := httptest.NewRecorder()
+		if err := RateLimitChecker(rr, req); err != nil {
+			t.Errorf("RateLimitChecker() request %d err want %v got %s", i, nil, err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/simulate", nil)
+	rr := httptest.NewRecorder()
+	if err := RateLimitChecker(rr, req); err != ErrRateLimited {
+		t.Errorf("RateLimitChecker() err want %v got %v", ErrRateLimited, err)
+	}
+
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("RateLimitChecker() status want %d got %d", http.StatusTooManyRequests, rr.Code)
+	}
+
+	if got := rr.Header().Get("Retry-After"); got != "12" {
+		t.Errorf("RateLimitChecker() Retry-After want %s got %s", "12", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/game", nil)
+	rr = httptest.NewRecorder()
+	if err := RateLimitChecker(rr, req); err != nil {
+		t.Errorf("RateLimitChecker() other route err want %v got %s", nil, err)
+	}
+}
+
+func TestClientAddr(t *testing.T) {
+	cases := []struct {
+		remote    string
+		forward   string
+		appengine string
+		proxied   bool
+		want      string
+	}{
+		{"192.0.2.1:1234", "", "", false, "192.0.2.1"},
+		{"[2001:db8::1]:1234", "", "", false, "2001:db8::1"},
+		{"192.0.2.1", "", "", false, "192.0.2.1"},
+		{"192.0.2.1:1234", "198.51.100.7", "198.51.100.8", false, "192.0.2.1"},
+		{"192.0.2.1:1234", "", "198.51.100.7", true, "198.51.100.7"},
+		{"192.0.2.1:1234", "203.0.113.66, 198.51.100.7", "", true, "198.51.100.7"},
+		{"192.0.2.1:1234", "203.0.113.66, 198.51.100.7", "192.0.2.9", true, "192.0.2.9"},
+		{"192.0.2.1:1234", "", "", true, "192.0.2.1"},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/game", nil)
+		req.RemoteAddr = c.remote
+		if c.forward != "" {
+			req.Header.Set("X-Forwarded-For", c.forward)
+		}
+		if c.appengine != "" {
+			req.Header.Set("X-Appengine-User-Ip", c.appengine)
+		}
+
+		if got := clientAddr(req, c.proxied); got != c.want {
+			t.Errorf("clientAddr(%s, %s, %s, %t) want %s got %s", c.remote, c.forward, c.appengine, c.proxied, c.want, got)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	cases := []struct {
+		in   time.Duration
+		want int
+	}{
+		{0, 1},
+		{300 * time.Millisecond, 1},
+		{1500 * time.Millisecond, 2},
+		{time.Minute, 60},
+	}
+
+	for _, c := range cases {
+		if got := retryAfter(c.in); got != c.want {
+			t.Errorf("retryAfter(%s) want %d got %d", c.in, c.want, got)
+		}
+	}
+}
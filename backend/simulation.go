@@ -0,0 +1,348 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultSimulationTrials   = 1000
+	defaultSimulationPlayers  = 10
+	defaultSimulationDuration = 60
+	// maxSimulationBoards caps trials times players, and
+	// maxSimulationDuration the minutes in a meeting, so one request can't tie
+	// up the server.
+	maxSimulationBoards   = 10000
+	maxSimulationDuration = 24 * 60
+	// simulationBoardWidth is the squares to a side of a board, which deals
+	// boardSize phrases.
+	simulationBoardWidth = 5
+)
+
+// winPatterns are the ways a board can win a simulated game. A line is
+// checked with Board.Bingo, the others are the squares, by display order,
+// that all have to be selected.
+var winPatterns = map[string][]int{
+	"line":     nil,
+	"corners":  {0, 4, 20, 24},
+	"x":        {0, 4, 6, 8, 12, 16, 18, 20, 24},
+	"blackout": {0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24},
+}
+
+// SimulationPhrase is a phrase in a simulated game, with the odds of it
+// happening at some point during the meeting.
+type SimulationPhrase struct {
+	Text        string  `json:"text"`
+	Probability float64 `json:"probability"`
+	Free        bool    `json:"free"`
+	Position    int     `json:"position"`
+}
+
+// Simulation describes the games to play out: the phrases, the patterns that
+// win, how many players there are, how many games to play and how long, in
+// minutes, each meeting lasts. Size is the squares to a side of the boards,
+// which for now can only be 5. Simulations with the same seed play out the
+// same way.
+type Simulation struct {
+	Phrases  []SimulationPhrase `json:"phrases"`
+	Patterns []string           `json:"patterns"`
+	Size     int                `json:"size"`
+	Players  int                `json:"players"`
+	Trials   int                `json:"trials"`
+	Duration int                `json:"duration"`
+	Seed     int64              `json:"seed"`
+}
+
+// SimulationResult is the distribution of the time, in minutes, to the first
+// bingo across the simulated games. Histogram counts the first bingos that
+// happened in each minute of the meeting.
+type SimulationResult struct {
+	Trials      int                `json:"trials"`
+	Bingos      int                `json:"bingos"`
+	Rate        float64            `json:"rate"`
+	Mean        float64            `json:"mean"`
+	Percentiles map[string]float64 `json:"percentiles"`
+	Histogram   []int              `json:"histogram"`
+}
+
+// JSON marshalls the content of a simulation result to json.
+func (s SimulationResult) JSON() (string, error) {
+	bytes, err := json.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal json for response: %s", err)
+	}
+
+	return string(bytes), nil
+}
+
+// Normalize fills in the defaults and checks the simulation can be run.
+func (s *Simulation) Normalize() error {
+	errs := ValidationError{}
+
+	if s.Players == 0 {
+		s.Players = defaultSimulationPlayers
+	}
+	if s.Trials == 0 {
+		s.Trials = defaultSimulationTrials
+	}
+	if s.Duration == 0 {
+		s.Duration = defaultSimulationDuration
+	}
+	if s.Size == 0 {
+		s.Size = simulationBoardWidth
+	}
+	if s.Seed == 0 {
+		s.Seed = time.Now().UnixNano()
+	}
+	if len(s.Patterns) == 0 {
+		s.Patterns = []string{"line"}
+	}
+
+	if s.Size != simulationBoardWidth {
+		errs["size"] = fmt.Sprintf("boards can only be %dx%d", simulationBoardWidth, simulationBoardWidth)
+	}
+
+	if len(s.Phrases) < boardSize {
+		errs["phrases"] = fmt.Sprintf("must have at least %d phrases", boardSize)
+	}
+
+	positions := make(map[int]bool)
+	for _, v := range s.Phrases {
+		if v.Probability < 0 || v.Probability > 1 {
+			errs["phrases"] = "probabilities must be from 0 to 1"
+		}
+		if !v.Free {
+			continue
+		}
+		if v.Position < 0 || v.Position >= boardSize || positions[v.Position] {
+			errs["phrases"] = fmt.Sprintf("free squares need their own position from 0 to %d", boardSize-1)
+		}
+		positions[v.Position] = true
+	}
+
+	for _, v := range s.Patterns {
+		if _, ok := winPatterns[v]; !ok {
+			errs["patterns"] = fmt.Sprintf("'%s' is not one of line, corners, x or blackout", v)
+		}
+	}
+
+	if s.Players < 0 {
+		errs["players"] = "must be positive"
+	}
+	if s.Trials < 0 {
+		errs["trials"] = "must be positive"
+	}
+	if s.Duration < 0 || s.Duration > maxSimulationDuration {
+		errs["duration"] = fmt.Sprintf("must be from 1 to %d minutes", maxSimulationDuration)
+	}
+	// Each is checked on its own first, so multiplying them can't overflow.
+	if s.Players > maxSimulationBoards || s.Trials > maxSimulationBoards ||
+		(len(errs) == 0 && s.Players*s.Trials > maxSimulationBoards) {
+		errs["trials"] = fmt.Sprintf("trials times players must be %d or fewer", maxSimulationBoards)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// Run plays out the simulated games. Each phrase happens with its probability
+// at a random point in the meeting, every player marks it as soon as it does,
+// and the game ends at the first board that wins.
+func (s Simulation) Run() (SimulationResult, error) {
+	if err := s.Normalize(); err != nil {
+		return SimulationResult{}, err
+	}
+
+	phrases := []Phrase{}
+	odds := make(map[string]float64)
+	for i, v := range s.Phrases {
+		p := Phrase{}
+		p.ID = strconv.Itoa(i)
+		p.Text = v.Text
+		p.Free = v.Free
+		p.DisplayOrder = v.Position
+		phrases = append(phrases, p)
+		odds[p.ID] = v.Probability
+	}
+
+	// The simulation has its own source, so it doesn't disturb the dealing
+	// of boards in real games.
+	rng := rand.New(rand.NewSource(s.Seed))
+
+	times := []float64{}
+	for i := 0; i < s.Trials; i++ {
+		if t, ok := s.trial(rng, phrases, odds); ok {
+			times = append(times, t)
+		}
+	}
+
+	return newSimulationResult(s.Trials, s.Duration, times), nil
+}
+
+type simulationEvent struct {
+	id   string
+	time float64
+}
+
+func (s Simulation) trial(rng *rand.Rand, phrases []Phrase, odds map[string]float64) (float64, bool) {
+	boards := []Board{}
+	for i := 0; i < s.Players; i++ {
+		b := InitBoard()
+		b.quiet = true
+		b.load(append([]Phrase{}, phrases...), rng.Shuffle)
+		boards = append(boards, b)
+	}
+
+	events := []simulationEvent{}
+	for _, v := range phrases {
+		if !v.Free && rng.Float64() < odds[v.ID] {
+			events = append(events, simulationEvent{v.ID, rng.Float64() * float64(s.Duration)})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].time < events[j].time })
+
+	for _, e := range events {
+		for i := range boards {
+			if _, ok := boards[i].Phrases[e.id]; !ok {
+				continue
+			}
+			boards[i].Select(Phrase{ID: e.id, Selected: true})
+			if s.wins(&boards[i]) {
+				return e.time, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+func (s Simulation) wins(b *Board) bool {
+	selected := make(map[int]bool)
+	for _, v := range b.Phrases {
+		if v.Selected {
+			selected[v.DisplayOrder] = true
+		}
+	}
+
+	for _, pattern := range s.Patterns {
+		squares := winPatterns[pattern]
+		if squares == nil {
+			if b.Bingo() {
+				return true
+			}
+			continue
+		}
+
+		complete := true
+		for _, i := range squares {
+			if !selected[i] {
+				complete = false
+				break
+			}
+		}
+		if complete {
+			return true
+		}
+	}
+	return false
+}
+
+func newSimulationResult(trials, duration int, times []float64) SimulationResult {
+	r := SimulationResult{}
+	r.Trials = trials
+	r.Bingos = len(times)
+	r.Percentiles = make(map[string]float64)
+	r.Histogram = make([]int, duration)
+
+	if trials > 0 {
+		r.Rate = float64(r.Bingos) / float64(trials)
+	}
+
+	if len(times) == 0 {
+		return r
+	}
+
+	sort.Float64s(times)
+	total := 0.0
+	for _, v := range times {
+		total += v
+		minute := int(v)
+		if minute >= duration {
+			minute = duration - 1
+		}
+		r.Histogram[minute]++
+	}
+	r.Mean = total / float64(len(times))
+
+	for _, p := range []int{10, 25, 50, 75, 90} {
+		i := int(math.Ceil(float64(p)/100*float64(len(times)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		r.Percentiles[strconv.Itoa(p)] = times[i]
+	}
+
+	return r
+}
+
+// simulateCommand runs a simulation from the command line, reading its
+// description as json from the file named in args, or stdin if there isn't
+// one, and writing the result as json.
+func simulateCommand(args []string, in io.Reader, out io.Writer) error {
+	if len(args) > 0 && args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("could not open simulation file: %s", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	content, err := ioutil.ReadAll(in)
+	if err != nil {
+		return fmt.Errorf("could not read simulation: %s", err)
+	}
+
+	s := Simulation{}
+	if err := json.Unmarshal(content, &s); err != nil {
+		return fmt.Errorf("could not parse simulation: %s", err)
+	}
+
+	result, err := s.Run()
+	if err != nil {
+		return err
+	}
+
+	bytes, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal simulation result: %s", err)
+	}
+
+	_, err = fmt.Fprintln(out, string(bytes))
+	return err
+}
@@ -0,0 +1,184 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func getSimulationPhrases(count int, probability float64) []SimulationPhrase {
+	phrases := []SimulationPhrase{}
+	for i := 0; i < count; i++ {
+		phrases = append(phrases, SimulationPhrase{Text: fmt.Sprintf("Phrase %d", i), Probability: probability})
+	}
+	return phrases
+}
+
+func TestSimulationNormalize(t *testing.T) {
+	cases := []struct {
+		label string
+		in    Simulation
+		field string
+	}{
+		{"defaults", Simulation{Phrases: getSimulationPhrases(25, 0.5)}, ""},
+		{"too few phrases", Simulation{Phrases: getSimulationPhrases(24, 0.5)}, "phrases"},
+		{"probability", Simulation{Phrases: getSimulationPhrases(25, 1.5)}, "phrases"},
+		{"pattern", Simulation{Phrases: getSimulationPhrases(25, 0.5), Patterns: []string{"zigzag"}}, "patterns"},
+		{"too many boards", Simulation{Phrases: getSimulationPhrases(25, 0.5), Players: 1000, Trials: 1000}, "trials"},
+		{"negative players", Simulation{Phrases: getSimulationPhrases(25, 0.5), Players: -1}, "players"},
+		{"negative trials", Simulation{Phrases: getSimulationPhrases(25, 0.5), Trials: -1}, "trials"},
+		{"negative duration", Simulation{Phrases: getSimulationPhrases(25, 0.5), Duration: -1}, "duration"},
+		{"long duration", Simulation{Phrases: getSimulationPhrases(25, 0.5), Duration: maxSimulationDuration + 1}, "duration"},
+		{"overflowing boards", Simulation{Phrases: getSimulationPhrases(25, 0.5), Players: math.MaxInt / 2, Trials: 4}, "trials"},
+		{"size", Simulation{Phrases: getSimulationPhrases(25, 0.5), Size: 5}, ""},
+		{"other size", Simulation{Phrases: getSimulationPhrases(25, 0.5), Size: 4}, "size"},
+	}
+
+	for _, c := range cases {
+		err := c.in.Normalize()
+		if c.field == "" {
+			if err != nil {
+				t.Errorf("%s: Simulation.Normalize() err want %v got %s", c.label, nil, err)
+			}
+			continue
+		}
+
+		verr, ok := err.(ValidationError)
+		if !ok || verr[c.field] == "" {
+			t.Errorf("%s: Simulation.Normalize() want error on %s got %v", c.label, c.field, err)
+		}
+	}
+
+	s := Simulation{Phrases: getSimulationPhrases(25, 0.5)}
+	s.Normalize()
+	if s.Players != defaultSimulationPlayers || s.Trials != defaultSimulationTrials || s.Duration != defaultSimulationDuration {
+		t.Errorf("Simulation.Normalize() did not fill in defaults: %+v", s)
+	}
+}
+
+func TestSimulationRun(t *testing.T) {
+	cases := []struct {
+		label    string
+		odds     float64
+		free     bool
+		patterns []string
+		want     float64
+	}{
+		{"always", 1, false, []string{"line"}, 1},
+		{"never", 0, false, []string{"line"}, 0},
+		{"never with free square", 0, true, []string{"line"}, 0},
+		{"always blackout", 1, true, []string{"blackout"}, 1},
+		{"always corners", 1, false, []string{"corners", "x"}, 1},
+	}
+
+	for _, c := range cases {
+		s := Simulation{}
+		s.Phrases = getSimulationPhrases(30, c.odds)
+		if c.free {
+			s.Phrases[0] = SimulationPhrase{Text: "FREE", Free: true, Position: boardCenter}
+		}
+		s.Patterns = c.patterns
+		s.Players = 3
+		s.Trials = 20
+		s.Duration = 30
+
+		got, err := s.Run()
+		if err != nil {
+			t.Errorf("%s: Simulation.Run() err want %v got %s", c.label, nil, err)
+			continue
+		}
+
+		if got.Rate != c.want {
+			t.Errorf("%s: Simulation.Run() rate want %f got %f", c.label, c.want, got.Rate)
+		}
+
+		if len(got.Histogram) != s.Duration {
+			t.Errorf("%s: Simulation.Run() histogram want %d minutes got %d", c.label, s.Duration, len(got.Histogram))
+		}
+
+		if got.Bingos > 0 && (got.Mean <= 0 || got.Mean > float64(s.Duration)) {
+			t.Errorf("%s: Simulation.Run() mean out of range got %f", c.label, got.Mean)
+		}
+	}
+}
+
+func TestSimulationRunSeed(t *testing.T) {
+	s := Simulation{}
+	s.Phrases = getSimulationPhrases(30, 0.5)
+	s.Players = 3
+	s.Trials = 50
+	s.Seed = 42
+
+	first, err := s.Run()
+	if err != nil {
+		t.Fatalf("Simulation.Run() err want %v got %s", nil, err)
+	}
+
+	second, _ := s.Run()
+	if first.Bingos != second.Bingos || first.Mean != second.Mean {
+		t.Errorf("Simulation.Run() same seed want %d %f got %d %f", first.Bingos, first.Mean, second.Bingos, second.Mean)
+	}
+}
+
+func TestNewSimulationResult(t *testing.T) {
+	got := newSimulationResult(5, 10, []float64{9.5, 1.5, 3.2, 5.0})
+
+	if got.Rate != 0.8 {
+		t.Errorf("newSimulationResult() rate want %f got %f", 0.8, got.Rate)
+	}
+
+	if got.Mean != 4.8 {
+		t.Errorf("newSimulationResult() mean want %f got %f", 4.8, got.Mean)
+	}
+
+	if got.Percentiles["50"] != 3.2 {
+		t.Errorf("newSimulationResult() median want %f got %f", 3.2, got.Percentiles["50"])
+	}
+
+	if got.Histogram[1] != 1 || got.Histogram[9] != 1 {
+		t.Errorf("newSimulationResult() histogram got %v", got.Histogram)
+	}
+}
+
+func TestSimulateCommand(t *testing.T) {
+	s := Simulation{Phrases: getSimulationPhrases(25, 1), Players: 2, Trials: 5, Duration: 10}
+	in, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("json.Marshal() err want %v got %s", nil, err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := simulateCommand([]string{}, bytes.NewReader(in), out); err != nil {
+		t.Errorf("simulateCommand() err want %v got %s", nil, err)
+	}
+
+	got := SimulationResult{}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Errorf("simulateCommand() output is not json: %s", err)
+	}
+
+	if got.Trials != 5 || got.Bingos != 5 {
+		t.Errorf("simulateCommand() want %d/%d bingos got %d/%d", 5, 5, got.Bingos, got.Trials)
+	}
+
+	if err := simulateCommand([]string{}, strings.NewReader("not json"), out); err == nil {
+		t.Errorf("simulateCommand() expected an error parsing bad input")
+	}
+}
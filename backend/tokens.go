@@ -0,0 +1,419 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// tokenPrefix marks bearer tokens as API tokens, rather than ID tokens.
+	tokenPrefix = "bingo_"
+
+	// serviceDomain is the domain of the emails service tokens act as.
+	serviceDomain = "service.bingo"
+
+	// tokenInterval is how long a checked token is trusted before it's looked
+	// up again. A token revoked on another instance keeps working there for
+	// up to this long.
+	tokenInterval = 30 * time.Second
+
+	tokenPersonal = "personal"
+	tokenService  = "service"
+)
+
+// The scopes a token can have, each allowing everything the ones before it
+// do.
+const (
+	ScopeRead        = "read"
+	ScopePlay        = "play"
+	ScopeGameAdmin   = "game-admin"
+	ScopeGlobalAdmin = "global-admin"
+)
+
+// ScopeMetrics only lets a token read the metrics, so a scraper doesn't need
+// to be a global admin. It's outside the ranks of the other scopes: global
+// admin tokens can read the metrics too, but nothing else can.
+const ScopeMetrics = "metrics"
+
+var scopeRanks = map[string]int{
+	ScopeRead:        1,
+	ScopePlay:        2,
+	ScopeGameAdmin:   3,
+	ScopeGlobalAdmin: 4,
+}
+
+// routeScopes are the routes open to every player that a token needs less,
+// or more, than the play scope for.
+var routeScopes = map[string]string{
+	"/api/board":                ScopeRead,
+	"/api/game":                 ScopeRead,
+	"/api/game/scoreboard":      ScopeRead,
+	"/api/game/isadmin":         ScopeRead,
+	"/api/player/game/list":     ScopeRead,
+	"/api/player/identify":      ScopeRead,
+	"/api/player/isadmin":       ScopeRead,
+	"/api/tournament":           ScopeRead,
+	"/api/tournament/standings": ScopeRead,
+	"/api/game/new":             ScopeGameAdmin,
+	"/api/game/purge":           ScopeGlobalAdmin,
+}
+
+// ErrTokenScope is an error that indicates that a token isn't allowed to do
+// what it was used for.
+var ErrTokenScope = fmt.Errorf("token does not have the scope for this request")
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Token is an API token for scripts and bots. Personal tokens act as the
+// player they were made for, service tokens as a player of their own.
+type Token struct {
+	ID        string    `json:"id" firestore:"id"`
+	Name      string    `json:"name" firestore:"name"`
+	Kind      string    `json:"kind" firestore:"kind"`
+	Email     string    `json:"email" firestore:"email"`
+	Scope     string    `json:"scope" firestore:"scope"`
+	Hash      string    `json:"-" firestore:"hash"`
+	Created   time.Time `json:"created" firestore:"created"`
+	CreatedBy string    `json:"created_by" firestore:"created_by"`
+	Revoked   bool      `json:"revoked" firestore:"revoked"`
+}
+
+// NewToken makes a token and the secret to hand over for it. Only a hash of
+// the secret is kept.
+func NewToken(kind, name, email, scope, createdBy string) (Token, string, error) {
+	verr := ValidationError{}
+	t := Token{}
+
+	switch kind {
+	case tokenPersonal:
+		if email == "" {
+			verr["email"] = "a personal token needs the email of the player it's for"
+		}
+	case tokenService:
+		email = serviceEmail(name)
+		if email == "" {
+			verr["name"] = "a service token needs a name"
+		}
+	default:
+		verr["kind"] = fmt.Sprintf("kind must be %s or %s", tokenPersonal, tokenService)
+	}
+
+	if _, ok := scopeRanks[scope]; !ok && scope != ScopeMetrics {
+		verr["scope"] = fmt.Sprintf("scope must be one of %s, %s, %s, %s or %s", ScopeRead, ScopePlay, ScopeGameAdmin, ScopeGlobalAdmin, ScopeMetrics)
+	}
+
+	if len(verr) > 0 {
+		return t, "", verr
+	}
+
+	id, err := randomBytes(6)
+	if err != nil {
+		return t, "", fmt.Errorf("could not make token id: %s", err)
+	}
+
+	secret, err := randomBytes(32)
+	if err != nil {
+		return t, "", fmt.Errorf("could not make token secret: %s", err)
+	}
+
+	t.ID = hex.EncodeToString(id)
+	t.Name = name
+	t.Kind = kind
+	t.Email = email
+	t.Scope = scope
+	t.Created = time.Now()
+	t.CreatedBy = createdBy
+
+	raw := tokenPrefix + t.ID + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	t.Hash = hashToken(raw)
+
+	return t, raw, nil
+}
+
+// Allows reports whether the token's scope covers scope.
+func (t Token) Allows(scope string) bool {
+	if scope == ScopeMetrics {
+		return t.Scope == ScopeMetrics || t.Scope == ScopeGlobalAdmin
+	}
+	return scopeRanks[t.Scope] >= scopeRanks[scope]
+}
+
+// JSON marshalls the content of a token to json.
+func (t Token) JSON() (string, error) {
+	bytes, err := json.Marshal(t)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal json for response: %s", err)
+	}
+
+	return string(bytes), nil
+}
+
+// Tokens is a slice of Token structs
+type Tokens []Token
+
+// JSON marshalls the content of a slice of tokens to json.
+func (ts Tokens) JSON() (string, error) {
+	bytes, err := json.Marshal(ts)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal json for response: %s", err)
+	}
+
+	return string(bytes), nil
+}
+
+// IssuedToken is a new token along with its secret, which is only ever
+// shown this once.
+type IssuedToken struct {
+	Token  Token  `json:"token"`
+	Secret string `json:"secret"`
+}
+
+// JSON marshalls the content of an issued token to json.
+func (i IssuedToken) JSON() (string, error) {
+	bytes, err := json.Marshal(i)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal json for response: %s", err)
+	}
+
+	return string(bytes), nil
+}
+
+// serviceEmail is the email a service token acts as. Tokens with the same
+// name act as the same player, so a bot's token can be replaced without it
+// losing the games it runs.
+func serviceEmail(name string) string {
+	slug := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if slug == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s@%s", slug, serviceDomain)
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// tokenID pulls the ID out of a raw token, which looks like bingo_ID_SECRET.
+func tokenID(raw string) (string, bool) {
+	if !strings.HasPrefix(raw, tokenPrefix) {
+		return "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(raw, tokenPrefix), "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", false
+	}
+	return parts[0], true
+}
+
+type cachedToken struct {
+	token   Token
+	checked time.Time
+}
+
+// TokenChecker looks up the tokens requests carry, and remembers them for a
+// little while, as a request asks who it's from several times over.
+type TokenChecker struct {
+	mu     sync.Mutex
+	tokens map[string]cachedToken
+	get    func(ctx context.Context, id string) (Token, error)
+	now    func() time.Time
+}
+
+// NewTokenChecker returns a checker that looks tokens up with get.
+func NewTokenChecker(get func(ctx context.Context, id string) (Token, error)) *TokenChecker {
+	c := &TokenChecker{}
+	c.tokens = make(map[string]cachedToken)
+	c.get = get
+	c.now = time.Now
+	return c
+}
+
+var tokens = NewTokenChecker(func(ctx context.Context, id string) (Token, error) {
+	return a.GetToken(ctx, id)
+})
+
+// Check returns the token for a raw token, if it's real and not revoked.
+func (c *TokenChecker) Check(ctx context.Context, raw string) (Token, error) {
+	hash := hashToken(raw)
+
+	c.mu.Lock()
+	cached, ok := c.tokens[hash]
+	c.mu.Unlock()
+
+	if ok && c.now().Sub(cached.checked) < tokenInterval {
+		return cached.token, nil
+	}
+
+	id, ok := tokenID(raw)
+	if !ok {
+		return Token{}, AuthError{"malformed API token"}
+	}
+
+	t, err := c.get(ctx, id)
+	if err != nil {
+		return Token{}, AuthError{"unknown API token"}
+	}
+
+	if subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hash)) != 1 {
+		return Token{}, AuthError{"unknown API token"}
+	}
+
+	if t.Revoked {
+		return Token{}, AuthError{"API token has been revoked"}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, v := range c.tokens {
+		if c.now().Sub(v.checked) >= tokenInterval {
+			delete(c.tokens, k)
+		}
+	}
+	c.tokens[hash] = cachedToken{t, c.now()}
+
+	return t, nil
+}
+
+// Forget drops a token, so this instance stops trusting it straight away.
+func (c *TokenChecker) Forget(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, v := range c.tokens {
+		if v.token.ID == id {
+			delete(c.tokens, k)
+		}
+	}
+}
+
+// requestToken returns the API token a request carries, if it has one.
+func requestToken(r *http.Request) (Token, bool, error) {
+	raw := bearerToken(r)
+	if !strings.HasPrefix(raw, tokenPrefix) {
+		return Token{}, false, nil
+	}
+
+	t, err := tokens.Check(r.Context(), raw)
+	if err != nil {
+		return Token{}, true, err
+	}
+	return t, true, nil
+}
+
+// TokenAuthenticator accepts API tokens as bearer tokens, and leaves every
+// other request to the authenticator it wraps.
+type TokenAuthenticator struct {
+	Next Authenticator
+}
+
+// Email returns the email the API token acts as.
+func (ta TokenAuthenticator) Email(r *http.Request) (string, error) {
+	t, ok, err := requestToken(r)
+	if !ok {
+		return ta.Next.Email(r)
+	}
+	if err != nil {
+		return "", err
+	}
+	return t.Email, nil
+}
+
+// requiredScope is the scope a token needs for a route at an admin level.
+func requiredScope(route, adminlevel string) string {
+	switch adminlevel {
+	case "global":
+		return ScopeGlobalAdmin
+	case "game":
+		return ScopeGameAdmin
+	}
+
+	if scope, ok := routeScopes[route]; ok {
+		return scope
+	}
+	return ScopePlay
+}
+
+// TokenScopeChecker turns away requests with an API token that doesn't have
+// the scope for them, writing a 403.
+func TokenScopeChecker(w http.ResponseWriter, r *http.Request, adminlevel string) error {
+	t, ok, err := requestToken(r)
+	if !ok {
+		return nil
+	}
+
+	if err != nil {
+		writeResponse(w, http.StatusUnauthorized, fmt.Sprintf("{\"error\":\"%s\"}", err))
+		return err
+	}
+
+	if !t.Allows(requiredScope(r.URL.Path, adminlevel)) {
+		writeResponse(w, http.StatusForbidden, fmt.Sprintf("{\"error\":\"%s\"}", ErrTokenScope))
+		return ErrTokenScope
+	}
+
+	return nil
+}
+
+// issueToken makes and saves a token.
+func issueToken(ctx context.Context, kind, name, email, scope, createdBy string) (IssuedToken, error) {
+	t, secret, err := NewToken(kind, name, email, scope, createdBy)
+	if err != nil {
+		return IssuedToken{}, err
+	}
+
+	if err := a.SaveToken(ctx, t); err != nil {
+		return IssuedToken{}, fmt.Errorf("error saving token: %s", err)
+	}
+
+	return IssuedToken{t, secret}, nil
+}
+
+// revokeToken stops a token from working.
+func revokeToken(ctx context.Context, id string) error {
+	t, err := a.GetToken(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error getting token: %s", err)
+	}
+
+	t.Revoked = true
+	if err := a.SaveToken(ctx, t); err != nil {
+		return fmt.Errorf("error saving token: %s", err)
+	}
+
+	tokens.Forget(id)
+	return nil
+}
@@ -0,0 +1,355 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeTokenStore struct {
+	tokens map[string]Token
+	gets   int
+}
+
+func (f *fakeTokenStore) get(ctx context.Context, id string) (Token, error) {
+	f.gets++
+	t, ok := f.tokens[id]
+	if !ok {
+		return Token{}, fmt.Errorf("token %s not found", id)
+	}
+	return t, nil
+}
+
+func tokenTestSetup(t *testing.T, scope string) (*fakeTokenStore, string, func()) {
+	tok, raw, err := NewToken(tokenPersonal, "", "player@example.com", scope, "admin@example.com")
+	if err != nil {
+		t.Fatalf("could not make token: %s", err)
+	}
+
+	store := &fakeTokenStore{tokens: map[string]Token{tok.ID: tok}}
+	orig := tokens
+	tokens = NewTokenChecker(store.get)
+
+	return store, raw, func() {
+		tokens = orig
+	}
+}
+
+func TestNewToken(t *testing.T) {
+	cases := []struct {
+		kind   string
+		name   string
+		email  string
+		scope  string
+		want   string
+		fields []string
+	}{
+		{tokenPersonal, "", "player@example.com", ScopePlay, "player@example.com", nil},
+		{tokenService, "Chat Bot", "ignored@example.com", ScopeGameAdmin, "chat-bot@service.bingo", nil},
+		{tokenService, "Scraper", "", ScopeMetrics, "scraper@service.bingo", nil},
+		{tokenPersonal, "", "", ScopeRead, "", []string{"email"}},
+		{tokenService, "!!!", "", ScopeRead, "", []string{"name"}},
+		{"robot", "bot", "", "owner", "", []string{"kind", "scope"}},
+	}
+
+	for _, c := range cases {
+		got, raw, err := NewToken(c.kind, c.name, c.email, c.scope, "admin@example.com")
+
+		if len(c.fields) > 0 {
+			verr, ok := err.(ValidationError)
+			if !ok {
+				t.Errorf("NewToken(%s, %s) err want ValidationError got %v", c.kind, c.scope, err)
+				continue
+			}
+			for _, f := range c.fields {
+				if _, ok := verr[f]; !ok {
+					t.Errorf("NewToken(%s, %s) want error for %s got %v", c.kind, c.scope, f, verr)
+				}
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("NewToken(%s, %s) err want %v got %s", c.kind, c.scope, nil, err)
+			continue
+		}
+
+		if got.Email != c.want {
+			t.Errorf("NewToken(%s, %s) email want %s got %s", c.kind, c.scope, c.want, got.Email)
+		}
+
+		if !strings.HasPrefix(raw, tokenPrefix+got.ID+"_") {
+			t.Errorf("NewToken(%s, %s) secret want prefix %s got %s", c.kind, c.scope, tokenPrefix+got.ID+"_", raw)
+		}
+
+		if got.Hash != hashToken(raw) || strings.Contains(got.Hash, raw) {
+			t.Errorf("NewToken(%s, %s) hash want %s got %s", c.kind, c.scope, hashToken(raw), got.Hash)
+		}
+
+		out, err := got.JSON()
+		if err != nil || strings.Contains(out, got.Hash) {
+			t.Errorf("Token.JSON() should not include the hash got %s", out)
+		}
+	}
+}
+
+func TestTokenAllows(t *testing.T) {
+	cases := []struct {
+		scope string
+		need  string
+		want  bool
+	}{
+		{ScopeRead, ScopeRead, true},
+		{ScopeRead, ScopePlay, false},
+		{ScopePlay, ScopeRead, true},
+		{ScopeGameAdmin, ScopePlay, true},
+		{ScopeGameAdmin, ScopeGlobalAdmin, false},
+		{ScopeGlobalAdmin, ScopeGameAdmin, true},
+		{ScopeGlobalAdmin, ScopeMetrics, true},
+		{ScopeMetrics, ScopeMetrics, true},
+		{ScopeMetrics, ScopeRead, false},
+		{ScopeRead, ScopeMetrics, false},
+		{ScopeGameAdmin, ScopeMetrics, false},
+		{"", ScopeRead, false},
+	}
+
+	for _, c := range cases {
+		got := Token{Scope: c.scope}.Allows(c.need)
+		if got != c.want {
+			t.Errorf("Token{%s}.Allows(%s) want %t got %t", c.scope, c.need, c.want, got)
+		}
+	}
+}
+
+func TestTokenID(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want string
+		ok   bool
+	}{
+		{"bingo_abc123_c2VjcmV0", "abc123", true},
+		{"bingo_abc123_sec_ret", "abc123", true},
+		{"bingo_abc123", "", false},
+		{"bingo__secret", "", false},
+		{"eyJhbGciOiJSUzI1NiJ9", "", false},
+	}
+
+	for _, c := range cases {
+		got, ok := tokenID(c.raw)
+		if got != c.want || ok != c.ok {
+			t.Errorf("tokenID(%s) want %s %t got %s %t", c.raw, c.want, c.ok, got, ok)
+		}
+	}
+}
+
+func TestTokenChecker(t *testing.T) {
+	store, raw, teardown := tokenTestSetup(t, ScopePlay)
+	defer teardown()
+
+	now := time.Now()
+	tokens.now = func() time.Time { return now }
+
+	if _, err := tokens.Check(context.Background(), raw); err != nil {
+		t.Fatalf("TokenChecker.Check() err want %v got %s", nil, err)
+	}
+
+	if _, err := tokens.Check(context.Background(), raw); err != nil || store.gets != 1 {
+		t.Errorf("TokenChecker.Check() cached want 1 lookup got %d %v", store.gets, err)
+	}
+
+	if _, err := tokens.Check(context.Background(), raw+"x"); err == nil {
+		t.Errorf("TokenChecker.Check() wrong secret err want error got %v", nil)
+	}
+
+	id, _ := tokenID(raw)
+	revoked := store.tokens[id]
+	revoked.Revoked = true
+	store.tokens[id] = revoked
+
+	if _, err := tokens.Check(context.Background(), raw); err != nil {
+		t.Errorf("TokenChecker.Check() within interval err want %v got %s", nil, err)
+	}
+
+	tokens.Forget(id)
+	_, err := tokens.Check(context.Background(), raw)
+	if _, ok := err.(AuthError); !ok {
+		t.Errorf("TokenChecker.Check() revoked err want AuthError got %v", err)
+	}
+}
+
+func TestTokenCheckerExpiry(t *testing.T) {
+	store, raw, teardown := tokenTestSetup(t, ScopePlay)
+	defer teardown()
+
+	now := time.Now()
+	tokens.now = func() time.Time { return now }
+
+	tokens.Check(context.Background(), raw)
+	now = now.Add(tokenInterval)
+	tokens.Check(context.Background(), raw)
+
+	if store.gets != 2 {
+		t.Errorf("TokenChecker.Check() after interval want %d lookups got %d", 2, store.gets)
+	}
+}
+
+func TestTokenAuthenticator(t *testing.T) {
+	_, raw, teardown := tokenTestSetup(t, ScopePlay)
+	defer teardown()
+
+	ta := TokenAuthenticator{HeaderAuthenticator{}}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/game", nil)
+	req.Header.Set(proxyHeader, "proxy@example.com")
+	if got, _ := ta.Email(req); got != "proxy@example.com" {
+		t.Errorf("TokenAuthenticator.Email() without token want %s got %s", "proxy@example.com", got)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+raw)
+	if got, _ := ta.Email(req); got != "player@example.com" {
+		t.Errorf("TokenAuthenticator.Email() want %s got %s", "player@example.com", got)
+	}
+
+	req.Header.Set("Authorization", "Bearer bingo_nope_nope")
+	_, err := ta.Email(req)
+	if _, ok := err.(AuthError); !ok {
+		t.Errorf("TokenAuthenticator.Email() unknown token err want AuthError got %v", err)
+	}
+}
+
+func TestRequiredScope(t *testing.T) {
+	cases := []struct {
+		route      string
+		adminlevel string
+		want       string
+	}{
+		{"/api/game", "none", ScopeRead},
+		{"/api/record", "none", ScopePlay},
+		{"/api/game/new", "none", ScopeGameAdmin},
+		{"/api/game/phrase/update", "game", ScopeGameAdmin},
+		{"/api/game/purge", "global", ScopeGlobalAdmin},
+	}
+
+	for _, c := range cases {
+		got := requiredScope(c.route, c.adminlevel)
+		if got != c.want {
+			t.Errorf("requiredScope(%s, %s) want %s got %s", c.route, c.adminlevel, c.want, got)
+		}
+	}
+}
+
+func TestTokenScopeChecker(t *testing.T) {
+	_, raw, teardown := tokenTestSetup(t, ScopeRead)
+	defer teardown()
+
+	cases := []struct {
+		route  string
+		token  string
+		status int
+	}{
+		{"/api/game", "", http.StatusOK},
+		{"/api/game", raw, http.StatusOK},
+		{"/api/record", raw, http.StatusForbidden},
+		{"/api/game", "bingo_nope_nope", http.StatusUnauthorized},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.route, nil)
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		rr := httptest.NewRecorder()
+		TokenScopeChecker(rr, req, "none")
+
+		if rr.Code != c.status {
+			t.Errorf("TokenScopeChecker(%s) status want %d got %d", c.route, c.status, rr.Code)
+		}
+	}
+}
+
+func TestAdminTokenScope(t *testing.T) {
+	store, personal, teardown := tokenTestSetup(t, ScopePlay)
+	defer teardown()
+
+	admin, adminRaw, err := NewToken(tokenService, "Chat Bot", "", ScopeGlobalAdmin, "admin@example.com")
+	if err != nil {
+		t.Fatalf("could not make token: %s", err)
+	}
+	reader, readerRaw, err := NewToken(tokenService, "Read Bot", "", ScopeRead, "admin@example.com")
+	if err != nil {
+		t.Fatalf("could not make token: %s", err)
+	}
+	store.tokens[admin.ID] = admin
+	store.tokens[reader.ID] = reader
+
+	cases := []struct {
+		label string
+		token string
+	}{
+		{"personal play token", personal},
+		{"service read token", readerRaw},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodDelete, "/api/board/delete?g=game1&b=board1", nil)
+		req.Header.Set("Authorization", "Bearer "+c.token)
+
+		if got, err := isGlobalAdmin(req); got != http.StatusForbidden || err != ErrNotAdmin {
+			t.Errorf("%s: isGlobalAdmin() want %d %v got %d %v", c.label, http.StatusForbidden, ErrNotAdmin, got, err)
+		}
+
+		if got, err := isGameAdmin(req, "game1"); got != http.StatusForbidden || err != ErrNotAdmin {
+			t.Errorf("%s: isGameAdmin() want %d %v got %d %v", c.label, http.StatusForbidden, ErrNotAdmin, got, err)
+		}
+
+		if got, err := isAdmin(req, "game1"); got != http.StatusForbidden || err != ErrNotAdmin {
+			t.Errorf("%s: isAdmin() want %d %v got %d %v", c.label, http.StatusForbidden, ErrNotAdmin, got, err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/game/list", nil)
+	req.Header.Set("Authorization", "Bearer "+adminRaw)
+	if got, err := isGlobalAdmin(req); got != http.StatusOK || err != nil {
+		t.Errorf("isGlobalAdmin() service admin token want %d %v got %d %v", http.StatusOK, nil, got, err)
+	}
+}
+
+func TestBoardDeleteNotOwner(t *testing.T) {
+	_, raw, teardown := tokenTestSetup(t, ScopePlay)
+	defer teardown()
+
+	orig := authenticator
+	authenticator = TokenAuthenticator{HeaderAuthenticator{}}
+	defer func() { authenticator = orig }()
+
+	board := Board{ID: "board1", Game: "game1", Player: Player{Name: "Other", Email: "other@example.com"}}
+	if err := cache.SaveBoard(context.Background(), board); err != nil {
+		t.Fatalf("could not cache board: %s", err)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/board/delete?g=game1&b=board1", nil)
+	req.Header.Set("Authorization", "Bearer "+raw)
+
+	if err := boardDeleteHandle(httptest.NewRecorder(), req); err != ErrNotAdminOrPlayer {
+		t.Errorf("boardDeleteHandle() other player's board want %v got %v", ErrNotAdminOrPlayer, err)
+	}
+}
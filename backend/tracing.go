@@ -0,0 +1,137 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name the app's spans are recorded under.
+const tracerName = "bingo"
+
+// configureTracing sets up tracing from the TRACEEXPORTER, TRACEFILE and
+// TRACESAMPLING environment variables. Spans are written as JSON lines to
+// stdout, or appended to a file, or sent to an OpenTelemetry collector set up
+// with the usual OTEL_EXPORTER_OTLP_* variables. Traces are only sampled when
+// there is somewhere to send them, but the traceparent header is always
+// passed on. The returned func flushes any spans still waiting to be sent.
+func configureTracing(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	none := func(context.Context) error { return nil }
+
+	exporter := os.Getenv("TRACEEXPORTER")
+
+	var exp sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case "", "none":
+		return none, nil
+	case "stdout":
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "file":
+		path := os.Getenv("TRACEFILE")
+		if path == "" {
+			return none, fmt.Errorf("TRACEFILE must be set to export traces to a file")
+		}
+		f, ferr := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if ferr != nil {
+			return none, fmt.Errorf("could not open trace file: %s", ferr)
+		}
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	case "otlp":
+		exp, err = otlptracegrpc.New(ctx)
+	default:
+		return none, fmt.Errorf("unknown trace exporter: %s", exporter)
+	}
+	if err != nil {
+		return none, fmt.Errorf("could not create %s trace exporter: %s", exporter, err)
+	}
+
+	fraction := 1.0
+	if s := os.Getenv("TRACESAMPLING"); s != "" {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || f < 0 || f > 1 {
+			return none, fmt.Errorf("TRACESAMPLING must be between 0 and 1, got %s", s)
+		}
+		fraction = f
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(fraction))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// traceRequest starts the span for a request, as a child of the span in its
+// traceparent header if it has one. The returned writer should be used for
+// the response and the func called once it's written.
+func traceRequest(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request, func()) {
+	route := requestRoute(r)
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := otel.Tracer(tracerName).Start(ctx, route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.method", r.Method),
+			attribute.String("http.route", route),
+		),
+	)
+
+	rec := &statusRecorder{w, http.StatusOK}
+
+	return rec, r.WithContext(ctx), func() {
+		span.SetAttributes(attribute.Int("http.status_code", rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+		span.End()
+	}
+}
+
+// startSpan starts a span for an operation within a request, as a child of
+// the request's span. The returned func ends it, marking it failed if err
+// points to an error by then.
+func startSpan(ctx context.Context, name string, err *error) (context.Context, func()) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, name)
+	if f, ok := logFieldsFrom(ctx); ok {
+		if f.Game != "" {
+			span.SetAttributes(attribute.String("bingo.game", f.Game))
+		}
+		if f.Board != "" {
+			span.SetAttributes(attribute.String("bingo.board", f.Board))
+		}
+	}
+
+	return ctx, func() {
+		if err != nil && *err != nil {
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type collectingExporter struct {
+	*tracetest.InMemoryExporter
+}
+
+func (c collectingExporter) find(name string) *tracetest.SpanStub {
+	for _, v := range c.GetSpans() {
+		if v.Name == name {
+			return &v
+		}
+	}
+	return nil
+}
+
+func spanAttribute(s *tracetest.SpanStub, key string) attribute.Value {
+	for _, v := range s.Attributes {
+		if string(v.Key) == key {
+			return v.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func traceTestSetup() (collectingExporter, func()) {
+	e := collectingExporter{tracetest.NewInMemoryExporter()}
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(e), sdktrace.WithSampler(sdktrace.AlwaysSample())))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return e, func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+	}
+}
+
+func TestTraceRequest(t *testing.T) {
+	e, teardown := traceTestSetup()
+	defer teardown()
+
+	h := requestMiddleware(JSONHandler(func(w http.ResponseWriter, r *http.Request) (JSONProducer, error) {
+		var err error
+		_, done := startSpan(r.Context(), "Cache.GetGame", &err)
+		err = ErrCacheMiss
+		done()
+		return Game{}, nil
+	}, "none"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/game?g=game1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	server := e.find("/api/game")
+	if server == nil {
+		t.Fatalf("traceRequest() want a span for %s got none", "/api/game")
+	}
+
+	if got := server.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("traceRequest() trace want %s got %s", "4bf92f3577b34da6a3ce929d0e0e4736", got)
+	}
+
+	if got := server.Parent.SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("traceRequest() parent want %s got %s", "00f067aa0ba902b7", got)
+	}
+
+	if got := spanAttribute(server, "http.status_code").AsInt64(); got != int64(http.StatusOK) {
+		t.Errorf("traceRequest() status want %d got %v", http.StatusOK, got)
+	}
+
+	child := e.find("Cache.GetGame")
+	if child == nil {
+		t.Fatalf("startSpan() want a span for %s got none", "Cache.GetGame")
+	}
+
+	if child.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("startSpan() parent want %s got %s", server.SpanContext.SpanID(), child.Parent.SpanID())
+	}
+
+	if child.Status.Code != codes.Error || child.Status.Description != ErrCacheMiss.Error() {
+		t.Errorf("startSpan() status want %s got %s %s", ErrCacheMiss, child.Status.Code, child.Status.Description)
+	}
+
+	if got := spanAttribute(child, "bingo.game").AsString(); got != "game1" {
+		t.Errorf("startSpan() game want %s got %v", "game1", got)
+	}
+}
+
+func TestAgentObserveSpan(t *testing.T) {
+	e, teardown := traceTestSetup()
+	defer teardown()
+
+	ag := &Agent{}
+	err := fmt.Errorf("failed")
+	_, done := ag.observe(context.Background(), "GetGame", &err)
+	done()
+
+	span := e.find("Agent.GetGame")
+	if span == nil {
+		t.Fatalf("Agent.observe() want a span for %s got none", "Agent.GetGame")
+	}
+
+	if span.Status.Description != "failed" {
+		t.Errorf("Agent.observe() status want %s got %s", "failed", span.Status.Description)
+	}
+}
+
+func TestConfigureTracing(t *testing.T) {
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	cases := []struct {
+		exporter string
+		file     string
+		sampling string
+		err      string
+	}{
+		{"", "", "", ""},
+		{"none", "", "", ""},
+		{"otlp", "", "", ""},
+		{"zipkin", "", "", "unknown trace exporter"},
+		{"file", "", "", "TRACEFILE must be set"},
+		{"stdout", "", "2", "TRACESAMPLING must be between 0 and 1"},
+	}
+
+	for _, c := range cases {
+		os.Setenv("TRACEEXPORTER", c.exporter)
+		os.Setenv("TRACEFILE", c.file)
+		os.Setenv("TRACESAMPLING", c.sampling)
+
+		shutdown, err := configureTracing(context.Background())
+		if c.err == "" && err != nil {
+			t.Errorf("configureTracing(%s) err want %v got %s", c.exporter, nil, err)
+		}
+		if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Errorf("configureTracing(%s) err want %s got %v", c.exporter, c.err, err)
+		}
+		shutdown(context.Background())
+	}
+
+	os.Unsetenv("TRACEEXPORTER")
+	os.Unsetenv("TRACEFILE")
+	os.Unsetenv("TRACESAMPLING")
+}
+
+func TestConfigureTracingFile(t *testing.T) {
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	path := t.TempDir() + "/traces.json"
+	os.Setenv("TRACEEXPORTER", "file")
+	os.Setenv("TRACEFILE", path)
+	defer os.Unsetenv("TRACEEXPORTER")
+	defer os.Unsetenv("TRACEFILE")
+
+	shutdown, err := configureTracing(context.Background())
+	if err != nil {
+		t.Fatalf("configureTracing(file) err want %v got %s", nil, err)
+	}
+
+	var serr error
+	_, done := startSpan(context.Background(), "Cache.GetGame", &serr)
+	done()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("configureTracing(file) shutdown err want %v got %s", nil, err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("configureTracing(file) could not read %s: %s", path, err)
+	}
+
+	if !strings.Contains(string(b), `"Name":"Cache.GetGame"`) {
+		t.Errorf("configureTracing(file) want span %s written got %s", "Cache.GetGame", string(b))
+	}
+}
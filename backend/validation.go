@@ -0,0 +1,114 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxPhraseLength = 80
+
+var (
+	blocklist = []string{}
+	tagRegexp = regexp.MustCompile(`<\s*/?\s*[a-zA-Z!][^>]*>`)
+	wordSplit = regexp.MustCompile(`[^\p{L}\p{N}']+`)
+)
+
+// ValidationError reports which fields of a request failed validation, keyed
+// by the name of the field.
+type ValidationError map[string]string
+
+// Error lists every field that failed validation.
+func (v ValidationError) Error() string {
+	fields := []string{}
+	for k := range v {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+
+	msgs := []string{}
+	for _, k := range fields {
+		msgs = append(msgs, fmt.Sprintf("%s %s", k, v[k]))
+	}
+
+	return strings.Join(msgs, ", ")
+}
+
+// loadBlocklist sets the words that can't appear in phrases from a comma
+// separated list.
+func loadBlocklist(list string) {
+	blocklist = []string{}
+	for _, v := range strings.Split(list, ",") {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v != "" {
+			blocklist = append(blocklist, v)
+		}
+	}
+}
+
+// sanitizePhraseText strips extra whitespace from the text of a phrase. The
+// text is otherwise kept as it was typed, and escaped wherever it's shown.
+func sanitizePhraseText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// validatePhrase sanitizes the text of a phrase and checks it for markup, and
+// against the length limit, the blocklist and the other phrases it will sit
+// alongside. It reports the first check the text fails.
+func validatePhrase(phrase Phrase, existing []Phrase) (Phrase, error) {
+	phrase.Text = sanitizePhraseText(phrase.Text)
+
+	switch {
+	case phrase.Text == "":
+		return phrase, ValidationError{"text": "must not be empty"}
+	case utf8.RuneCountInString(phrase.Text) > maxPhraseLength:
+		return phrase, ValidationError{"text": fmt.Sprintf("must be %d characters or fewer", maxPhraseLength)}
+	case tagRegexp.MatchString(html.UnescapeString(phrase.Text)):
+		return phrase, ValidationError{"text": "must not contain markup"}
+	}
+
+	if word := blockedWord(phrase.Text); word != "" {
+		return phrase, ValidationError{"text": fmt.Sprintf("contains the blocked word '%s'", word)}
+	}
+
+	for _, v := range existing {
+		if v.ID != phrase.ID && strings.EqualFold(v.Text, phrase.Text) {
+			return phrase, ValidationError{"text": "is already a phrase in this list"}
+		}
+	}
+
+	return phrase, nil
+}
+
+func blockedWord(text string) string {
+	padded := " " + normalizeWords(text) + " "
+	for _, v := range blocklist {
+		if strings.Contains(padded, " "+normalizeWords(v)+" ") {
+			return v
+		}
+	}
+	return ""
+}
+
+// normalizeWords lowercases text and reduces it to its words separated by
+// single spaces, so blocked words match regardless of punctuation.
+func normalizeWords(text string) string {
+	return strings.Join(strings.Fields(wordSplit.ReplaceAllString(strings.ToLower(text), " ")), " ")
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizePhraseText(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"Sports metaphor", "Sports metaphor"},
+		{"  Sports   metaphor ", "Sports metaphor"},
+		{"5 < 6", "5 < 6"},
+		{"Q&A  session", "Q&A session"},
+	}
+
+	for _, c := range cases {
+		got := sanitizePhraseText(c.in)
+		if got != c.want {
+			t.Errorf("sanitizePhraseText(%s) got %s, want %s", c.in, got, c.want)
+		}
+	}
+}
+
+func TestValidatePhrase(t *testing.T) {
+	loadBlocklist("darn, heck,circle back")
+	defer loadBlocklist("")
+
+	existing := getTestPhrases()
+
+	cases := []struct {
+		label string
+		in    Phrase
+		err   string
+		text  string
+	}{
+		{"Valid", Phrase{ID: "100", Text: "Awkward silence"}, "", "Awkward silence"},
+		{"Sanitized", Phrase{ID: "100", Text: " Awkward  silence "}, "", "Awkward silence"},
+		{"Comparison", Phrase{ID: "100", Text: "Revenue > forecast"}, "", "Revenue > forecast"},
+		{"Empty", Phrase{ID: "100", Text: "   "}, "must not be empty", ""},
+		{"Markup", Phrase{ID: "100", Text: "<em>Awkward</em> silence"}, "must not contain markup", ""},
+		{"Escaped markup", Phrase{ID: "100", Text: "&lt;img src=x onerror=alert(1)&gt;"}, "must not contain markup", ""},
+		{"Too long", Phrase{ID: "100", Text: strings.Repeat("a", maxPhraseLength+1)}, "characters or fewer", ""},
+		{"Blocked", Phrase{ID: "100", Text: "Darn, the demo broke"}, "blocked word 'darn'", ""},
+		{"Blocked phrase", Phrase{ID: "100", Text: "Let's circle  back"}, "blocked word 'circle back'", ""},
+		{"Markup and blocked", Phrase{ID: "100", Text: "<em>Darn</em> it"}, "must not contain markup", ""},
+		{"Not blocked in a word", Phrase{ID: "100", Text: "Checkers"}, "", "Checkers"},
+		{"Duplicate", Phrase{ID: "100", Text: "filler 2"}, "already a phrase", ""},
+		{"Same phrase", Phrase{ID: "2", Text: "Filler 2"}, "", "Filler 2"},
+	}
+
+	for _, c := range cases {
+		got, err := validatePhrase(c.in, existing)
+
+		if c.err == "" {
+			if err != nil {
+				t.Errorf("validatePhrase(%s) err want %v got %s", c.label, nil, err)
+			}
+			if got.Text != c.text {
+				t.Errorf("validatePhrase(%s) text got %s, want %s", c.label, got.Text, c.text)
+			}
+			continue
+		}
+
+		verr, ok := err.(ValidationError)
+		if !ok {
+			t.Errorf("validatePhrase(%s) err want ValidationError got %v", c.label, err)
+			continue
+		}
+
+		if !strings.Contains(verr["text"], c.err) {
+			t.Errorf("validatePhrase(%s) text err got %s, want %s", c.label, verr["text"], c.err)
+		}
+	}
+}
@@ -34,6 +34,7 @@ export class Phrase{
   selected:boolean
   tid:string
   displayorder:number
+  free:boolean
 }
 
 const GAPI_CONFIG = {
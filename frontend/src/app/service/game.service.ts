@@ -28,6 +28,11 @@ export class Board{
   player:Player
   phrases:Phrase[]
   bingodeclared:boolean=false
+  odds:number
+  fairness:number
+  points:number
+  rejected:number
+  suspicion:number
 }
 
 export class Record{
@@ -40,6 +45,7 @@ export class Phrase{
   text:string
   selected:boolean
   tid:string
+  free:boolean
 }
 
 export class Game  {
@@ -47,6 +53,9 @@ export class Game  {
 	name:string 
   active:boolean
   created:any
+  lobby:boolean
+  balanced:boolean
+  oneaway:boolean
   master:Master   
   admins:Player[]
   players:Player[]
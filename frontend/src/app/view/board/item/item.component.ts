@@ -60,7 +60,7 @@ export class ItemComponent implements OnInit {
       this.disable();
     }
 
-    if (this.phrase.text == "FREE"){
+    if (this.phrase.free){
       return;
     }
 